@@ -3,9 +3,13 @@ package main
 /*
 #include <stdlib.h>
 
-// Define callback function types with void* user_data for context/reference passing
+// Define callback function types with void* user_data for context/reference passing.
+// Callbacks may be called concurrently from several threads while a file is translated,
+// so the host must handle them in a thread-safe way. The strings are freed when the
+// callback returns; copy them to keep them.
 typedef void (*ProgressCallback)(char* phase, int done, int total, void* user_data);
 typedef void (*ErrorCallback)(char* stage, char* error, void* user_data);
+typedef void (*TranslatedCallback)(char* original, char* translated, void* user_data);
 
 // Helper functions to call the function pointers from Go
 static void call_progress(ProgressCallback cb, char* phase, int done, int total, void* user_data) {
@@ -15,6 +19,10 @@ static void call_progress(ProgressCallback cb, char* phase, int done, int total,
 static void call_error(ErrorCallback cb, char* stage, char* error, void* user_data) {
     if (cb) cb(stage, error, user_data);
 }
+
+static void call_translated(TranslatedCallback cb, char* original, char* translated, void* user_data) {
+    if (cb) cb(original, translated, user_data);
+}
 */
 import "C"
 import (
@@ -23,8 +31,6 @@ import (
 	"exceltranslator/pkg/runner"
 	"sync"
 	"unsafe"
-
-	"github.com/pelletier/go-toml/v2"
 )
 
 var taskMap sync.Map // map[int64]context.CancelFunc
@@ -38,6 +44,37 @@ func Translate(
 	progressCB C.ProgressCallback,
 	errorCB C.ErrorCallback,
 	userData unsafe.Pointer,
+) *C.char {
+	return translate(taskID, inputPath, outputPath, configToml, progressCB, errorCB, nil, userData)
+}
+
+// TranslateWithCallbacks is Translate with an additional callback receiving every translated
+// text, e.g. for a live log in the host application. translatedCB may be NULL.
+//
+//export TranslateWithCallbacks
+func TranslateWithCallbacks(
+	taskID C.longlong,
+	inputPath *C.char,
+	outputPath *C.char,
+	configToml *C.char,
+	progressCB C.ProgressCallback,
+	errorCB C.ErrorCallback,
+	translatedCB C.TranslatedCallback,
+	userData unsafe.Pointer,
+) *C.char {
+	return translate(taskID, inputPath, outputPath, configToml, progressCB, errorCB, translatedCB, userData)
+}
+
+// translate runs a translation task, passing its events to the C callbacks.
+func translate(
+	taskID C.longlong,
+	inputPath *C.char,
+	outputPath *C.char,
+	configToml *C.char,
+	progressCB C.ProgressCallback,
+	errorCB C.ErrorCallback,
+	translatedCB C.TranslatedCallback,
+	userData unsafe.Pointer,
 ) *C.char {
 	// Create cancellable context
 	ctx, cancel := context.WithCancel(context.Background())
@@ -53,16 +90,23 @@ func Translate(
 	goOutput := C.GoString(outputPath)
 	goConfigToml := C.GoString(configToml)
 
-	// Parse config
-	var cfg config.AppConfig
-	if err := toml.Unmarshal([]byte(goConfigToml), &cfg); err != nil {
+	// Parse config; settings missing from the TOML keep their defaults
+	cfg, err := config.Parse([]byte(goConfigToml))
+	if err != nil {
 		return C.CString("failed to parse config toml: " + err.Error())
 	}
 
 	// Map Go callbacks to C callbacks
 	cb := runner.TranslationCallbacks{
 		OnTranslated: func(original, translated string) {
-			// Optional: Add OnTranslated callback if needed in the future
+			if translatedCB == nil {
+				return
+			}
+			cOriginal := C.CString(original)
+			cTranslated := C.CString(translated)
+			defer C.free(unsafe.Pointer(cOriginal))
+			defer C.free(unsafe.Pointer(cTranslated))
+			C.call_translated(translatedCB, cOriginal, cTranslated, userData)
 		},
 		OnProgress: func(phase string, done, total int) {
 			cPhase := C.CString(phase)
@@ -81,7 +125,7 @@ func Translate(
 		},
 	}
 
-	err := runner.RunTranslationWithConfig(ctx, goInput, goOutput, &cfg, cb)
+	err = runner.RunTranslationWithConfig(ctx, goInput, goOutput, cfg, cb)
 	if err != nil {
 		// If cancelled, we might want to return a specific message or just the error
 		return C.CString(err.Error())
@@ -90,6 +134,44 @@ func Translate(
 	return nil // Success
 }
 
+//export ExportSegments
+func ExportSegments(inputPath *C.char, jsonPath *C.char, configToml *C.char) *C.char {
+	cfg, err := config.Parse([]byte(C.GoString(configToml)))
+	if err != nil {
+		return C.CString("failed to parse config toml: " + err.Error())
+	}
+
+	if err := runner.ExportSegments(C.GoString(inputPath), C.GoString(jsonPath), cfg); err != nil {
+		return C.CString(err.Error())
+	}
+	return nil // Success
+}
+
+//export ImportSegments
+func ImportSegments(inputPath *C.char, jsonPath *C.char, outputPath *C.char, configToml *C.char) *C.char {
+	cfg, err := config.Parse([]byte(C.GoString(configToml)))
+	if err != nil {
+		return C.CString("failed to parse config toml: " + err.Error())
+	}
+
+	err = runner.ImportSegments(context.Background(), C.GoString(inputPath), C.GoString(jsonPath), C.GoString(outputPath), cfg)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	return nil // Success
+}
+
+// RepairSheetNames makes the sheet names of a workbook valid and unique without translating
+// anything, e.g. to fix an output with colliding names, and saves it to outputPath.
+//
+//export RepairSheetNames
+func RepairSheetNames(inputPath *C.char, outputPath *C.char) *C.char {
+	if err := runner.RepairSheetNames(C.GoString(inputPath), C.GoString(outputPath)); err != nil {
+		return C.CString(err.Error())
+	}
+	return nil // Success
+}
+
 //export CancelTranslate
 func CancelTranslate(taskID C.longlong) {
 	if val, ok := taskMap.Load(int64(taskID)); ok {
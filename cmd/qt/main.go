@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -15,9 +16,21 @@ import (
 	"github.com/mappu/miqt/qt6/mainthread"
 
 	"exceltranslator/pkg/config"
+	"exceltranslator/pkg/fileprocessor"
+	"exceltranslator/pkg/llmservice"
+	"exceltranslator/pkg/logger"
 	"exceltranslator/pkg/runner"
 )
 
+// outputModes 是输出方式选择框各项对应的配置值
+var outputModes = []string{fileprocessor.OutputReplace, fileprocessor.OutputBilingual}
+
+// logColors 是在日志区域中显示的日志级别及其颜色
+var logColors = map[logger.LogLevel]string{
+	logger.WARN:  "#E65100",
+	logger.ERROR: "#D32F2F",
+}
+
 // MainWindow Excel翻译器的主窗口，包含所有UI组件和状态管理
 type MainWindow struct {
 	window *qt.QMainWindow
@@ -28,12 +41,18 @@ type MainWindow struct {
 	fileGroup     *qt.QGroupBox   // 文件选择区域容器，支持拖拽
 
 	// 设置页面UI组件
-	apiKeyEdit            *qt.QLineEdit // API密钥输入框
-	apiUrlEdit            *qt.QLineEdit // API地址输入框
-	modelEdit             *qt.QLineEdit // 模型名称输入框
-	promptEdit            *qt.QTextEdit // 翻译提示词输入框
-	maxConcurrentSpin     *qt.QSpinBox  // 最大并发数设置
-	onlyTranslateCJKCheck *qt.QCheckBox // 仅翻译CJK文本选项
+	apiKeyEdit            *qt.QLineEdit    // API密钥输入框
+	apiUrlEdit            *qt.QLineEdit    // API地址输入框
+	modelEdit             *qt.QLineEdit    // 模型名称输入框
+	targetLangCombo       *qt.QComboBox    // 目标语言选择框
+	promptEdit            *qt.QTextEdit    // 翻译提示词输入框
+	maxConcurrentSpin     *qt.QSpinBox     // 最大并发数设置
+	requestsPerMinuteSpin *qt.QSpinBox     // 每分钟请求数上限，0 为不限
+	onlyTranslateCJKCheck *qt.QCheckBox    // 仅翻译CJK文本选项
+	outputModeCombo       *qt.QComboBox    // 输出方式选择框，替换原文或双语对照
+	glossaryTable         *qt.QTableWidget // 术语表编辑表格，每行为原文和译文
+	testConnBtn           *qt.QPushButton  // 测试连接按钮
+	testConnLabel         *qt.QLabel       // 测试连接结果
 
 	// 主界面控制组件
 	progressBar *qt.QProgressBar // 翻译进度条
@@ -41,6 +60,8 @@ type MainWindow struct {
 	startBtn    *qt.QPushButton  // 开始翻译按钮
 	stopBtn     *qt.QPushButton  // 停止翻译按钮
 
+	recentMenu *qt.QMenu // 最近翻译的文件菜单
+
 	// 应用状态
 	isTranslating  bool   // 当前是否正在翻译
 	tempOutputFile string // 临时输出文件路径
@@ -183,7 +204,7 @@ QTextEdit {
 	return page
 }
 
-// createSettingsPage 创建设置页面，包含LLM配置和客户端配置两个分组
+// createSettingsPage 创建设置页面，包含LLM配置、客户端配置和术语表三个分组
 func (mw *MainWindow) createSettingsPage() *qt.QWidget {
 	settingsPage := qt.NewQWidget2()
 	mainLayout := qt.NewQVBoxLayout2()
@@ -216,6 +237,17 @@ QGroupBox::title {
 	mw.modelEdit = qt.NewQLineEdit(llmGroup.QWidget)
 	llmLayout.AddRow3("模型:", mw.modelEdit.QWidget)
 
+	testLayout := qt.NewQHBoxLayout2()
+	mw.testConnBtn = qt.NewQPushButton5("测试连接", llmGroup.QWidget)
+	mw.testConnBtn.OnPressed(func() {
+		mw.testConnection()
+	})
+	testLayout.AddWidget(mw.testConnBtn.QWidget)
+	mw.testConnLabel = qt.NewQLabel5("", llmGroup.QWidget)
+	mw.testConnLabel.SetWordWrap(true)
+	testLayout.AddWidget2(mw.testConnLabel.QWidget, 1)
+	llmLayout.AddRow4("", testLayout.QBoxLayout.QLayout)
+
 	mainLayout.AddWidget(llmGroup.QWidget)
 
 	mainLayout.AddSpacing(12)
@@ -236,26 +268,174 @@ QGroupBox::title {
 	clientLayout.SetFieldGrowthPolicy(qt.QFormLayout__ExpandingFieldsGrow)
 	clientGroup.SetLayout(clientLayout.QLayout)
 
-	//mw.maxConcurrentSpin = qt.NewQSpinBox(clientGroup.QWidget)
-	//mw.maxConcurrentSpin.SetRange(1, 20)
-	//mw.maxConcurrentSpin.SetValue(5)
-	//clientLayout.AddRow3("最大并发请求数:", mw.maxConcurrentSpin.QWidget)
+	mw.maxConcurrentSpin = qt.NewQSpinBox(clientGroup.QWidget)
+	mw.maxConcurrentSpin.SetRange(1, 20)
+	mw.maxConcurrentSpin.SetValue(5)
+	clientLayout.AddRow3("最大并发请求数:", mw.maxConcurrentSpin.QWidget)
+
+	// 按 API 套餐的速率限制设置，避免请求频繁被拒绝（429）
+	mw.requestsPerMinuteSpin = qt.NewQSpinBox(clientGroup.QWidget)
+	mw.requestsPerMinuteSpin.SetRange(0, 100000)
+	mw.requestsPerMinuteSpin.SetSpecialValueText("不限")
+	clientLayout.AddRow3("每分钟请求上限:", mw.requestsPerMinuteSpin.QWidget)
+
+	// 目标语言以英文名称写入提示词，可选择常用语言或直接输入
+	mw.targetLangCombo = qt.NewQComboBox(clientGroup.QWidget)
+	mw.targetLangCombo.SetEditable(true)
+	mw.targetLangCombo.AddItems([]string{
+		"Simplified Chinese", "Traditional Chinese", "English", "Japanese", "Korean",
+		"French", "German", "Spanish", "Russian", "Arabic", "Hebrew",
+	})
+	clientLayout.AddRow3("目标语言:", mw.targetLangCombo.QWidget)
 
 	mw.onlyTranslateCJKCheck = qt.NewQCheckBox(clientGroup.QWidget)
 	mw.onlyTranslateCJKCheck.SetChecked(true)
 	clientLayout.AddRow3("仅翻译CJK文本:", mw.onlyTranslateCJKCheck.QWidget)
 
+	// 双语对照时保留原文，在单元格中换行或在文档中以括号附上译文
+	mw.outputModeCombo = qt.NewQComboBox(clientGroup.QWidget)
+	mw.outputModeCombo.AddItems([]string{"替换原文", "双语对照"})
+	clientLayout.AddRow3("输出方式:", mw.outputModeCombo.QWidget)
+
 	mw.promptEdit = qt.NewQTextEdit(clientGroup.QWidget)
 	mw.promptEdit.SetMaximumHeight(100)
 	clientLayout.AddRow3("翻译提示词:", mw.promptEdit.QWidget)
 
 	mainLayout.AddWidget(clientGroup.QWidget)
 
+	mainLayout.AddSpacing(12)
+
+	mainLayout.AddWidget(mw.createGlossaryGroup(settingsPage).QWidget)
+
 	mainLayout.AddStretch()
 
 	return settingsPage
 }
 
+// createGlossaryGroup 创建术语表分组，用户可在表格中维护原文术语及其固定译文
+func (mw *MainWindow) createGlossaryGroup(parent *qt.QWidget) *qt.QGroupBox {
+	glossaryGroup := qt.NewQGroupBox4("术语表", parent)
+	glossaryGroup.SetStyleSheet(`
+QGroupBox::title {
+	subcontrol-origin: margin;
+	subcontrol-position: top left;
+	top: 10px;
+	left: 12px;
+}
+`)
+	glossaryLayout := qt.NewQVBoxLayout2()
+	glossaryLayout.SetContentsMargins(10, 20, 10, 20)
+	glossaryLayout.SetSpacing(10)
+	glossaryGroup.SetLayout(glossaryLayout.QBoxLayout.QLayout)
+
+	mw.glossaryTable = qt.NewQTableWidget4(0, 2, glossaryGroup.QWidget)
+	mw.glossaryTable.SetHorizontalHeaderLabels([]string{"原文", "译文"})
+	mw.glossaryTable.HorizontalHeader().SetSectionResizeMode(qt.QHeaderView__Stretch)
+	mw.glossaryTable.SetMinimumHeight(150)
+	glossaryLayout.AddWidget(mw.glossaryTable.QWidget)
+
+	buttonLayout := qt.NewQHBoxLayout2()
+	buttonLayout.AddStretch()
+
+	addBtn := qt.NewQPushButton5("添加", glossaryGroup.QWidget)
+	addBtn.SetFixedWidth(80)
+	addBtn.OnPressed(func() {
+		row := mw.glossaryTable.RowCount()
+		mw.glossaryTable.InsertRow(row)
+		mw.glossaryTable.SetCurrentCell(row, 0)
+	})
+	buttonLayout.AddWidget(addBtn.QWidget)
+
+	removeBtn := qt.NewQPushButton5("删除", glossaryGroup.QWidget)
+	removeBtn.SetFixedWidth(80)
+	removeBtn.OnPressed(func() {
+		if row := mw.glossaryTable.CurrentRow(); row >= 0 {
+			mw.glossaryTable.RemoveRow(row)
+		}
+	})
+	buttonLayout.AddWidget(removeBtn.QWidget)
+
+	glossaryLayout.AddLayout(buttonLayout.QBoxLayout.QLayout)
+
+	return glossaryGroup
+}
+
+// glossaryPath 返回术语表文件路径，未配置时使用配置目录下的默认文件
+func glossaryPath(cfg *config.AppConfig) (string, error) {
+	if cfg.LLM.GlossaryFile != "" {
+		return cfg.LLM.GlossaryFile, nil
+	}
+	return config.DefaultGlossaryPath()
+}
+
+// loadGlossaryToTable 将术语表文件的内容按原文排序后填入表格
+func (mw *MainWindow) loadGlossaryToTable(cfg *config.AppConfig) {
+	mw.glossaryTable.SetRowCount(0)
+
+	path, err := glossaryPath(cfg)
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	terms, err := llmservice.LoadGlossary(path)
+	if err != nil {
+		qt.QMessageBox_Warning(mw.window.QWidget, "警告", fmt.Sprintf("加载术语表失败: %v", err))
+		return
+	}
+
+	sources := make([]string, 0, len(terms))
+	for source := range terms {
+		sources = append(sources, source)
+	}
+	slices.Sort(sources)
+
+	mw.glossaryTable.SetRowCount(len(sources))
+	for row, source := range sources {
+		mw.glossaryTable.SetItem(row, 0, qt.NewQTableWidgetItem2(source))
+		mw.glossaryTable.SetItem(row, 1, qt.NewQTableWidgetItem2(terms[source]))
+	}
+}
+
+// glossaryFromTable 读取表格中的术语，忽略原文为空的行
+func (mw *MainWindow) glossaryFromTable() map[string]string {
+	cellText := func(row, column int) string {
+		item := mw.glossaryTable.Item(row, column)
+		if item == nil {
+			return ""
+		}
+		return strings.TrimSpace(item.Text())
+	}
+
+	terms := make(map[string]string)
+	for row := 0; row < mw.glossaryTable.RowCount(); row++ {
+		if source := cellText(row, 0); source != "" {
+			terms[source] = cellText(row, 1)
+		}
+	}
+	return terms
+}
+
+// saveGlossary 将表格中的术语写入术语表文件，并在配置中记录文件路径
+// 未配置术语表且表格为空时不创建文件
+func (mw *MainWindow) saveGlossary(cfg *config.AppConfig) error {
+	terms := mw.glossaryFromTable()
+	if len(terms) == 0 && cfg.LLM.GlossaryFile == "" {
+		return nil
+	}
+
+	path, err := glossaryPath(cfg)
+	if err != nil {
+		return err
+	}
+	if err := llmservice.SaveGlossary(path, terms); err != nil {
+		return err
+	}
+	cfg.LLM.GlossaryFile = path
+	return nil
+}
+
 // selectInputFile 打开文件选择对话框，让用户选择要翻译的Excel文件
 func (mw *MainWindow) selectInputFile() {
 	startDir := mw.lastOpenDir
@@ -282,7 +462,7 @@ func (mw *MainWindow) selectInputFile() {
 		mw.window.QWidget,
 		"选择Excel文件",
 		startDir,
-		"Excel files (*.xlsx *.docx);;All Files (*)",
+		"Excel files (*.xlsx *.docx *.pptx *.rtf *.csv *.tsv);;All Files (*)",
 	)
 	if fileName != "" {
 		mw.inputFileEdit.SetText(fileName)
@@ -327,6 +507,11 @@ func (mw *MainWindow) startTranslation() {
 
 	mw.addLog("开始翻译...")
 	mw.addLog(fmt.Sprintf("输入文件: %s", inputFile))
+	if err := config.AddRecent(inputFile); err != nil {
+		log.Printf("保存最近文件失败: %v", err)
+	} else {
+		mw.refreshRecentMenu()
+	}
 
 	mw.ctx, mw.cancel = context.WithCancel(context.Background())
 
@@ -334,14 +519,22 @@ func (mw *MainWindow) startTranslation() {
 		// 确保临时文件最终被清理
 		defer func() {
 			if mw.tempOutputFile != "" {
-				if _, statErr := os.Stat(mw.tempOutputFile); statErr == nil {
-					if removeErr := os.Remove(mw.tempOutputFile); removeErr != nil {
-						log.Printf("清理临时文件失败: %v", removeErr)
+				for _, path := range []string{mw.tempOutputFile, runner.TextOutputPath(mw.tempOutputFile), runner.TranslogPath(mw.tempOutputFile)} {
+					if _, statErr := os.Stat(path); statErr == nil {
+						if removeErr := os.Remove(path); removeErr != nil {
+							log.Printf("清理临时文件失败: %v", removeErr)
+						}
 					}
 				}
 			}
 		}()
 
+		if cfg, err := config.Load(); err == nil {
+			if calls, err := runner.EstimateCalls(inputFile, cfg); err == nil {
+				mw.addLogFromGoroutine(fmt.Sprintf("预计调用 API 约 %d 次", calls))
+			}
+		}
+
 		handleComplete := func(err error) {
 			mainthread.Wait(func() {
 				// 使用互斥锁保护状态更新
@@ -401,7 +594,34 @@ func (mw *MainWindow) startTranslation() {
 					}
 				})
 			},
+			OnFlagged: func(original, translated, reason string) {
+				mainthread.Wait(func() {
+					mw.addLogUnsafe(fmt.Sprintf("译文过长，请人工检查: %s -> %s", original, translated))
+				})
+			},
+			OnStats: func(stats runner.Stats) {
+				if stats.Segments == 0 {
+					return
+				}
+				mw.addLogFromGoroutine(fmt.Sprintf("已翻译 %d 个文本，用时 %v（其中 %d 个来自缓存，调用 API %d 次）",
+					stats.Segments, stats.Elapsed.Round(time.Second), stats.CacheHits, stats.APICalls))
+				if stats.TokensLeft >= 0 {
+					mw.addLogFromGoroutine(fmt.Sprintf("消耗 %d 个 token，剩余预算 %d", stats.Tokens, stats.TokensLeft))
+				}
+			},
+			// 警告和错误日志按级别着色显示，其余日志只输出到控制台
+			OnLog: func(level logger.LogLevel, msg string) {
+				color, ok := logColors[level]
+				if !ok {
+					return
+				}
+				mainthread.Wait(func() {
+					mw.addColoredLogUnsafe(msg, color)
+				})
+			},
 			OnComplete: handleComplete,
+			// 保持日志按文档顺序显示
+			Ordered: true,
 		})
 	}()
 }
@@ -484,6 +704,23 @@ func (mw *MainWindow) addLogUnsafe(message string) {
 	mw.logTextEdit.EnsureCursorVisible()
 }
 
+// addColoredLogUnsafe 以指定颜色添加日志到界面，必须在主线程中调用
+func (mw *MainWindow) addColoredLogUnsafe(message, color string) {
+	timestamp := time.Now().Format("15:04:05")
+	logMessage := fmt.Sprintf("[%s] %s", timestamp, message)
+
+	format := qt.NewQTextCharFormat()
+	format.SetForeground(qt.NewQBrush3(qt.NewQColor6(color)))
+	cursor := mw.logTextEdit.TextCursor()
+	cursor.MovePosition(qt.QTextCursor__End)
+	cursor.InsertText2(logMessage, format)
+	// 换行使用默认格式，之后添加的日志不会沿用颜色
+	cursor.InsertText2("\n", qt.NewQTextCharFormat())
+	mw.logTextEdit.SetTextCursor(cursor)
+
+	mw.logTextEdit.EnsureCursorVisible()
+}
+
 // addLog 添加日志到界面（主线程调用版本）
 func (mw *MainWindow) addLog(message string) {
 	mw.addLogUnsafe(message)
@@ -499,19 +736,19 @@ func (mw *MainWindow) addLogFromGoroutine(message string) {
 
 // saveConfig 保存当前设置到配置文件
 func (mw *MainWindow) saveConfig() {
-	cfg := &config.AppConfig{
-		LLM: config.LLMConfig{
-			APIKey:  mw.apiKeyEdit.Text(),
-			BaseURL: mw.apiUrlEdit.Text(),
-			Model:   mw.modelEdit.Text(),
-			Prompt:  mw.promptEdit.ToPlainText(),
-		},
-		Extractor: config.ExtractorConfig{
-			CJKOnly: mw.onlyTranslateCJKCheck.IsChecked(),
-		},
-	}
-
-	err := config.Save(cfg)
+	// 在已有配置上更新界面中的字段，保留界面未提供的设置
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	mw.applySettings(cfg)
+
+	if err := mw.saveGlossary(cfg); err != nil {
+		qt.QMessageBox_Critical(mw.window.QWidget, "错误", fmt.Sprintf("保存术语表失败: %v", err))
+		return
+	}
+
+	err = config.Save(cfg)
 	if err != nil {
 		qt.QMessageBox_Critical(mw.window.QWidget, "错误", fmt.Sprintf("保存配置失败: %v", err))
 	} else {
@@ -519,6 +756,66 @@ func (mw *MainWindow) saveConfig() {
 	}
 }
 
+// applySettings 将设置界面中的字段写入配置
+func (mw *MainWindow) applySettings(cfg *config.AppConfig) {
+	cfg.LLM.APIKey = mw.apiKeyEdit.Text()
+	cfg.LLM.BaseURL = mw.apiUrlEdit.Text()
+	cfg.LLM.Model = mw.modelEdit.Text()
+	cfg.LLM.TargetLang = strings.TrimSpace(mw.targetLangCombo.CurrentText())
+	cfg.LLM.Prompt = mw.promptEdit.ToPlainText()
+	cfg.Extractor.CJKOnly = mw.onlyTranslateCJKCheck.IsChecked()
+	cfg.Processor.OutputMode = outputModes[max(mw.outputModeCombo.CurrentIndex(), 0)]
+	cfg.Translator.Concurrency = mw.maxConcurrentSpin.Value()
+	cfg.LLM.RequestsPerMinute = mw.requestsPerMinuteSpin.Value()
+}
+
+// testConnection 使用设置界面中尚未保存的 API 配置发送测试请求，并以绿色或红色显示结果
+func (mw *MainWindow) testConnection() {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	mw.applySettings(cfg)
+
+	mw.testConnBtn.SetEnabled(false)
+	mw.testConnLabel.SetStyleSheet("")
+	mw.testConnLabel.SetToolTip("")
+	mw.testConnLabel.SetText("正在连接...")
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		err := runner.TestConnection(ctx, cfg)
+
+		mainthread.Wait(func() {
+			mw.testConnBtn.SetEnabled(true)
+			if err == nil {
+				mw.testConnLabel.SetStyleSheet("color: #4CAF50;")
+				mw.testConnLabel.SetText("连接成功")
+				return
+			}
+			mw.testConnLabel.SetStyleSheet("color: #F44336;")
+			mw.testConnLabel.SetText("连接失败: " + connectionErrorMessage(err))
+			mw.testConnLabel.SetToolTip(err.Error())
+		})
+	}()
+}
+
+// connectionErrorMessage 返回测试连接失败原因的提示
+func connectionErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, llmservice.ErrAuthentication):
+		return "认证失败，请检查 API Key"
+	case errors.Is(err, llmservice.ErrUnknownModel):
+		return "模型不存在，请检查模型名称"
+	case errors.Is(err, llmservice.ErrInvalidURL):
+		return "无法访问 API，请检查 API URL"
+	case errors.Is(err, llmservice.ErrTimeout):
+		return "连接超时，请检查网络和 API URL"
+	}
+	return err.Error()
+}
+
 // promptSaveFile 翻译完成后提示用户保存翻译结果
 // 自动生成默认文件名，并记住用户选择的保存目录
 func (mw *MainWindow) promptSaveFile() {
@@ -543,7 +840,7 @@ func (mw *MainWindow) promptSaveFile() {
 		mw.window.QWidget,
 		"保存翻译后的文件",
 		defaultPath,
-		"Excel files (*.xlsx *.docx);;All Files (*)",
+		"Excel files (*.xlsx *.docx *.pptx *.rtf *.csv *.tsv);;All Files (*)",
 	)
 
 	if savePath != "" {
@@ -555,6 +852,22 @@ func (mw *MainWindow) promptSaveFile() {
 			return
 		}
 
+		// 同时保存用于校对的译文纯文本（如已启用）
+		textFile := runner.TextOutputPath(mw.tempOutputFile)
+		if _, statErr := os.Stat(textFile); statErr == nil {
+			if err := copyFile(textFile, runner.TextOutputPath(savePath)); err != nil {
+				mw.addLogUnsafe(fmt.Sprintf("保存译文文本失败: %v", err))
+			}
+		}
+
+		// 同时保存翻译日志（如已启用）
+		translogFile := runner.TranslogPath(mw.tempOutputFile)
+		if _, statErr := os.Stat(translogFile); statErr == nil {
+			if err := copyFile(translogFile, runner.TranslogPath(savePath)); err != nil {
+				mw.addLogUnsafe(fmt.Sprintf("保存翻译日志失败: %v", err))
+			}
+		}
+
 		qt.QMessageBox_Information(mw.window.QWidget, "成功", fmt.Sprintf("文件已保存到: %s", savePath))
 	} else {
 		qt.QMessageBox_Information(mw.window.QWidget, "完成", "翻译已完成，但未保存文件。\n临时文件位置: "+mw.tempOutputFile)
@@ -590,6 +903,44 @@ func (mw *MainWindow) createMenuBar() {
 		mw.showSettingsWindow()
 	})
 	appMenu.AddAction(preferencesAction)
+
+	fileMenu := menuBar.AddMenuWithTitle("文件")
+	mw.recentMenu = fileMenu.AddMenuWithTitle("最近文件")
+	mw.refreshRecentMenu()
+}
+
+// refreshRecentMenu 根据最近翻译的文件列表重建最近文件菜单
+func (mw *MainWindow) refreshRecentMenu() {
+	mw.recentMenu.Clear()
+	files, err := config.RecentFiles()
+	if err != nil {
+		log.Printf("读取最近文件失败: %v", err)
+	}
+	if len(files) == 0 {
+		mw.recentMenu.AddActionWithText("无").SetEnabled(false)
+		return
+	}
+	for _, file := range files {
+		action := mw.recentMenu.AddActionWithText(file)
+		action.OnTriggered(func() {
+			mw.openRecentFile(file)
+		})
+	}
+}
+
+// openRecentFile 将最近翻译的文件设为输入文件
+func (mw *MainWindow) openRecentFile(file string) {
+	if mw.isTranslating {
+		return
+	}
+	if _, err := os.Stat(file); err != nil {
+		qt.QMessageBox_Warning(mw.window.QWidget, "错误", fmt.Sprintf("文件不存在: %s", file))
+		return
+	}
+	mw.inputFileEdit.SetText(file)
+	mw.lastOpenDir = filepath.Dir(file)
+	mw.logTextEdit.Clear()
+	mw.resetProgressBar()
 }
 
 // showSettingsWindow 显示设置对话框，允许用户配置API参数和翻译选项
@@ -660,14 +1011,14 @@ func (mw *MainWindow) setupDragAndDrop() {
 				filePath := urls[0].ToLocalFile()
 
 				ext := strings.ToLower(filepath.Ext(filePath))
-				if ext == ".xlsx" || ext == ".docx" {
+				if ext == ".xlsx" || ext == ".docx" || ext == ".pptx" || ext == ".rtf" || ext == ".csv" || ext == ".tsv" {
 					mw.inputFileEdit.SetText(filePath)
 					mw.lastOpenDir = filepath.Dir(filePath)
 					mw.logTextEdit.Clear()
 					mw.resetProgressBar()
 					event.AcceptProposedAction()
 				} else {
-					qt.QMessageBox_Warning(mw.window.QWidget, "错误", "请拖拽Excel文件(.xlsx或.docx)")
+					qt.QMessageBox_Warning(mw.window.QWidget, "错误", "请拖拽Excel文件(.xlsx、.docx、.pptx、.rtf或.csv)")
 				}
 			}
 		} else {
@@ -687,9 +1038,13 @@ func (mw *MainWindow) loadConfigToSettings() {
 	mw.apiKeyEdit.SetText(cfg.LLM.APIKey)
 	mw.apiUrlEdit.SetText(cfg.LLM.BaseURL) // Note: APIURL in GUI maps to BaseURL in config
 	mw.modelEdit.SetText(cfg.LLM.Model)
+	mw.targetLangCombo.SetCurrentText(cfg.LLM.TargetLang)
 	mw.promptEdit.SetText(cfg.LLM.Prompt) // Map LLM.Prompt directly
-	// mw.maxConcurrentSpin.SetValue(cfg.Client.MaxConcurrentRequests) // No direct mapping in AppConfig
+	mw.maxConcurrentSpin.SetValue(max(cfg.Translator.Concurrency, 1))
+	mw.requestsPerMinuteSpin.SetValue(max(cfg.LLM.RequestsPerMinute, 0))
 	mw.onlyTranslateCJKCheck.SetChecked(cfg.Extractor.CJKOnly) // Map Extractor.CJKOnly
+	mw.outputModeCombo.SetCurrentIndex(max(slices.Index(outputModes, cfg.Processor.OutputMode), 0))
+	mw.loadGlossaryToTable(cfg)
 }
 
 // main 函数是程序的入口点
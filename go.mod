@@ -8,6 +8,8 @@ require (
 	github.com/mappu/miqt v0.12.0
 	github.com/openai/openai-go/v3 v3.16.0
 	github.com/pelletier/go-toml/v2 v2.2.4
+	golang.org/x/sync v0.16.0
+	golang.org/x/text v0.28.0
 )
 
 require (
@@ -11,38 +11,215 @@ import (
 const (
 	AppName    = "Excel-Translator"
 	ConfigName = "config.toml"
+
+	// GlossaryName is the glossary file in the configuration directory used when none is configured
+	GlossaryName = "glossary.json"
 )
 
 // AppConfig represents the persistent application configuration.
 // It combines settings for LLMService and TextExtractor.
 type AppConfig struct {
-	LLM       LLMConfig       `toml:"llm" json:"llm"`
-	Extractor ExtractorConfig `toml:"extractor" json:"extractor"`
+	LLM        LLMConfig        `toml:"llm" json:"llm"`
+	Extractor  ExtractorConfig  `toml:"extractor" json:"extractor"`
+	Processor  ProcessorConfig  `toml:"processor" json:"processor"`
+	Translator TranslatorConfig `toml:"translator" json:"translator"`
+	Log        LogConfig        `toml:"log" json:"log"`
 }
 
 type LLMConfig struct {
-	BaseURL string `toml:"base_url" json:"base_url"`
-	APIKey  string `toml:"api_key" json:"api_key"`
-	Model   string `toml:"model" json:"model"`
-	Prompt  string `toml:"prompt" json:"prompt"`
+	Provider      string `toml:"provider" json:"provider"` // openai (default), ollama, anthropic or pseudo
+	BaseURL       string `toml:"base_url" json:"base_url"`
+	APIKey        string `toml:"api_key" json:"api_key"`
+	Model         string `toml:"model" json:"model"`
+	TargetLang    string `toml:"target_lang" json:"target_lang"` // e.g. English; composed into the prompt
+	Prompt        string `toml:"prompt" json:"prompt"`
+	EmptyResponse string `toml:"empty_response" json:"empty_response"` // fail, keep_original or blank
+
+	// ClientMaxRetries is the retry count of a failed request; 0 uses the default, negative disables
+	ClientMaxRetries int `toml:"client_max_retries" json:"client_max_retries"`
+	// RetryBackoffMs is the delay before the first retry in milliseconds, doubled for each further retry; 0 uses 500
+	RetryBackoffMs int `toml:"retry_backoff_ms" json:"retry_backoff_ms"`
+	// RateLimitRetries is how often a 429 response with Retry-After is retried after the requested wait;
+	// 0 uses the default, negative disables
+	RateLimitRetries int `toml:"rate_limit_retries" json:"rate_limit_retries"`
+
+	ProtectEmoji   bool `toml:"protect_emoji" json:"protect_emoji"`     // Keep emoji out of the model's reach
+	ProtectSymbols bool `toml:"protect_symbols" json:"protect_symbols"` // Also protect all other symbols
+
+	// ProtectPhrases lists symbols and phrases kept verbatim, e.g. legal marks and product names
+	ProtectPhrases []string `toml:"protect_phrases" json:"protect_phrases"`
+
+	// BatchSize is the number of texts sent in one request; 0 or 1 sends them one by one
+	BatchSize int `toml:"batch_size" json:"batch_size"`
+
+	// CacheSize caps the in-memory translation cache; 0 is unlimited, negative disables it
+	CacheSize int `toml:"cache_size" json:"cache_size"`
+
+	// CacheFile keeps translations across runs in a JSON file; empty disables it
+	CacheFile string `toml:"cache_file" json:"cache_file"`
+
+	// GlossaryFile is a JSON object of source terms and their required translations; empty disables it
+	GlossaryFile string `toml:"glossary_file" json:"glossary_file"`
+
+	// MaxTokensBudget stops sending requests once this many tokens are used; 0 is unlimited
+	MaxTokensBudget int64 `toml:"max_tokens_budget" json:"max_tokens_budget"`
+
+	// AdaptiveConcurrency lowers the number of requests in flight while the API fails or slows
+	// down and raises it again up to the translator concurrency as it recovers
+	AdaptiveConcurrency bool `toml:"adaptive_concurrency" json:"adaptive_concurrency"`
+
+	// RequestsPerMinute and TokensPerMinute keep the requests within the provider's rate limits; 0 is unlimited
+	RequestsPerMinute int   `toml:"requests_per_minute" json:"requests_per_minute"`
+	TokensPerMinute   int64 `toml:"tokens_per_minute" json:"tokens_per_minute"`
 }
 
 type ExtractorConfig struct {
-	CJKOnly bool `toml:"cjk_only" json:"cjk_only"`
+	// Scope limits the translated texts of workbooks: all (default), cells_only, cells_and_comments
+	// or text_only (everything but sheet and defined names)
+	Scope string `toml:"scope" json:"scope"`
+
+	CJKOnly           bool `toml:"cjk_only" json:"cjk_only"`
+	SkipSameLanguage  bool `toml:"skip_same_language" json:"skip_same_language"` // Skip text already in the target language
+	KeepSheetNames    bool `toml:"keep_sheet_names" json:"keep_sheet_names"`     // Leave sheet names untranslated
+	PreserveSheetTag  bool `toml:"preserve_sheet_tag" json:"preserve_sheet_tag"` // Keep "[A]" in "[A] 概要" sheet names
+	RTL               bool `toml:"rtl" json:"rtl"`                               // Target language is right-to-left
+	KeepRuns          bool `toml:"keep_runs" json:"keep_runs"`                   // Translate formatted runs separately
+	DefinedNames      bool `toml:"defined_names" json:"defined_names"`           // Translate string constants of defined names
+	AutoFilters       bool `toml:"auto_filters" json:"auto_filters"`             // Translate text criteria of autofilters
+	HyperlinkTooltips bool `toml:"hyperlink_tooltips" json:"hyperlink_tooltips"` // Translate tooltips of hyperlinks
+	FormulaResults    bool `toml:"formula_results" json:"formula_results"`       // Translate cached string results of formulas
+	AltText           bool `toml:"alt_text" json:"alt_text"`                     // Translate alt text and titles of Word images
+	FormControls      bool `toml:"form_controls" json:"form_controls"`           // Translate captions of buttons and other form controls
+
+	// CellStyle translates only the workbook cells whose font matches; empty translates all cells
+	CellStyle CellStyleConfig `toml:"cell_style" json:"cell_style"`
+
+	// Ranges translates only the workbook cells in the given cells, areas, columns, rows or sheets,
+	// e.g. B2:B999, Data!A:A or Data; empty translates all cells
+	Ranges []string `toml:"ranges" json:"ranges"`
+
+	// DocxParts lists the docx parts to translate: document, headers, footers, footnotes,
+	// endnotes, comments, textboxes; empty uses document, headers, footers and textboxes
+	DocxParts []string `toml:"docx_parts" json:"docx_parts"`
+
+	// CSVColumns lists the CSV/TSV columns to translate, by header or 1-based number; empty translates all
+	CSVColumns []string `toml:"csv_columns" json:"csv_columns"`
+}
+
+// CellStyleConfig selects workbook cells by the font of their cell style, see textextractor.StyleFilter.
+type CellStyleConfig struct {
+	FontColor string `toml:"font_color" json:"font_color"` // RGB hex, e.g. FF0000
+	FontName  string `toml:"font_name" json:"font_name"`
+	Bold      bool   `toml:"bold" json:"bold"`
+	Italic    bool   `toml:"italic" json:"italic"`
+}
+
+// PhaseConcurrency holds the concurrency of each phase, see textextractor.Phase.
+type PhaseConcurrency struct {
+	Cell  int `toml:"cell" json:"cell"`   // Cell text and comments
+	Sheet int `toml:"sheet" json:"sheet"` // Sheet names
+	Shape int `toml:"shape" json:"shape"` // Shapes and text boxes
+	Docx  int `toml:"docx" json:"docx"`   // Word and RTF documents
+}
+
+// Max returns the highest concurrency configured for any phase.
+func (c PhaseConcurrency) Max() int {
+	return max(c.Cell, c.Sheet, c.Shape, c.Docx)
+}
+
+// Of returns the concurrency configured for a phase, or 0 if none is.
+func (c PhaseConcurrency) Of(phase string) int {
+	switch phase {
+	case "cell":
+		return c.Cell
+	case "sheet":
+		return c.Sheet
+	case "shape":
+		return c.Shape
+	case "docx":
+		return c.Docx
+	}
+	return 0
+}
+
+type TranslatorConfig struct {
+	Concurrency int `toml:"concurrency" json:"concurrency"` // Max translations in flight; 0 or 1 is sequential
+
+	// PhaseConcurrency overrides Concurrency for the texts of one phase; 0 uses Concurrency
+	PhaseConcurrency PhaseConcurrency `toml:"phase_concurrency" json:"phase_concurrency"`
+
+	// Translations longer than these limits are flagged for review; 0 disables the check
+	MaxLengthRatio float64 `toml:"max_length_ratio" json:"max_length_ratio"` // Translated/source character ratio
+	MaxLength      int     `toml:"max_length" json:"max_length"`             // Translated character count
+
+	// FullWidth handles full-width letters and digits (ＡＢＣ１２３) in the source: "" keeps them,
+	// normalize converts them to half-width before translation, restore also converts them back in the translation
+	FullWidth string `toml:"full_width" json:"full_width"`
+
+	// TrailingPunct handles the sentence-final punctuation of translations: "" keeps what the model
+	// returns, match adds or removes a final period so that it matches the source
+	TrailingPunct string `toml:"trailing_punct" json:"trailing_punct"`
+
+	// PreviewLimit translates only the first N distinct texts of a file for a quick quality check; 0 translates all
+	PreviewLimit int `toml:"preview_limit" json:"preview_limit"`
+}
+
+type ProcessorConfig struct {
+	TextOut       bool `toml:"text_out" json:"text_out"`             // Also write the translated text to a .txt next to the output
+	WriteTranslog bool `toml:"write_translog" json:"write_translog"` // Log every segment to a .translog (JSON lines) next to the output
+
+	// FormatMismatch handles an output extension that differs from the input:
+	// error (default) fails the run, rename writes the output with the input's extension
+	FormatMismatch string `toml:"format_mismatch" json:"format_mismatch"`
+
+	// OutputMode is replace (default) to replace the source text, or bilingual to keep it and add
+	// the translation, in workbooks on a copy of each sheet
+	OutputMode string `toml:"output_mode" json:"output_mode"`
+
+	// SourceComments keeps the source text of translated workbook cells as cell comments;
+	// cells that already have a comment are skipped
+	SourceComments bool `toml:"source_comments" json:"source_comments"`
+
+	// Deterministic writes every entry of docx/xlsx/pptx outputs with a fixed modification time,
+	// so that the same input and translations give byte-identical outputs
+	Deterministic bool `toml:"deterministic" json:"deterministic"`
+
+	// WriteSheetMap writes the renamed sheets of a workbook to a .sheets.json next to the output,
+	// mapping each original name to its translation
+	WriteSheetMap bool `toml:"write_sheet_map" json:"write_sheet_map"`
+
+	// Lenient copies parts that cannot be read or processed, e.g. of corrupt or hand-edited
+	// files, unchanged and reports them instead of failing the whole file
+	Lenient bool `toml:"lenient" json:"lenient"`
+
+	// Resume records the completed files of a folder translation in the output folder, so that
+	// an interrupted run continues where it stopped
+	Resume bool `toml:"resume" json:"resume"`
 }
 
 // DefaultConfig returns the default configuration.
 func DefaultConfig() *AppConfig {
 	return &AppConfig{
 		LLM: LLMConfig{
-			BaseURL: "https://dashscope.aliyuncs.com/compatible-mode/v1",
-			APIKey:  os.Getenv("DASHSCOPE_API_KEY"),
-			Model:   "qwen-flash",
-			Prompt:  "Translate to Simplified Chinese.Ignore if already Chinese. Keep all numbers and letters intact.",
+			Provider:      "openai",
+			BaseURL:       "https://dashscope.aliyuncs.com/compatible-mode/v1",
+			APIKey:        os.Getenv("DASHSCOPE_API_KEY"),
+			Model:         "qwen-flash",
+			TargetLang:    "Simplified Chinese",
+			Prompt:        "Ignore if already in the target language. Keep all numbers and letters intact.",
+			EmptyResponse: "keep_original",
+
+			ClientMaxRetries: 3,
+			RateLimitRetries: 3,
+			ProtectEmoji:     true,
+			ProtectPhrases:   []string{"©", "®", "™"},
 		},
 		Extractor: ExtractorConfig{
 			CJKOnly: false,
 		},
+		Translator: TranslatorConfig{
+			Concurrency: 5,
+		},
 	}
 }
 
@@ -62,8 +239,18 @@ func getConfigPath() (string, error) {
 	return filepath.Join(appConfigDir, ConfigName), nil
 }
 
+// DefaultGlossaryPath returns the path of the glossary file in the configuration directory.
+func DefaultGlossaryPath() (string, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), GlossaryName), nil
+}
+
 // Load reads the configuration from the config file.
-// If the file doesn't exist, it returns the default configuration.
+// If the file doesn't exist, it returns the default configuration; settings missing from the
+// file keep their defaults.
 func Load() (*AppConfig, error) {
 	path, err := getConfigPath()
 	if err != nil {
@@ -80,15 +267,21 @@ func Load() (*AppConfig, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var cfg AppConfig
-	if err := toml.Unmarshal(data, &cfg); err != nil {
+	cfg, err := Parse(data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	return cfg, nil
+}
 
-	// Apply defaults if fields are missing (basic approach, or just return loaded)
-	// For robust app, you might want to merge with defaults.
-	// Here we'll just return what we loaded.
-	return &cfg, nil
+// Parse reads a configuration from TOML. Settings missing from it keep their defaults, see
+// DefaultConfig, so that files written before a setting was added get its default value.
+func Parse(data []byte) (*AppConfig, error) {
+	cfg := DefaultConfig()
+	if err := toml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
 // Save writes the configuration to the config file.
@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestParseKeepsDefaults(t *testing.T) {
+	cfg, err := Parse([]byte("[llm]\nmodel = 'other'\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LLM.Model != "other" {
+		t.Errorf("model = %q, want the value of the file", cfg.LLM.Model)
+	}
+	if cfg.LLM.RateLimitRetries != 3 {
+		t.Errorf("rate_limit_retries = %d, want the default 3", cfg.LLM.RateLimitRetries)
+	}
+	if !cfg.LLM.ProtectEmoji {
+		t.Error("protect_emoji is off, want it on by default")
+	}
+}
+
+func TestParseOverridesDefaults(t *testing.T) {
+	cfg, err := Parse([]byte("[llm]\nrate_limit_retries = -1\nprotect_emoji = false\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LLM.RateLimitRetries != -1 {
+		t.Errorf("rate_limit_retries = %d, want -1", cfg.LLM.RateLimitRetries)
+	}
+	if cfg.LLM.ProtectEmoji {
+		t.Error("protect_emoji is on, want it off as set in the file")
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// LogName is the log file in the configuration directory, see LogConfig.File
+const LogName = "excel-translator.log"
+
+// redactedKey replaces the API key in logged configurations
+const redactedKey = "REDACTED"
+
+type LogConfig struct {
+	// File also writes the log to LogName in the configuration directory, rotated at 1 MB
+	File bool `toml:"file" json:"file"`
+}
+
+// DefaultLogPath returns the path of the log file in the configuration directory.
+func DefaultLogPath() (string, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), LogName), nil
+}
+
+// Redacted returns the configuration as TOML with the API key replaced, so that it can be
+// written to logs and bug reports.
+func (c *AppConfig) Redacted() string {
+	redacted := *c
+	if redacted.LLM.APIKey != "" {
+		redacted.LLM.APIKey = redactedKey
+	}
+	data, err := toml.Marshal(&redacted)
+	if err != nil {
+		return fmt.Sprintf("failed to marshal config: %v\n", err)
+	}
+	return string(data)
+}
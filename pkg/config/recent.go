@@ -0,0 +1,88 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// RecentName is the file in the configuration directory listing recently translated files
+	RecentName = "recent.json"
+
+	// MaxRecentFiles caps the number of recently translated files that are kept
+	MaxRecentFiles = 10
+)
+
+// recentPath returns the path of the recent files list in the configuration directory.
+func recentPath() (string, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), RecentName), nil
+}
+
+// RecentFiles returns the recently translated input files, most recent first.
+// A missing list returns no files.
+func RecentFiles() ([]string, error) {
+	path, err := recentPath()
+	if err != nil {
+		return nil, err
+	}
+	return loadRecent(path)
+}
+
+// AddRecent moves path to the front of the recently translated files, removing an earlier
+// entry for the same file and dropping the oldest entries beyond MaxRecentFiles.
+func AddRecent(path string) error {
+	listPath, err := recentPath()
+	if err != nil {
+		return err
+	}
+	files, err := loadRecent(listPath)
+	if err != nil {
+		files = nil // A corrupt list is replaced
+	}
+
+	data, err := json.MarshalIndent(addRecent(files, path), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode recent files: %w", err)
+	}
+	if err := os.WriteFile(listPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write recent files: %w", err)
+	}
+	return nil
+}
+
+// loadRecent reads the recent files list at path.
+func loadRecent(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recent files: %w", err)
+	}
+	var files []string
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("failed to parse recent files: %w", err)
+	}
+	return files, nil
+}
+
+// addRecent returns files with path in front, without duplicates and capped at MaxRecentFiles.
+func addRecent(files []string, path string) []string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	recent := []string{path}
+	for _, file := range files {
+		if file != path && len(recent) < MaxRecentFiles {
+			recent = append(recent, file)
+		}
+	}
+	return recent
+}
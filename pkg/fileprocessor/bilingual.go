@@ -0,0 +1,165 @@
+package fileprocessor
+
+import (
+	"archive/zip"
+	"context"
+	"exceltranslator/pkg/textextractor"
+	"exceltranslator/pkg/translator"
+	"fmt"
+	"path"
+)
+
+// Output modes, see SetOutputMode.
+const (
+	OutputReplace   = "replace"   // Replace the source text with its translation (default)
+	OutputBilingual = "bilingual" // Keep the source text and add the translation after it
+)
+
+const (
+	worksheetRelType     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+	worksheetContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"
+)
+
+// SetOutputMode sets how translations are written: OutputReplace (or "") replaces the source
+// text, OutputBilingual keeps it and adds the translation. In workbooks the sheets are kept as
+// they are and each gets a mirror sheet showing the translation of its cells, see
+// planMirrorSheets; elsewhere the translation follows the source text, see bilingualText.
+func (fp *FileProcessor) SetOutputMode(mode string) {
+	fp.bilingual = mode == OutputBilingual
+}
+
+// bilingualTexts returns the texts a part is written with in bilingual mode. The shared strings,
+// worksheets and tables of a workbook are only translated for its mirror sheets, and sheet and
+// defined names cannot hold two texts; they only get the translation.
+func (fp *FileProcessor) bilingualTexts(name string, texts, translations []string) []string {
+	if !fp.bilingual || textextractor.Phase(name) == textextractor.PhaseSheet || isCellPart(name) {
+		return translations
+	}
+	combined := make([]string, len(translations))
+	for i, dst := range translations {
+		combined[i] = bilingualText(name, texts[i], dst)
+	}
+	return combined
+}
+
+// bilingualText returns the source text followed by its translation. Comments and CSV fields get
+// the translation on a line of its own; in documents, slides and shapes, where a line break in
+// the text is not shown, it follows in parentheses. Untranslated texts are kept as they are.
+func bilingualText(name, src, dst string) string {
+	if dst == src {
+		return src
+	}
+	if textextractor.Phase(name) == textextractor.PhaseCell {
+		return src + "\n" + dst
+	}
+	return src + " (" + dst + ")"
+}
+
+// isCellPart reports whether the part holds the cells of a workbook: its shared strings, a
+// worksheet or a table.
+func isCellPart(name string) bool {
+	return name == sharedStringsPart || path.Dir(name) == "xl/worksheets" || path.Dir(name) == "xl/tables"
+}
+
+// keepsSource reports whether a part is written unchanged as its mirror sheet shows the
+// translation: the worksheets and tables of a workbook in bilingual mode, see planCells.
+func (fp *FileProcessor) keepsSource(name string) bool {
+	return fp.mirrorIndices != nil && name != sharedStringsPart && isCellPart(name)
+}
+
+// mirrorWorksheet returns the mirror sheet of a worksheet before translation, whose selected
+// cells show the copies of their shared strings, or false if the part is not mirrored.
+func (fp *FileProcessor) mirrorWorksheet(name, content string) (string, bool) {
+	indices, ok := fp.mirrorIndices[name]
+	if !ok {
+		return "", false
+	}
+	return textextractor.SetSharedStringIndices(textextractor.MirrorWorksheet(content), indices), true
+}
+
+// planMirrorSheets adds a mirror sheet after the last sheet of a workbook for every worksheet
+// planned by planCells, named after the translation of its sheet name, and returns the names of
+// the mirror sheet parts by worksheet part; their content is set by translateMirror. The
+// workbook, added to translated, keeps its sheet and defined names.
+func (fp *FileProcessor) planMirrorSheets(ctx context.Context, files []*zip.File, trans translator.Translator, translated map[string]string, edits *partEdits) (map[string]string, error) {
+	byName := make(map[string]*zip.File, len(files))
+	for _, f := range files {
+		byName[f.Name] = f
+	}
+	workbook, err := readZipFile(byName[workbookPart])
+	if err != nil {
+		fp.logger.Errorf("Failed to read content of %s: %v", workbookPart, err)
+		return nil, fmt.Errorf("failed to process file %s: %w", workbookPart, err)
+	}
+	rels := ""
+	if f, ok := byName[workbookRelsPart]; ok {
+		if rels, err = readZipFile(f); err != nil {
+			fp.logger.Errorf("Failed to read content of %s: %v", workbookRelsPart, err)
+			return nil, fmt.Errorf("failed to process file %s: %w", workbookRelsPart, err)
+		}
+	}
+
+	var renamed map[string]string
+	if fp.extractor.Supports(workbookPart) {
+		after, err := fp.translatePart(ctx, workbookPart, workbook, trans)
+		if err != nil {
+			return nil, fmt.Errorf("failed to process file %s: %w", workbookPart, err)
+		}
+		renamed = textextractor.RenamedSheets(workbook, after)
+	}
+	translated[workbookPart] = workbook
+
+	mirrors := make(map[string]string)
+	var sheets []textextractor.NewSheet
+	var mirrored []string // Names of the mirrored sheets
+	var newRels []string
+	for _, sheet := range textextractor.WorkbookSheets(workbook) {
+		target := relTarget(rels, workbookPart, func(attrs string) bool { return relAttr(attrs, "Id") == sheet.RelID })
+		if _, ok := fp.mirrorIndices[target]; !ok || mirrors[target] != "" {
+			continue
+		}
+		name := sheet.Name
+		if newName, ok := renamed[sheet.Name]; ok {
+			name = newName
+		}
+		mirror := unusedPartName(byName, edits, "xl/worksheets/sheet", ".xml")
+		edits.added = append(edits.added, addedPart{mirror, ""})
+		mirrors[target] = mirror
+		mirrored = append(mirrored, sheet.Name)
+		sheets = append(sheets, textextractor.NewSheet{Name: name, RelID: nextRelID(rels, len(newRels)), State: sheet.State})
+		newRels = append(newRels, relationship(worksheetRelType, mirror, workbookPart))
+		edits.add(contentTypesPart, func(content string) string {
+			return addContentType(content, fmt.Sprintf(`<Override PartName="/%s" ContentType="%s"/>`, mirror, worksheetContentType))
+		})
+	}
+	if len(sheets) == 0 {
+		return mirrors, nil
+	}
+	edits.add(workbookPart, func(content string) string {
+		content, names := textextractor.AddSheets(content, sheets)
+		for i, name := range names {
+			fp.logger.Debugf("Added sheet %s showing the translation of %s", name, mirrored[i])
+		}
+		return content
+	})
+	edits.add(workbookRelsPart, func(content string) string { return newRelsPart(content, newRels) })
+	return mirrors, nil
+}
+
+// translateMirror translates the mirror sheet of a worksheet and sets it as the content of the
+// mirror sheet part added by planMirrorSheets.
+func (fp *FileProcessor) translateMirror(ctx context.Context, f *zip.File, mirror string, trans translator.Translator, edits *partEdits) error {
+	content, err := readZipFile(f)
+	if err != nil {
+		fp.logger.Errorf("Failed to read content of %s: %v", f.Name, err)
+		return err
+	}
+	content, _ = fp.mirrorWorksheet(f.Name, content)
+	if fp.extractor.Supports(f.Name) {
+		if content, err = fp.translatePart(ctx, mirror, content, trans); err != nil {
+			return err
+		}
+	}
+	edits.set(mirror, content)
+	return nil
+}
@@ -0,0 +1,181 @@
+package fileprocessor
+
+import (
+	"archive/zip"
+	"exceltranslator/pkg/textextractor"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Parts of a workbook read to select cells.
+const (
+	stylesPart       = "xl/styles.xml"              // Fonts and cell formats
+	workbookPart     = "xl/workbook.xml"            // Sheet names
+	workbookRelsPart = "xl/_rels/workbook.xml.rels" // Worksheet parts of the sheets
+)
+
+// planCells sets the shared strings that are translated when the extractor selects cells by
+// their style (see textextractor.StyleFilter) or by ranges (see textextractor.ParseRanges):
+// those shown in at least one selected cell. A shared string also shown in cells that are not
+// selected is copied for the selected cells, which are pointed at the copy, so that the other
+// cells keep the source text; see selectCells. Without a filter or ranges all are translated.
+//
+// In bilingual mode every worksheet gets a mirror sheet instead (see planMirrorSheets), whose
+// selected cells show translated copies of their shared strings; the worksheets keep showing the
+// source text.
+func (fp *FileProcessor) planCells(files []*zip.File) error {
+	fp.cellStrings, fp.cellCopies, fp.cellIndices, fp.mirrorIndices = nil, nil, nil, nil
+	filter := fp.extractor.CellStyle()
+	specs := fp.extractor.Ranges()
+	mirror := fp.bilingual
+	if filter.IsZero() && len(specs) == 0 && !mirror {
+		return nil
+	}
+
+	// Until cells are selected, e.g. if the workbook cannot be read in lenient mode, none are translated
+	fp.cellStrings = make(map[int]bool)
+	cellStrings := make(map[int]bool)
+	parts := make(map[string]string) // Contents of the parts read to select cells
+	for _, f := range files {
+		if f.Name != stylesPart && f.Name != workbookPart && f.Name != workbookRelsPart && f.Name != sharedStringsPart {
+			continue
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			fp.logger.Errorf("Failed to read content of %s: %v", f.Name, err)
+			return fmt.Errorf("failed to process file %s: %w", f.Name, err)
+		}
+		parts[f.Name] = content
+	}
+	if _, ok := parts[workbookPart]; !ok {
+		fp.cellStrings = nil // Not a workbook
+		return nil
+	}
+
+	var styles map[int]bool
+	if !filter.IsZero() {
+		styles = textextractor.MatchingStyles(parts[stylesPart], filter)
+		if len(styles) == 0 {
+			fp.logger.Warnf("No cell style matches the cell style filter, no cells are translated")
+		}
+	}
+
+	// Worksheet parts by the name of their sheet
+	var ranges []textextractor.CellRange
+	sheetNames := make(map[string]string)
+	if len(specs) > 0 {
+		var names []string
+		for _, sheet := range textextractor.WorkbookSheets(parts[workbookPart]) {
+			names = append(names, sheet.Name)
+			target := relTarget(parts[workbookRelsPart], workbookPart, func(attrs string) bool { return relAttr(attrs, "Id") == sheet.RelID })
+			if target != "" {
+				sheetNames[target] = sheet.Name
+			}
+		}
+		var err error
+		if ranges, err = textextractor.ParseRanges(specs, names); err != nil {
+			return err
+		}
+	}
+
+	// Selected cells by worksheet part, and the shared strings shown in cells that are not selected
+	selected := make(map[string][]textextractor.CellText)
+	var sheets []string
+	shownElsewhere := make(map[int]bool)
+	for _, f := range files {
+		if path.Dir(f.Name) != "xl/worksheets" || !strings.HasSuffix(f.Name, ".xml") {
+			continue
+		}
+		sheet, err := readZipFile(f)
+		if err != nil {
+			fp.logger.Errorf("Failed to read content of %s: %v", f.Name, err)
+			return fmt.Errorf("failed to process file %s: %w", f.Name, err)
+		}
+		sheets = append(sheets, f.Name)
+		for _, cell := range textextractor.SharedStringCells(sheet) {
+			if styles != nil && !styles[cell.Style] || ranges != nil && !inRanges(ranges, sheetNames[f.Name], cell.Ref) {
+				shownElsewhere[cell.Index] = true
+				continue
+			}
+			selected[f.Name] = append(selected[f.Name], cell)
+		}
+	}
+
+	// Copies are added after the existing shared strings, one per string shared with cells that
+	// are not selected, or per string of the selected cells of mirror sheets, in document order
+	count := len(textextractor.SharedStrings(parts[sharedStringsPart]))
+	copies := make(map[int]int) // Index of the copy by index of the shared string
+	var cellCopies []int
+	cellIndices := make(map[string]map[string]int)
+	var mirrorIndices map[string]map[string]int
+	if mirror {
+		mirrorIndices = make(map[string]map[string]int)
+	}
+	for _, name := range sheets {
+		if mirror {
+			mirrorIndices[name] = make(map[string]int)
+		}
+		for _, cell := range selected[name] {
+			if cell.Index >= count || !mirror && !shownElsewhere[cell.Index] {
+				if !mirror {
+					cellStrings[cell.Index] = true
+				}
+				continue
+			}
+			index, ok := copies[cell.Index]
+			if !ok {
+				index = count + len(cellCopies)
+				copies[cell.Index] = index
+				cellCopies = append(cellCopies, cell.Index)
+				cellStrings[index] = true
+			}
+			if mirror {
+				mirrorIndices[name][cell.Ref] = index
+				continue
+			}
+			if cellIndices[name] == nil {
+				cellIndices[name] = make(map[string]int)
+			}
+			cellIndices[name][cell.Ref] = index
+		}
+	}
+	fp.cellStrings, fp.cellCopies, fp.cellIndices, fp.mirrorIndices = cellStrings, cellCopies, cellIndices, mirrorIndices
+	fp.logger.Debugf("%d shared strings are shown in the selected cells, %d of them copied from strings also shown in other cells or in mirror sheets", len(cellStrings), len(cellCopies))
+	return nil
+}
+
+// selectCells returns the content of a part with the changes planned by planCells: the shared
+// strings gain the copies for the selected cells, which worksheets point at. Other parts are
+// returned unchanged. It applies to the parts as read from the input.
+func (fp *FileProcessor) selectCells(name, content string) string {
+	if name == sharedStringsPart && len(fp.cellCopies) > 0 {
+		return textextractor.CopySharedStrings(content, fp.cellCopies)
+	}
+	return textextractor.SetSharedStringIndices(content, fp.cellIndices[name])
+}
+
+// selectsCells reports whether selectCells changes the part.
+func (fp *FileProcessor) selectsCells(name string) bool {
+	return name == sharedStringsPart && len(fp.cellCopies) > 0 || len(fp.cellIndices[name]) > 0
+}
+
+// inRanges reports whether the cell of the sheet is in one of the ranges.
+func inRanges(ranges []textextractor.CellRange, sheet, ref string) bool {
+	for _, r := range ranges {
+		if r.Contains(sheet, ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// extract extracts the texts of a part, leaving out the shared strings that are not shown in
+// the selected cells, see planCells.
+func (fp *FileProcessor) extract(name, content string) (string, []textextractor.ExtractionItem, error) {
+	extracted, items, err := fp.extractor.Extract(content, name)
+	if err == nil && fp.cellStrings != nil && name == sharedStringsPart {
+		items = textextractor.FilterSharedStrings(extracted, items, func(index int) bool { return fp.cellStrings[index] })
+	}
+	return extracted, items, err
+}
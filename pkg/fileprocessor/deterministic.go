@@ -0,0 +1,64 @@
+package fileprocessor
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Extra field IDs holding timestamps other than the extended timestamp, see stampHeader.
+const (
+	ntfsExtraID = 0x000a // NTFS modification, access and creation times
+	unixExtraID = 0x5855 // Info-ZIP Unix access and modification times (old format)
+)
+
+// deterministicTime is the modification time of every entry of deterministic output: the
+// earliest date an MS-DOS timestamp can hold.
+var deterministicTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SetDeterministic enables deterministic output: every entry of a docx/xlsx/pptx output gets the
+// same fixed modification time instead of that of the source entry, so that translating the
+// same input with the same translations (e.g. from the translation cache) writes the same bytes.
+func (fp *FileProcessor) SetDeterministic(enabled bool) {
+	fp.deterministic = enabled
+}
+
+// stampHeader sets the fixed modification time of deterministic output on a header and drops
+// the timestamps kept in its extra fields. It does nothing unless deterministic output is enabled.
+func (fp *FileProcessor) stampHeader(header *zip.FileHeader) {
+	if !fp.deterministic {
+		return
+	}
+	// A zero Modified keeps zip.Writer from adding an extended timestamp
+	header.Modified = time.Time{}
+	header.ModifiedTime = 0
+	header.ModifiedDate = uint16((deterministicTime.Year()-1980)<<9 | int(deterministicTime.Month())<<5 | deterministicTime.Day())
+	header.Extra = withoutExtraFields(header.Extra, zip64ExtraID, extTimeExtraID, ntfsExtraID, unixExtraID)
+}
+
+// copyZipEntry copies f to the zip writer without decompressing it. In deterministic mode its
+// header gets the fixed modification time, see stampHeader.
+func (fp *FileProcessor) copyZipEntry(w *zip.Writer, f *zip.File) error {
+	if !fp.deterministic {
+		if err := w.Copy(f); err != nil {
+			return stageError(StageWrite, f.Name, fmt.Errorf("failed to copy %s to zip: %w", f.Name, err))
+		}
+		return nil
+	}
+
+	r, err := f.OpenRaw()
+	if err != nil {
+		return stageError(StageWrite, f.Name, fmt.Errorf("failed to copy %s to zip: %w", f.Name, err))
+	}
+	header := f.FileHeader
+	fp.stampHeader(&header)
+	fw, err := w.CreateRaw(&header)
+	if err == nil {
+		_, err = io.Copy(fw, r)
+	}
+	if err != nil {
+		return stageError(StageWrite, f.Name, fmt.Errorf("failed to copy %s to zip: %w", f.Name, err))
+	}
+	return nil
+}
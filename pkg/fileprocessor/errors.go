@@ -0,0 +1,56 @@
+package fileprocessor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPartSkipped is reported for a part that lenient mode copied unchanged because it could
+// not be read or processed, see SetLenient.
+var ErrPartSkipped = errors.New("part skipped")
+
+// Stage identifies the processing step in which an error occurred.
+type Stage string
+
+const (
+	StageOpen      Stage = "open"      // Reading the source file or one of its parts
+	StageExtract   Stage = "extract"   // Extracting text from a part or replacing it with the translation
+	StageTranslate Stage = "translate" // Translating the extracted text
+	StageWrite     Stage = "write"     // Writing the output file or text output
+)
+
+// StageError wraps an error of ProcessFile or ExtractTexts with the stage it occurred in.
+// Use errors.As to recover it from the returned error.
+type StageError struct {
+	Stage Stage
+	Part  string // Internal file being processed, empty if the error is not specific to one
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// stageError wraps err in a StageError.
+func stageError(stage Stage, part string, err error) error {
+	return &StageError{Stage: stage, Part: part, Err: err}
+}
+
+// skippable reports whether lenient mode may skip the part that failed with err: only errors
+// reading or processing one part are, not failures to translate or to write the output.
+func skippable(err error) (*StageError, bool) {
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) || stageErr.Part == "" {
+		return nil, false
+	}
+	return stageErr, stageErr.Stage == StageOpen || stageErr.Stage == StageExtract
+}
+
+// skippedError returns the error reported for a part skipped because of err.
+func skippedError(stageErr *StageError) error {
+	return stageError(stageErr.Stage, stageErr.Part, fmt.Errorf("%w: %s: %w", ErrPartSkipped, stageErr.Part, stageErr.Err))
+}
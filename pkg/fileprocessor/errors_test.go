@@ -0,0 +1,122 @@
+package fileprocessor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// failingTranslator fails every translation with err.
+type failingTranslator struct{ err error }
+
+func (t failingTranslator) TranslateFileTexts(ctx context.Context, fileName string, texts []string) ([]string, error) {
+	return nil, t.err
+}
+
+// testSharedStrings is a workbook part with one text.
+const testSharedStrings = `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>收入</t></si></sst>`
+
+// writeCorruptArchive writes an archive whose stored shared strings fail their checksum.
+func writeCorruptArchive(t *testing.T, path string) {
+	t.Helper()
+	writeArchive(t, path, []testPart{
+		{zip.FileHeader{Name: "xl/sharedStrings.xml", Method: zip.Store}, testSharedStrings},
+	})
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = bytes.Replace(b, []byte("收入"), []byte("成本"), 1)
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStageErrors(t *testing.T) {
+	netErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, dir string) (input, output string)
+		trans failingTranslator
+		stage Stage
+		part  string
+		cause func(err error) bool
+	}{
+		{"not a zip archive", func(t *testing.T, dir string) (string, string) {
+			input := filepath.Join(dir, "in.xlsx")
+			if err := os.WriteFile(input, []byte("not a workbook"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			return input, filepath.Join(dir, "out.xlsx")
+		}, failingTranslator{}, StageOpen, "", func(err error) bool { return errors.Is(err, zip.ErrFormat) }},
+
+		{"corrupt part", func(t *testing.T, dir string) (string, string) {
+			input := filepath.Join(dir, "in.xlsx")
+			writeCorruptArchive(t, input)
+			return input, filepath.Join(dir, "out.xlsx")
+		}, failingTranslator{}, StageOpen, "xl/sharedStrings.xml", func(err error) bool { return errors.Is(err, zip.ErrChecksum) }},
+
+		{"network failure", func(t *testing.T, dir string) (string, string) {
+			input := filepath.Join(dir, "in.xlsx")
+			writeArchive(t, input, []testPart{{zip.FileHeader{Name: "xl/sharedStrings.xml"}, testSharedStrings}})
+			return input, filepath.Join(dir, "out.xlsx")
+		}, failingTranslator{netErr}, StageTranslate, "xl/sharedStrings.xml", func(err error) bool {
+			var opErr *net.OpError
+			return errors.As(err, &opErr) && opErr == netErr
+		}},
+
+		{"unwritable output", func(t *testing.T, dir string) (string, string) {
+			input := filepath.Join(dir, "in.xlsx")
+			writeArchive(t, input, []testPart{{zip.FileHeader{Name: "xl/sharedStrings.xml"}, testSharedStrings}})
+			// The output is an existing directory
+			return input, dir
+		}, failingTranslator{}, StageWrite, "", func(err error) bool {
+			var pathErr *fs.PathError
+			return errors.As(err, &pathErr)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, output := tt.setup(t, t.TempDir())
+			err := NewFileProcessor().ProcessFile(context.Background(), input, output, tt.trans)
+
+			var stageErr *StageError
+			if !errors.As(err, &stageErr) {
+				t.Fatalf("got error %v, want a StageError", err)
+			}
+			if stageErr.Stage != tt.stage || stageErr.Part != tt.part {
+				t.Errorf("got stage %q of part %q, want %q of %q", stageErr.Stage, stageErr.Part, tt.stage, tt.part)
+			}
+			if !tt.cause(err) {
+				t.Errorf("got error %v, want it to wrap its cause", err)
+			}
+			if errors.Is(err, ErrPartSkipped) {
+				t.Errorf("got error %v, want no part skipped outside lenient mode", err)
+			}
+		})
+	}
+}
+
+func TestLenientSkipsCorruptPart(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.xlsx"), filepath.Join(dir, "out.xlsx")
+	writeCorruptArchive(t, input)
+
+	var skipped []error
+	fp := NewFileProcessor()
+	fp.SetLenient(func(err error) { skipped = append(skipped, err) })
+	if err := fp.ProcessFile(context.Background(), input, output, failingTranslator{}); err != nil {
+		t.Fatal(err)
+	}
+	var stageErr *StageError
+	if len(skipped) != 1 || !errors.Is(skipped[0], ErrPartSkipped) || !errors.Is(skipped[0], zip.ErrChecksum) ||
+		!errors.As(skipped[0], &stageErr) || stageErr.Part != "xl/sharedStrings.xml" {
+		t.Errorf("got skipped %v, want the shared strings skipped with their checksum error", skipped)
+	}
+}
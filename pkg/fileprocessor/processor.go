@@ -2,6 +2,9 @@ package fileprocessor
 
 import (
 	"archive/zip"
+	"cmp"
+	"context"
+	"encoding/binary"
 	"exceltranslator/pkg/logger" // Import the logger package
 	"exceltranslator/pkg/textextractor"
 	"exceltranslator/pkg/translator"
@@ -9,12 +12,45 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
+// PartTexts holds the translatable texts extracted from one internal file, in document order.
+type PartTexts struct {
+	Name  string
+	Texts []string
+}
+
 type FileProcessor struct {
 	extractor *textextractor.Extractor
 	logger    *logger.Logger // Add logger instance
+	textOut   io.Writer      // Optional plain-text output of translations in document order
+	textParts []PartTexts    // Translations of the current file for textOut, in translation order
+	bilingual bool           // Keep the source text next to its translation, see SetOutputMode
+
+	sourceComments bool // Keep the source text of translated cells as comments, see SetSourceComments
+	deterministic  bool // Write every zip entry with a fixed modification time, see SetDeterministic
+
+	// cellStrings holds the shared strings shown in the cells selected by the cell style filter
+	// and ranges of the current file; nil translates all, see planCells
+	cellStrings map[int]bool
+	cellCopies  []int                     // Shared strings copied for the selected cells
+	cellIndices map[string]map[string]int // Copies shown by the selected cells, by worksheet part and cell
+
+	// mirrorIndices holds the copies shown by the selected cells of the mirror sheets of the
+	// worksheets in bilingual mode, by worksheet part and cell; nil if there are none
+	mirrorIndices map[string]map[string]int
+	mirrorParts   map[string]string // Mirror sheet parts by worksheet part, see planMirrorSheets
+
+	// onSkip receives the parts skipped in lenient mode; nil fails the file instead, see SetLenient
+	onSkip func(err error)
+
+	// beforeApply reviews every translation before it is written back, see SetBeforeApply
+	beforeApply func(src, dst string) (string, bool)
+
+	// onSheetRenames receives the renamed sheets of a workbook, see SetOnSheetRenames
+	onSheetRenames func(renames map[string]string)
 }
 
 func NewFileProcessor() *FileProcessor {
@@ -35,137 +71,447 @@ func (fp *FileProcessor) SetExtractorConfig(config textextractor.ExtractorConfig
 	fp.extractor = textextractor.NewExtractor(config)
 }
 
-// ProcessFile processes the input docx/xlsx file and saves the translated version to outputPath.
-// The translator performs translation operations and progress reporting.
-func (fp *FileProcessor) ProcessFile(inputPath string, outputPath string, trans translator.Translator) error {
+// SetTextOutput sets a writer that receives every translated segment in document order,
+// one segment per line, for proofreading. Line breaks within a segment are written as \n
+// (and \r), backslashes are doubled. A nil writer disables the text output.
+func (fp *FileProcessor) SetTextOutput(w io.Writer) {
+	fp.textOut = w
+}
+
+// SetBeforeApply sets a callback that reviews every translation before it is written to the output.
+// It returns the text to write and whether to apply it; returning false keeps the original text.
+// A nil callback applies all translations unchanged.
+func (fp *FileProcessor) SetBeforeApply(fn func(src, dst string) (string, bool)) {
+	fp.beforeApply = fn
+}
+
+// SetOnSheetRenames sets a callback that receives the renamed sheets of every workbook, keyed by
+// their original names, once its sheet names are translated; the map is empty when no sheet is
+// renamed. A nil callback disables it.
+func (fp *FileProcessor) SetOnSheetRenames(fn func(renames map[string]string)) {
+	fp.onSheetRenames = fn
+}
+
+// SetLenient makes ProcessFile copy parts that cannot be read or processed, e.g. of corrupt or
+// hand-edited files, unchanged instead of failing the whole file. Each skipped part is passed
+// to onSkip as a StageError wrapping ErrPartSkipped. A nil onSkip disables lenient mode.
+func (fp *FileProcessor) SetLenient(onSkip func(err error)) {
+	fp.onSkip = onSkip
+}
+
+// ProcessFile processes the input docx/xlsx/pptx/rtf/csv file and saves the translated version to outputPath.
+// The translator performs translation operations and progress reporting. Cancelling ctx stops
+// the processing and returns the context's error.
+func (fp *FileProcessor) ProcessFile(ctx context.Context, inputPath string, outputPath string, trans translator.Translator) error {
 	fp.logger.Infof("Processing file: %s", inputPath)
 
+	if textextractor.IsTextFile(inputPath) {
+		return fp.processTextFile(ctx, inputPath, outputPath, trans)
+	}
+
 	// Open the zip file
 	r, err := zip.OpenReader(inputPath)
 	if err != nil {
 		fp.logger.Errorf("Failed to open source file %s: %v", inputPath, err)
-		return fmt.Errorf("failed to open source file: %w", err)
+		return stageError(StageOpen, "", fmt.Errorf("failed to open source file: %w", err))
 	}
 	defer r.Close()
 
 	// Ensure output directory exists
 	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
 		fp.logger.Errorf("Failed to create output directory %s: %v", filepath.Dir(outputPath), err)
-		return fmt.Errorf("failed to create output directory: %w", err)
+		return stageError(StageWrite, "", fmt.Errorf("failed to create output directory: %w", err))
 	}
 
 	// Create the output file
 	outFile, err := os.Create(outputPath)
 	if err != nil {
 		fp.logger.Errorf("Failed to create output file %s: %v", outputPath, err)
-		return fmt.Errorf("failed to create output file: %w", err)
+		return stageError(StageWrite, "", fmt.Errorf("failed to create output file: %w", err))
 	}
 	defer outFile.Close()
 
 	// Create a zip writer
 	w := zip.NewWriter(outFile)
 	defer w.Close()
+	fp.textParts = nil
+
+	// In lenient mode a workbook whose styles or sheets cannot be read has none of its cells translated
+	if err := fp.planCells(r.File); err != nil {
+		if _, ok := skippable(err); !ok || fp.onSkip == nil {
+			return err
+		}
+		fp.logger.Warnf("Not translating cells: %v", err)
+	}
+
+	// Sheet names are translated before the other parts so that references to renamed
+	// sheets can be updated in formulas, charts and defined names
+	// In lenient mode a part that fails here is skipped and reported below, when it is processed again
+	// Workbooks with mirror sheets keep their sheet names, the mirror sheets get the translations
+	var translated, renames map[string]string
+	var edits *partEdits
+	fp.mirrorParts = nil
+	if fp.mirrorIndices != nil {
+		translated = make(map[string]string)
+		edits = &partEdits{edit: make(map[string]func(string) string)}
+		fp.mirrorParts, err = fp.planMirrorSheets(ctx, r.File, trans, translated, edits)
+	} else {
+		translated, renames, err = fp.translateSheetNames(ctx, r.File, trans)
+	}
+	if _, ok := skippable(err); ok && fp.onSkip != nil {
+		translated, renames, edits, fp.mirrorParts, err = nil, nil, nil, nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	if fp.sourceComments {
+		if translated == nil {
+			translated = make(map[string]string)
+		}
+		commentEdits, err := fp.planSourceComments(ctx, r.File, trans, translated)
+		if _, ok := skippable(err); ok && fp.onSkip != nil {
+			commentEdits, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+		edits = edits.merge(commentEdits)
+	}
 
 	// Iterate through the files in the archive
 	for _, f := range r.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		fp.logger.Tracef("Processing internal file: %s", f.Name)
-		err := fp.processZipFile(f, w, trans)
+		err := fp.processZipFile(ctx, f, w, trans, translated, renames, edits)
+		if stageErr, ok := skippable(err); ok && fp.onSkip != nil {
+			fp.logger.Warnf("Skipping %s, copied unchanged: %v", f.Name, err)
+			fp.onSkip(skippedError(stageErr))
+			err = fp.copyZipEntry(w, f)
+		}
 		if err != nil {
 			fp.logger.Errorf("Failed to process internal file %s: %v", f.Name, err)
 			return fmt.Errorf("failed to process file %s: %w", f.Name, err)
 		}
 	}
+	if err := edits.write(w, fp.stampHeader); err != nil {
+		fp.logger.Errorf("Failed to add parts: %v", err)
+		return err
+	}
+	if err := fp.writeText(r.File); err != nil {
+		fp.logger.Errorf("Failed to write text output: %v", err)
+		return stageError(StageWrite, "", fmt.Errorf("failed to write text output: %w", err))
+	}
 	fp.logger.Tracef("Finished processing file: %s", inputPath)
 	return nil
 }
 
-// processZipFile handles individual files within the zip archive.
-// It applies translation if the file is an XML document requiring text extraction.
-func (fp *FileProcessor) processZipFile(f *zip.File, w *zip.Writer, trans translator.Translator) error {
-	// Open the file inside the zip
-	rc, err := f.Open()
-	if err != nil {
-		fp.logger.Errorf("Failed to open file %s in zip: %v", f.Name, err)
-		return fmt.Errorf("failed to open file in zip %s: %w", f.Name, err)
+// ExtractTexts extracts the translatable texts from every internal file of the input docx/xlsx/pptx
+// (or from the rtf/csv document) without translating anything. Parts without translatable text are omitted.
+func (fp *FileProcessor) ExtractTexts(inputPath string) ([]PartTexts, error) {
+	if textextractor.IsTextFile(inputPath) {
+		return fp.extractTextFileTexts(inputPath)
 	}
-	defer rc.Close()
 
-	// Read content
-	contentBytes, err := io.ReadAll(rc)
+	r, err := zip.OpenReader(inputPath)
 	if err != nil {
-		fp.logger.Errorf("Failed to read content of %s: %v", f.Name, err)
-		return fmt.Errorf("failed to read content of %s: %w", f.Name, err)
+		fp.logger.Errorf("Failed to open source file %s: %v", inputPath, err)
+		return nil, stageError(StageOpen, "", fmt.Errorf("failed to open source file: %w", err))
 	}
-	content := string(contentBytes)
+	defer r.Close()
 
-	// Determine if this file needs processing
-	isXmlFile := strings.HasSuffix(f.Name, ".xml")
-	needsTranslation := false
+	if err := fp.planCells(r.File); err != nil {
+		return nil, err
+	}
 
-	if isXmlFile {
-		// Common for DOCX and XLSX
-		if strings.Contains(f.Name, "word/document.xml") ||
-			strings.Contains(f.Name, "word/header") ||
-			strings.Contains(f.Name, "word/footer") ||
-			strings.Contains(f.Name, "xl/sharedStrings.xml") ||
-			strings.Contains(f.Name, "xl/drawings/drawing") ||
-			strings.Contains(f.Name, "xl/comments") ||
-			strings.Contains(f.Name, "xl/workbook.xml") {
-			needsTranslation = true
+	var parts []PartTexts
+	for _, f := range r.File {
+		if !fp.extractor.Supports(f.Name) {
+			continue
 		}
-	}
 
-	var newContent string
-	if needsTranslation {
-		fp.logger.Tracef("Extracting and translating text from %s", f.Name)
+		content, err := readZipFile(f)
+		if err != nil {
+			fp.logger.Errorf("Failed to read content of %s: %v", f.Name, err)
+			return nil, err
+		}
 
-		// 1. Extract text
-		extractedContent, items, err := fp.extractor.Extract(content, f.Name)
+		content = fp.selectCells(f.Name, content)
+		if mirror, ok := fp.mirrorWorksheet(f.Name, content); ok {
+			content = mirror
+		} else if fp.keepsSource(f.Name) {
+			continue
+		}
+		_, items, err := fp.extract(f.Name, content)
 		if err != nil {
 			fp.logger.Errorf("Extraction failed for %s: %v", f.Name, err)
-			return fmt.Errorf("extraction failed for %s: %w", f.Name, err)
+			return nil, stageError(StageExtract, f.Name, fmt.Errorf("extraction failed for %s: %w", f.Name, err))
+		}
+		if len(items) == 0 {
+			continue
 		}
 
-		// 2. Translate text batch
 		texts := make([]string, len(items))
 		for i, item := range items {
 			texts[i] = item.Text
 		}
-		translations, err := trans.TranslateFileTexts(f.Name, texts)
+		parts = append(parts, PartTexts{Name: f.Name, Texts: texts})
+	}
+	return parts, nil
+}
+
+// translateSheetNames translates the sheet names of a workbook ahead of the other parts.
+// It returns the translated workbook part, keyed by name, and the renamed sheets (see textextractor.SheetRenames).
+func (fp *FileProcessor) translateSheetNames(ctx context.Context, files []*zip.File, trans translator.Translator) (map[string]string, map[string]string, error) {
+	for _, f := range files {
+		if textextractor.Phase(f.Name) != textextractor.PhaseSheet || !fp.extractor.Supports(f.Name) {
+			continue
+		}
+		content, err := readZipFile(f)
 		if err != nil {
-			fp.logger.Errorf("Translation failed for %s: %v", f.Name, err)
-			return fmt.Errorf("translation failed for %s: %w", f.Name, err)
+			fp.logger.Errorf("Failed to read content of %s: %v", f.Name, err)
+			return nil, nil, fmt.Errorf("failed to process file %s: %w", f.Name, err)
 		}
-
-		// 3. Apply replacements
-		newContent, err = fp.extractor.Apply(extractedContent, f.Name, items, translations)
+		newContent, err := fp.translatePart(ctx, f.Name, content, trans)
 		if err != nil {
-			fp.logger.Errorf("Replacement failed for %s: %v", f.Name, err)
-			return fmt.Errorf("replacement failed for %s: %w", f.Name, err)
+			return nil, nil, fmt.Errorf("failed to process file %s: %w", f.Name, err)
 		}
-		fp.logger.Tracef("Finished translating text from %s", f.Name)
-	} else {
-		newContent = content // No translation needed, use original content
+		// Translated formula results are cached values; Excel recomputes them when opening the workbook
+		if fp.extractor.Recalculate() {
+			newContent = textextractor.SetFullCalcOnLoad(newContent)
+		}
+		renamed := textextractor.RenamedSheets(content, newContent)
+		for old, name := range renamed {
+			fp.logger.Debugf("Renamed sheet %s to %s", old, name)
+		}
+		if fp.onSheetRenames != nil {
+			fp.onSheetRenames(renamed)
+		}
+		renames := textextractor.SheetRenames(content, newContent)
+		return map[string]string{f.Name: newContent}, renames, nil
+	}
+	return nil, nil, nil
+}
+
+// CountTexts returns the number of texts ProcessFile sends for translation from the input file,
+// counting repeated texts every time. It is used to report the progress of the whole document.
+func (fp *FileProcessor) CountTexts(inputPath string) (int, error) {
+	parts, err := fp.ExtractTexts(inputPath)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, part := range parts {
+		count += len(part.Texts)
+	}
+	return count, nil
+}
+
+// processZipFile handles individual files within the zip archive.
+// It applies translation if the file is an XML document requiring text extraction, updates
+// references to renamed sheets and applies the edits made after translation. Parts in
+// translated have been translated already.
+func (fp *FileProcessor) processZipFile(ctx context.Context, f *zip.File, w *zip.Writer, trans translator.Translator, translated, renames map[string]string, edits *partEdits) error {
+	if mirror, ok := fp.mirrorParts[f.Name]; ok {
+		if err := fp.translateMirror(ctx, f, mirror, trans, edits); err != nil {
+			return err
+		}
+	}
+
+	// Parts that stay unchanged (media, styles, binaries) are copied without being decompressed
+	// or held in memory
+	_, isTranslated := translated[f.Name]
+	edit := edits.of(f.Name)
+	supported := fp.extractor.Supports(f.Name) && !fp.keepsSource(f.Name)
+	if !isTranslated && !supported && (len(renames) == 0 || !textextractor.HasSheetRefs(f.Name)) && edit == nil && !fp.selectsCells(f.Name) {
 		fp.logger.Tracef("No translation needed for %s, copying directly.", f.Name)
+		if err := fp.copyZipEntry(w, f); err != nil {
+			fp.logger.Errorf("Failed to copy %s to zip: %v", f.Name, err)
+			return err
+		}
+		return nil
 	}
 
-	// Create a header for the new file in the zip writer, preserving original metadata
-	header := &zip.FileHeader{
-		Name:     f.Name,
-		Method:   f.Method,
-		Modified: f.Modified,
+	// Read content
+	content, err := readZipFile(f)
+	if err != nil {
+		fp.logger.Errorf("Failed to read content of %s: %v", f.Name, err)
+		return err
+	}
+	content = fp.selectCells(f.Name, content)
+
+	var newContent string
+	if part, ok := translated[f.Name]; ok {
+		newContent = part
+	} else if supported {
+		newContent, err = fp.translatePart(ctx, f.Name, content, trans)
+		if err != nil {
+			return err
+		}
+	} else {
+		newContent = content // Only sheet references are updated
 	}
+	if len(renames) > 0 && textextractor.HasSheetRefs(f.Name) {
+		newContent = textextractor.RenameSheetRefs(newContent, renames)
+	}
+	if edit != nil {
+		newContent = edit(newContent)
+	}
+	return fp.writeZipEntry(w, f, newContent)
+}
+
+// Extra field IDs that zip.Writer generates itself, see writeZipEntry.
+const (
+	zip64ExtraID   = 0x0001 // Zip64 sizes and offset
+	extTimeExtraID = 0x5455 // Extended timestamp, written for FileHeader.Modified
+)
 
-	wWrapper, err := w.CreateHeader(header)
+// writeZipEntry writes the new content of f to the zip writer, preserving its metadata.
+func (fp *FileProcessor) writeZipEntry(w *zip.Writer, f *zip.File, newContent string) error {
+	// Copy the original header, e.g. the flags (such as the UTF-8 name flag), comment and file
+	// attributes. The checksum, the sizes and the extra fields the writer adds are recomputed
+	// for the new content
+	header := f.FileHeader
+	header.CRC32 = 0
+	header.CompressedSize, header.CompressedSize64 = 0, 0
+	header.UncompressedSize, header.UncompressedSize64 = 0, 0
+	header.Extra = withoutExtraFields(f.Extra, zip64ExtraID, extTimeExtraID)
+	fp.stampHeader(&header)
+
+	wWrapper, err := w.CreateHeader(&header)
 	if err != nil {
 		fp.logger.Errorf("Failed to create zip entry for %s: %v", f.Name, err)
-		return fmt.Errorf("failed to create zip entry for %s: %w", f.Name, err)
+		return stageError(StageWrite, f.Name, fmt.Errorf("failed to create zip entry for %s: %w", f.Name, err))
 	}
 	_, err = wWrapper.Write([]byte(newContent))
 	if err != nil {
 		fp.logger.Errorf("Failed to write content for %s to zip: %v", f.Name, err)
-		return fmt.Errorf("failed to write content for %s to zip: %w", f.Name, err)
+		return stageError(StageWrite, f.Name, fmt.Errorf("failed to write content for %s to zip: %w", f.Name, err))
+	}
+
+	return nil
+}
+
+// withoutExtraFields returns a copy of the extra fields of a zip header without the fields with
+// the given IDs. A malformed remainder is dropped.
+func withoutExtraFields(extra []byte, ids ...uint16) []byte {
+	var kept []byte
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:])) + 4
+		if size > len(extra) {
+			break
+		}
+		if !slices.Contains(ids, id) {
+			kept = append(kept, extra[:size]...)
+		}
+		extra = extra[size:]
+	}
+	return kept
+}
+
+// translatePart extracts, translates and replaces the text of one document part.
+func (fp *FileProcessor) translatePart(ctx context.Context, name, content string, trans translator.Translator) (string, error) {
+	fp.logger.Tracef("Extracting and translating text from %s", name)
+
+	// 1. Extract text
+	extractedContent, items, err := fp.extract(name, content)
+	if err != nil {
+		fp.logger.Errorf("Extraction failed for %s: %v", name, err)
+		return "", stageError(StageExtract, name, fmt.Errorf("extraction failed for %s: %w", name, err))
+	}
+
+	// 2. Translate text batch
+	texts := make([]string, len(items))
+	for i, item := range items {
+		texts[i] = item.Text
+	}
+	translations, err := trans.TranslateFileTexts(ctx, name, texts)
+	if err != nil {
+		fp.logger.Errorf("Translation failed for %s: %v", name, err)
+		return "", stageError(StageTranslate, name, fmt.Errorf("translation failed for %s: %w", name, err))
+	}
+
+	translations = fp.review(texts, translations)
+	if fp.textOut != nil {
+		fp.textParts = append(fp.textParts, PartTexts{Name: name, Texts: translations})
+	}
+
+	// Keep the part as it is, including markup dropped by Extract, if nothing was translated
+	if slices.Equal(texts, translations) {
+		fp.logger.Tracef("No changes in %s", name)
+		return content, nil
+	}
+
+	// 3. Apply replacements
+	newContent, err := fp.extractor.Apply(extractedContent, name, items, fp.bilingualTexts(name, texts, translations))
+	if err != nil {
+		fp.logger.Errorf("Replacement failed for %s: %v", name, err)
+		return "", stageError(StageExtract, name, fmt.Errorf("replacement failed for %s: %w", name, err))
+	}
+	fp.logger.Tracef("Finished translating text from %s", name)
+	return newContent, nil
+}
+
+// review passes the translations to the before-apply callback, if any. Vetoed translations
+// are replaced by their source text.
+func (fp *FileProcessor) review(texts, translations []string) []string {
+	if fp.beforeApply == nil {
+		return translations
+	}
+	reviewed := make([]string, len(translations))
+	for i, dst := range translations {
+		if applied, ok := fp.beforeApply(texts[i], dst); ok {
+			reviewed[i] = applied
+		} else {
+			reviewed[i] = texts[i]
+		}
 	}
+	return reviewed
+}
+
+// textLineEscaper keeps every segment of the text output on one line.
+var textLineEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
 
+// writeText writes the translated segments of the current file to the text output, if any.
+// Parts are translated out of order, e.g. sheet names first, so they are written in the order
+// of files, the parts of the archive; a mirror sheet takes the place of its worksheet.
+func (fp *FileProcessor) writeText(files []*zip.File) error {
+	if fp.textOut == nil {
+		return nil
+	}
+	order := make(map[string]int, len(files))
+	for i, f := range files {
+		order[f.Name] = i
+	}
+	for part, mirror := range fp.mirrorParts {
+		order[mirror] = order[part]
+	}
+	parts := fp.textParts
+	fp.textParts = nil
+	slices.SortStableFunc(parts, func(a, b PartTexts) int { return cmp.Compare(order[a.Name], order[b.Name]) })
+	for _, part := range parts {
+		for _, t := range part.Texts {
+			if _, err := io.WriteString(fp.textOut, textLineEscaper.Replace(t)+"\n"); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
+
+// readZipFile reads the full content of a file inside the zip archive.
+func readZipFile(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", stageError(StageOpen, f.Name, fmt.Errorf("failed to open file in zip %s: %w", f.Name, err))
+	}
+	defer rc.Close()
+
+	contentBytes, err := io.ReadAll(rc)
+	if err != nil {
+		return "", stageError(StageOpen, f.Name, fmt.Errorf("failed to read content of %s: %w", f.Name, err))
+	}
+	return string(contentBytes), nil
+}
@@ -0,0 +1,148 @@
+package fileprocessor
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// prefixTranslator translates every text into "T " followed by the text.
+type prefixTranslator struct{}
+
+func (prefixTranslator) TranslateFileTexts(ctx context.Context, fileName string, texts []string) ([]string, error) {
+	translations := make([]string, len(texts))
+	for i, text := range texts {
+		translations[i] = "T " + text
+	}
+	return translations, nil
+}
+
+// testPart is an entry of a test archive with its header.
+type testPart struct {
+	header  zip.FileHeader
+	content string
+}
+
+// writeArchive writes the parts, in order, to a zip archive at path.
+func writeArchive(t *testing.T, path string, parts []testPart) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, part := range parts {
+		header := part.header
+		fw, err := w.CreateHeader(&header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(part.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readHeaders returns the headers of the central directory of a zip archive, in order.
+func readHeaders(t *testing.T, path string) []zip.FileHeader {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var headers []zip.FileHeader
+	for _, f := range r.File {
+		headers = append(headers, f.FileHeader)
+	}
+	return headers
+}
+
+func TestProcessFileKeepsEntries(t *testing.T) {
+	modified := time.Date(2023, 5, 17, 9, 30, 12, 0, time.UTC)
+	// A custom extra field (e.g. of the tool that wrote the file) that is not a timestamp
+	extra := []byte{0xfe, 0xca, 0x02, 0x00, 0x01, 0x02}
+	parts := []testPart{
+		{zip.FileHeader{Name: "[Content_Types].xml", Method: zip.Deflate, Modified: modified},
+			`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		{zip.FileHeader{Name: "xl/sharedStrings.xml", Method: zip.Deflate, Modified: modified, Comment: "strings", Extra: extra},
+			`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>收入</t></si></sst>`},
+		{zip.FileHeader{Name: "xl/media/image1.png", Method: zip.Store, Modified: modified, Extra: extra},
+			"\x89PNG\r\n\x1a\n"},
+		{zip.FileHeader{Name: "docProps/app.xml", Method: zip.Deflate, Modified: modified.Add(time.Hour), Comment: "properties"},
+			`<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties"><Application>Microsoft Excel</Application></Properties>`},
+		{zip.FileHeader{Name: "customXml/数据.xml", Method: zip.Deflate, Modified: modified},
+			`<data/>`},
+	}
+	parts[4].header.SetMode(0644)
+
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.xlsx"), filepath.Join(dir, "out.xlsx")
+	writeArchive(t, input, parts)
+	if err := NewFileProcessor().ProcessFile(context.Background(), input, output, prefixTranslator{}); err != nil {
+		t.Fatal(err)
+	}
+
+	in, out := readHeaders(t, input), readHeaders(t, output)
+	if len(out) != len(in) {
+		t.Fatalf("output has %d entries, want %d", len(out), len(in))
+	}
+	for i := range in {
+		if out[i].Name != in[i].Name {
+			t.Fatalf("entry %d is %s, want %s", i, out[i].Name, in[i].Name)
+		}
+		if in[i].Name != "xl/sharedStrings.xml" {
+			// Unmodified parts are copied with their header as it is
+			if !reflect.DeepEqual(out[i], in[i]) {
+				t.Errorf("header of %s changed:\n%+v\nwant\n%+v", in[i].Name, out[i], in[i])
+			}
+			continue
+		}
+		// The translated part keeps its metadata, only its checksum and sizes change
+		want, got := in[i], out[i]
+		if got.CRC32 == want.CRC32 || got.UncompressedSize64 == want.UncompressedSize64 {
+			t.Errorf("%s was not translated", want.Name)
+		}
+		got.CRC32, got.CompressedSize, got.CompressedSize64, got.UncompressedSize, got.UncompressedSize64 = 0, 0, 0, 0, 0
+		want.CRC32, want.CompressedSize, want.CompressedSize64, want.UncompressedSize, want.UncompressedSize64 = 0, 0, 0, 0, 0
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("header of %s changed:\n%+v\nwant\n%+v", want.Name, got, want)
+		}
+	}
+}
+
+func TestTextOutputOrder(t *testing.T) {
+	const main = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+	// The workbook, whose sheet names are translated first, comes after the shared strings
+	parts := []testPart{
+		{zip.FileHeader{Name: "[Content_Types].xml"}, `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		{zip.FileHeader{Name: "xl/sharedStrings.xml"}, `<sst ` + main + `><si><t>收入</t></si><si><t xml:space="preserve">第一行
+第二行</t></si><si><t>C:\报表</t></si></sst>`},
+		{zip.FileHeader{Name: "xl/workbook.xml"}, `<workbook ` + main + `><sheets><sheet name="数据" sheetId="1"/></sheets></workbook>`},
+		{zip.FileHeader{Name: "xl/comments1.xml"}, `<comments ` + main + `><commentList><comment ref="A1"><text><t>备注</t></text></comment></commentList></comments>`},
+	}
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.xlsx"), filepath.Join(dir, "out.xlsx")
+	writeArchive(t, input, parts)
+
+	var text strings.Builder
+	fp := NewFileProcessor()
+	fp.SetTextOutput(&text)
+	if err := fp.ProcessFile(context.Background(), input, output, prefixTranslator{}); err != nil {
+		t.Fatal(err)
+	}
+	// One line per segment, in the order of the parts
+	want := "T 收入\nT 第一行\\n第二行\nT C:\\\\报表\nT 数据\nT 备注\n"
+	if text.String() != want {
+		t.Errorf("text output = %q, want %q", text.String(), want)
+	}
+}
@@ -0,0 +1,83 @@
+package fileprocessor
+
+import (
+	"archive/zip"
+	"exceltranslator/pkg/textextractor"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RepairSheetNames makes the sheet names of the xlsx at inputPath valid and unique without
+// translating anything and saves the workbook to outputPath, updating references to renamed
+// sheets (see textextractor.RepairSheetNames). It returns the renamed sheets, keyed by the
+// lower-cased old name; sheets renamed because they shared a name are not included.
+func (fp *FileProcessor) RepairSheetNames(inputPath, outputPath string) (map[string]string, error) {
+	// The output would overwrite the workbook while it is being read
+	if absInput, err := filepath.Abs(inputPath); err == nil {
+		if absOutput, err := filepath.Abs(outputPath); err == nil && absInput == absOutput {
+			return nil, stageError(StageWrite, "", fmt.Errorf("output file must differ from input file"))
+		}
+	}
+
+	r, err := zip.OpenReader(inputPath)
+	if err != nil {
+		fp.logger.Errorf("Failed to open source file %s: %v", inputPath, err)
+		return nil, stageError(StageOpen, "", fmt.Errorf("failed to open source file: %w", err))
+	}
+	defer r.Close()
+
+	var workbook *zip.File
+	for _, f := range r.File {
+		if textextractor.Phase(f.Name) == textextractor.PhaseSheet {
+			workbook = f
+			break
+		}
+	}
+	if workbook == nil {
+		return nil, stageError(StageOpen, "", fmt.Errorf("%s is not a workbook", inputPath))
+	}
+	content, err := readZipFile(workbook)
+	if err != nil {
+		fp.logger.Errorf("Failed to read content of %s: %v", workbook.Name, err)
+		return nil, err
+	}
+	repaired, renames := textextractor.RepairSheetNames(content)
+	for old, renamed := range renames {
+		fp.logger.Infof("Renamed sheet %s to %s", old, renamed)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return nil, stageError(StageWrite, "", fmt.Errorf("failed to create output directory: %w", err))
+	}
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		fp.logger.Errorf("Failed to create output file %s: %v", outputPath, err)
+		return nil, stageError(StageWrite, "", fmt.Errorf("failed to create output file: %w", err))
+	}
+	defer outFile.Close()
+	w := zip.NewWriter(outFile)
+
+	for _, f := range r.File {
+		switch {
+		case f == workbook:
+			err = fp.writeZipEntry(w, f, textextractor.RenameSheetRefs(repaired, renames))
+		case len(renames) > 0 && textextractor.HasSheetRefs(f.Name):
+			var part string
+			if part, err = readZipFile(f); err == nil {
+				err = fp.writeZipEntry(w, f, textextractor.RenameSheetRefs(part, renames))
+			}
+		default:
+			if err = w.Copy(f); err != nil {
+				err = stageError(StageWrite, f.Name, fmt.Errorf("failed to copy %s to zip: %w", f.Name, err))
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to process file %s: %w", f.Name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, stageError(StageWrite, "", fmt.Errorf("failed to write output file: %w", err))
+	}
+	return renames, nil
+}
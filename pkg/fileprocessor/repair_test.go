@@ -0,0 +1,63 @@
+package fileprocessor
+
+import (
+	"archive/zip"
+	"errors"
+	"exceltranslator/pkg/textextractor"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRepairSheetNames(t *testing.T) {
+	long := "Quarterly Sales Summary 2024 Q1 v2" // Over the 31-character limit
+	truncated := "Quarterly Sales Summary 2024 Q1"
+	sheets := ""
+	for i, name := range []string{"销售", "销售", "Summary", "SUMMARY", long} {
+		sheets += fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, name, i+1, i+1)
+	}
+	formula := func(sheet string) string {
+		return `<worksheet ` + testMain + `><sheetData><row r="1"><c r="A1"><f>'` + sheet + `'!A1</f></c></row></sheetData></worksheet>`
+	}
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.xlsx"), filepath.Join(dir, "out.xlsx")
+	writeArchive(t, input, []testPart{
+		{zip.FileHeader{Name: "[Content_Types].xml"}, `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		{zip.FileHeader{Name: "xl/workbook.xml"}, `<workbook ` + testMain + ` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheets + `</sheets></workbook>`},
+		{zip.FileHeader{Name: "xl/worksheets/sheet1.xml"}, formula(long)},
+		{zip.FileHeader{Name: "xl/sharedStrings.xml"}, `<sst ` + testMain + `><si><t>销售</t></si></sst>`},
+	})
+
+	renames, err := NewFileProcessor().RepairSheetNames(input, output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sheets renamed because they shared a name are not reported
+	if want := map[string]string{strings.ToLower(long): truncated}; !reflect.DeepEqual(renames, want) {
+		t.Errorf("got renames %q, want %q", renames, want)
+	}
+
+	var names []string
+	for _, sheet := range textextractor.WorkbookSheets(readArchivePart(t, output, "xl/workbook.xml")) {
+		names = append(names, sheet.Name)
+	}
+	if want := []string{"销售", "销售 (2)", "Summary", "SUMMARY (2)", truncated}; !slices.Equal(names, want) {
+		t.Errorf("got sheets %q, want %q", names, want)
+	}
+	if got, want := readArchivePart(t, output, "xl/worksheets/sheet1.xml"), formula(truncated); got != want {
+		t.Errorf("got worksheet\n%s\nwant\n%s", got, want)
+	}
+	// Other parts are copied, nothing is translated
+	if got, want := readArchivePart(t, output, "xl/sharedStrings.xml"), `<sst `+testMain+`><si><t>销售</t></si></sst>`; got != want {
+		t.Errorf("got shared strings\n%s\nwant\n%s", got, want)
+	}
+
+	// The output must not overwrite the input
+	var stageErr *StageError
+	if _, err := NewFileProcessor().RepairSheetNames(input, input); !errors.As(err, &stageErr) || stageErr.Stage != StageWrite {
+		t.Errorf("got error %v repairing in place, want a write error", err)
+	}
+}
@@ -0,0 +1,106 @@
+package fileprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrSegmentMismatch is returned by ImportSegments when a segment's source text differs from
+// the text extracted from the document, i.e. the JSON was exported from another file or with
+// another extractor configuration.
+var ErrSegmentMismatch = errors.New("segment does not match the source document")
+
+// Segment is one translatable text of a document in the JSON exchange format.
+type Segment struct {
+	ID          string `json:"id"`          // Stable ID: the internal file and the index of the text in it, e.g. "xl/sharedStrings.xml#3"
+	Part        string `json:"part"`        // Internal file holding the text
+	Source      string `json:"source"`      // Extracted source text
+	Translation string `json:"translation"` // Translation to apply; empty keeps the source text
+}
+
+// SegmentFile is the JSON document written by ExportSegments and read by ImportSegments.
+type SegmentFile struct {
+	File     string    `json:"file"` // Base name of the exported document
+	Segments []Segment `json:"segments"`
+}
+
+// segmentID returns the ID of the index-th text of an internal file. Extraction is deterministic,
+// so the ID identifies the same text as long as the document and extractor configuration are unchanged.
+func segmentID(part string, index int) string {
+	return part + "#" + strconv.Itoa(index)
+}
+
+// ExportSegments writes every translatable text of the input file as JSON, so that it can be
+// translated with an external tool and applied with ImportSegments.
+func (fp *FileProcessor) ExportSegments(inputPath string, w io.Writer) error {
+	parts, err := fp.ExtractTexts(inputPath)
+	if err != nil {
+		return err
+	}
+
+	doc := SegmentFile{File: filepath.Base(inputPath), Segments: []Segment{}}
+	for _, part := range parts {
+		for i, text := range part.Texts {
+			doc.Segments = append(doc.Segments, Segment{
+				ID:     segmentID(part.Name, i),
+				Part:   part.Name,
+				Source: text,
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return stageError(StageWrite, "", fmt.Errorf("failed to write segments: %w", err))
+	}
+	fp.logger.Infof("Exported %d segments from %s", len(doc.Segments), inputPath)
+	return nil
+}
+
+// ImportSegments applies the translations of a JSON document written by ExportSegments to the
+// input file and saves the result to outputPath. Segments that are missing or have an empty
+// translation keep their source text.
+func (fp *FileProcessor) ImportSegments(ctx context.Context, inputPath, outputPath string, r io.Reader) error {
+	var doc SegmentFile
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return stageError(StageOpen, "", fmt.Errorf("failed to read segments: %w", err))
+	}
+
+	byID := make(map[string]Segment, len(doc.Segments))
+	for _, seg := range doc.Segments {
+		byID[seg.ID] = seg
+	}
+	fp.logger.Infof("Importing %d segments into %s", len(doc.Segments), inputPath)
+	return fp.ProcessFile(ctx, inputPath, outputPath, segmentTranslator{byID: byID})
+}
+
+// segmentTranslator is a Translator that looks up the translations of an imported segment file.
+type segmentTranslator struct {
+	byID map[string]Segment
+}
+
+func (t segmentTranslator) TranslateFileTexts(ctx context.Context, fileName string, texts []string) ([]string, error) {
+	results := make([]string, len(texts))
+	for i, text := range texts {
+		results[i] = text
+		seg, ok := t.byID[segmentID(fileName, i)]
+		if !ok {
+			continue
+		}
+		if seg.Source != text {
+			return nil, fmt.Errorf("%w: %s", ErrSegmentMismatch, seg.ID)
+		}
+		if strings.TrimSpace(seg.Translation) != "" {
+			results[i] = seg.Translation
+		}
+	}
+	return results, nil
+}
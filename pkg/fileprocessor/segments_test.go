@@ -0,0 +1,140 @@
+package fileprocessor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testMain = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+
+// writeSegmentsWorkbook writes a workbook with the sheet 数据, whose cells show the shared
+// strings 收入, 成本 and 收入 again.
+func writeSegmentsWorkbook(t *testing.T, path string) {
+	t.Helper()
+	writeArchive(t, path, []testPart{
+		{zip.FileHeader{Name: "[Content_Types].xml"}, `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		{zip.FileHeader{Name: "xl/workbook.xml"}, `<workbook ` + testMain + ` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="数据" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{zip.FileHeader{Name: "xl/_rels/workbook.xml.rels"}, `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+		{zip.FileHeader{Name: "xl/worksheets/sheet1.xml"}, `<worksheet ` + testMain + `><sheetData><row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c></row></sheetData></worksheet>`},
+		{zip.FileHeader{Name: "xl/sharedStrings.xml"}, `<sst ` + testMain + `><si><t>收入</t></si><si><t>成本</t></si><si><t>收入</t></si></sst>`},
+	})
+}
+
+// readArchivePart returns the content of a part of a zip archive.
+func readArchivePart(t *testing.T, path, name string) string {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		b, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	t.Fatalf("%s has no part %s", path, name)
+	return ""
+}
+
+// exportSegments exports the segments of the input file and decodes them again.
+func exportSegments(t *testing.T, input string) SegmentFile {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := NewFileProcessor().ExportSegments(input, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc SegmentFile
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// importSegments encodes the segments and imports them into the input file.
+func importSegments(t *testing.T, input, output string, doc SegmentFile) error {
+	t.Helper()
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewFileProcessor().ImportSegments(context.Background(), input, output, bytes.NewReader(b))
+}
+
+func TestSegmentsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.xlsx"), filepath.Join(dir, "out.xlsx")
+	writeSegmentsWorkbook(t, input)
+
+	doc := exportSegments(t, input)
+	want := SegmentFile{File: "in.xlsx", Segments: []Segment{
+		{ID: "xl/workbook.xml#0", Part: "xl/workbook.xml", Source: "数据"},
+		{ID: "xl/sharedStrings.xml#0", Part: "xl/sharedStrings.xml", Source: "收入"},
+		{ID: "xl/sharedStrings.xml#1", Part: "xl/sharedStrings.xml", Source: "成本"},
+		{ID: "xl/sharedStrings.xml#2", Part: "xl/sharedStrings.xml", Source: "收入"},
+	}}
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("exported %+v, want %+v", doc, want)
+	}
+
+	// Repeated texts are translated by their ID; empty and missing translations keep the source text
+	doc.Segments[0].Translation = "Data"
+	doc.Segments[1].Translation = "Revenue"
+	doc.Segments[2].Translation = " "
+	doc.Segments = doc.Segments[:3]
+	if err := importSegments(t, input, output, doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readArchivePart(t, output, "xl/sharedStrings.xml"), `<sst `+testMain+`><si><t>Revenue</t></si><si><t>成本</t></si><si><t>收入</t></si></sst>`; got != want {
+		t.Errorf("got shared strings\n%s\nwant\n%s", got, want)
+	}
+	if got, want := readArchivePart(t, output, "xl/workbook.xml"), `<sheet name="Data"`; !strings.Contains(got, want) {
+		t.Errorf("got workbook\n%s\nwant it to contain %s", got, want)
+	}
+
+	// The translated file exports its translations as sources with the same IDs
+	translated := exportSegments(t, output)
+	if len(translated.Segments) != len(want.Segments) {
+		t.Fatalf("re-exported %d segments, want %d", len(translated.Segments), len(want.Segments))
+	}
+	for i, seg := range translated.Segments {
+		source := want.Segments[i].Source
+		if i < 2 {
+			source = doc.Segments[i].Translation
+		}
+		if seg.ID != want.Segments[i].ID || seg.Source != source {
+			t.Errorf("re-exported segment %d = %+v, want %s with source %q", i, seg, want.Segments[i].ID, source)
+		}
+	}
+}
+
+func TestImportSegmentsMismatch(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.xlsx"), filepath.Join(dir, "out.xlsx")
+	writeSegmentsWorkbook(t, input)
+
+	doc := exportSegments(t, input)
+	doc.Segments[2].Source = "利润"
+	doc.Segments[2].Translation = "Profit"
+	if err := importSegments(t, input, output, doc); !errors.Is(err, ErrSegmentMismatch) {
+		t.Errorf("got error %v, want ErrSegmentMismatch", err)
+	}
+}
@@ -0,0 +1,125 @@
+package fileprocessor
+
+import (
+	"archive/zip"
+	"exceltranslator/pkg/textextractor"
+	"fmt"
+)
+
+// SheetTexts holds the cell texts ProcessFile sends for translation from a workbook by the
+// sheet showing them, to report progress per sheet. A shared string shown in several sheets
+// counts for the first of them in workbook order; one shown in no sheet counts for none.
+type SheetTexts struct {
+	Sheets []string       // Names of the sheets showing texts, in workbook order
+	Totals map[string]int // Number of texts by sheet name
+
+	// owners holds the sheet of every occurrence of a text in the shared strings, in document
+	// order, "" for those shown in no sheet
+	owners map[string][]string
+}
+
+// Sheet returns the sheet of the next occurrence of a text translated in a part, or "" if the
+// part is not the shared strings of a workbook or no sheet shows the text. It is called once
+// per translated occurrence; occurrences of the same text are told apart by their order only.
+func (s *SheetTexts) Sheet(part, text string) string {
+	owners := s.owners[text]
+	if part != sharedStringsPart || len(owners) == 0 {
+		return ""
+	}
+	s.owners[text] = owners[1:]
+	return owners[0]
+}
+
+// SheetTexts counts the cell texts of the input file by sheet, following the cell selection
+// of ProcessFile. Documents other than workbooks have no sheets.
+func (fp *FileProcessor) SheetTexts(inputPath string) (*SheetTexts, error) {
+	s := &SheetTexts{Totals: make(map[string]int), owners: make(map[string][]string)}
+	if textextractor.IsTextFile(inputPath) {
+		return s, nil
+	}
+
+	r, err := zip.OpenReader(inputPath)
+	if err != nil {
+		fp.logger.Errorf("Failed to open source file %s: %v", inputPath, err)
+		return nil, stageError(StageOpen, "", fmt.Errorf("failed to open source file: %w", err))
+	}
+	defer r.Close()
+
+	if err := fp.planCells(r.File); err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		byName[f.Name] = f
+	}
+	read := func(name string) (string, error) {
+		f, ok := byName[name]
+		if !ok {
+			return "", nil
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			fp.logger.Errorf("Failed to read content of %s: %v", name, err)
+			return "", fmt.Errorf("failed to process file %s: %w", name, err)
+		}
+		return fp.selectCells(name, content), nil
+	}
+	if _, ok := byName[sharedStringsPart]; !ok || !fp.extractor.Supports(sharedStringsPart) {
+		return s, nil
+	}
+
+	workbook, err := read(workbookPart)
+	if err != nil {
+		return nil, err
+	}
+	rels, err := read(workbookRelsPart)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sheet of each shared string, including the copies shown by mirror sheets in bilingual mode
+	owner := make(map[int]string)
+	var sheets []string
+	for _, sheet := range textextractor.WorkbookSheets(workbook) {
+		name := relTarget(rels, workbookPart, func(attrs string) bool { return relAttr(attrs, "Id") == sheet.RelID })
+		content, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, sheet.Name)
+		for _, cell := range textextractor.SharedStringCells(content) {
+			if _, ok := owner[cell.Index]; !ok {
+				owner[cell.Index] = sheet.Name
+			}
+		}
+		for _, index := range fp.mirrorIndices[name] {
+			if _, ok := owner[index]; !ok {
+				owner[index] = sheet.Name
+			}
+		}
+	}
+
+	sst, err := read(sharedStringsPart)
+	if err != nil {
+		return nil, err
+	}
+	extracted, items, err := fp.extract(sharedStringsPart, sst)
+	if err != nil {
+		fp.logger.Errorf("Extraction failed for %s: %v", sharedStringsPart, err)
+		return nil, stageError(StageExtract, sharedStringsPart, fmt.Errorf("extraction failed for %s: %w", sharedStringsPart, err))
+	}
+	for i, index := range textextractor.SharedStringIndices(extracted, items) {
+		sheet := owner[index]
+		s.owners[items[i].Text] = append(s.owners[items[i].Text], sheet)
+		if sheet != "" {
+			s.Totals[sheet]++
+		}
+	}
+	for _, sheet := range sheets {
+		if s.Totals[sheet] > 0 {
+			s.Sheets = append(s.Sheets, sheet)
+		}
+	}
+	return s, nil
+}
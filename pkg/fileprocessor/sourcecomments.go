@@ -0,0 +1,464 @@
+package fileprocessor
+
+import (
+	"archive/zip"
+	"context"
+	"exceltranslator/pkg/textextractor"
+	"exceltranslator/pkg/translator"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// sourceCommentAuthor is the author of the comments holding the source text of cells
+	sourceCommentAuthor = "Source"
+
+	commentsRelType     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments"
+	vmlDrawingRelType   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing"
+	commentsContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.comments+xml"
+	vmlContentType      = "application/vnd.openxmlformats-officedocument.vmlDrawing"
+	relsNamespace       = "http://schemas.openxmlformats.org/package/2006/relationships"
+	sharedStringsPart   = "xl/sharedStrings.xml"
+	contentTypesPart    = "[Content_Types].xml"
+)
+
+var (
+	relationshipRegex = regexp.MustCompile(`<Relationship\b[^>]*>`)
+	relIDNumberRegex  = regexp.MustCompile(`\sId="rId(\d+)"`)
+	commentRefRegex   = regexp.MustCompile(`<(?:\w+:)?comment\b[^>]*?\sref="([^"]*)"`)
+	authorRegex       = regexp.MustCompile(`<(?:\w+:)?author\b`)
+	authorsEndRegex   = regexp.MustCompile(`</(\w+:)?authors>`)
+	commentListRegex  = regexp.MustCompile(`</(\w+:)?commentList>`)
+	vmlIDMapRegex     = regexp.MustCompile(`<o:idmap\b[^>]*?\sdata="(\d+)`)
+	vmlShapeIDRegex   = regexp.MustCompile(`\sid="_x0000_s(\d+)"`)
+	vmlDefaultRegex   = regexp.MustCompile(`(?i)<Default\b[^>]*?\sExtension="vml"`)
+)
+
+// vmlNoteShapeType is the shape type of comment boxes in VML drawings.
+const vmlNoteShapeType = `<v:shapetype id="_x0000_t202" coordsize="21600,21600" o:spt="202" path="m,l,21600r21600,l21600,xe"><v:stroke joinstyle="miter"/><v:path gradientshapeok="t" o:connecttype="rect"/></v:shapetype>`
+
+// partEdits holds changes made to a document after translation: edits of existing parts,
+// applied to their translated content, and parts added at the end of the archive.
+type partEdits struct {
+	edit  map[string]func(string) string
+	added []addedPart
+}
+
+// addedPart is a part that does not exist in the input document.
+type addedPart struct {
+	name    string
+	content string
+}
+
+// add appends fn to the edits of the part.
+func (e *partEdits) add(name string, fn func(string) string) {
+	if prev, ok := e.edit[name]; ok {
+		e.edit[name] = func(content string) string { return fn(prev(content)) }
+		return
+	}
+	e.edit[name] = fn
+}
+
+// merge adds the edits and parts of other after those of e and returns the result; either may be nil.
+func (e *partEdits) merge(other *partEdits) *partEdits {
+	if e == nil {
+		return other
+	}
+	if other == nil {
+		return e
+	}
+	for name, fn := range other.edit {
+		e.add(name, fn)
+	}
+	e.added = append(e.added, other.added...)
+	return e
+}
+
+// set sets the content of an added part.
+func (e *partEdits) set(name, content string) {
+	for i := range e.added {
+		if e.added[i].name == name {
+			e.added[i].content = content
+		}
+	}
+}
+
+// of returns the edit of the part, or nil if it is not edited. A nil partEdits edits nothing.
+func (e *partEdits) of(name string) func(string) string {
+	if e == nil {
+		return nil
+	}
+	return e.edit[name]
+}
+
+// write adds the new parts to the archive, passing their headers to stamp first. A nil
+// partEdits adds nothing.
+func (e *partEdits) write(w *zip.Writer, stamp func(*zip.FileHeader)) error {
+	if e == nil {
+		return nil
+	}
+	for _, part := range e.added {
+		header := &zip.FileHeader{Name: part.name, Method: zip.Deflate}
+		stamp(header)
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			return stageError(StageWrite, part.name, fmt.Errorf("failed to create zip entry for %s: %w", part.name, err))
+		}
+		if _, err := io.WriteString(fw, part.content); err != nil {
+			return stageError(StageWrite, part.name, fmt.Errorf("failed to write content for %s to zip: %w", part.name, err))
+		}
+	}
+	return nil
+}
+
+// sourceComment is a comment holding the source text of a translated cell.
+type sourceComment struct {
+	ref      string
+	col, row int
+	text     string
+}
+
+// SetSourceComments enables keeping the source text of translated workbook cells as cell
+// comments, so that reviewers can hover a cell to see it. Cells that already have a comment
+// are skipped.
+func (fp *FileProcessor) SetSourceComments(enabled bool) {
+	fp.sourceComments = enabled
+}
+
+// planSourceComments translates the shared strings of a workbook ahead of the other parts,
+// adding them to translated, and returns the edits that add the source text of the translated
+// cells as comments. It returns nil if the document is not a workbook or nothing was translated.
+func (fp *FileProcessor) planSourceComments(ctx context.Context, files []*zip.File, trans translator.Translator, translated map[string]string) (*partEdits, error) {
+	byName := make(map[string]*zip.File, len(files))
+	for _, f := range files {
+		byName[f.Name] = f
+	}
+	sst, ok := byName[sharedStringsPart]
+	if !ok || !fp.extractor.Supports(sst.Name) {
+		return nil, nil
+	}
+
+	before, err := readZipFile(sst)
+	if err != nil {
+		fp.logger.Errorf("Failed to read content of %s: %v", sst.Name, err)
+		return nil, fmt.Errorf("failed to process file %s: %w", sst.Name, err)
+	}
+	before = fp.selectCells(sst.Name, before)
+	after, err := fp.translatePart(ctx, sst.Name, before, trans)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process file %s: %w", sst.Name, err)
+	}
+	translated[sst.Name] = after
+
+	src, dst := textextractor.SharedStrings(before), textextractor.SharedStrings(after)
+	if len(src) != len(dst) {
+		fp.logger.Warnf("Not adding source comments: shared strings changed in number")
+		return nil, nil
+	}
+
+	edits := &partEdits{edit: make(map[string]func(string) string)}
+	vmlBlock := nextVMLBlock(files)
+	total := 0
+	for _, f := range files {
+		if path.Dir(f.Name) != "xl/worksheets" || !strings.HasSuffix(f.Name, ".xml") {
+			continue
+		}
+		sheet, err := readZipFile(f)
+		if err != nil {
+			fp.logger.Errorf("Failed to read content of %s: %v", f.Name, err)
+			return nil, fmt.Errorf("failed to process file %s: %w", f.Name, err)
+		}
+		sheet = fp.selectCells(f.Name, sheet)
+
+		relsName := path.Join(path.Dir(f.Name), "_rels", path.Base(f.Name)+".rels")
+		rels := ""
+		if rf, ok := byName[relsName]; ok {
+			if rels, err = readZipFile(rf); err != nil {
+				return nil, fmt.Errorf("failed to process file %s: %w", relsName, err)
+			}
+		}
+		commentsName := relTarget(rels, f.Name, func(attrs string) bool { return relAttr(attrs, "Type") == commentsRelType })
+		vmlID := textextractor.LegacyDrawingID(sheet)
+		vmlName := ""
+		if vmlID != "" {
+			vmlName = relTarget(rels, f.Name, func(attrs string) bool { return relAttr(attrs, "Id") == vmlID })
+		}
+		// A relationship to a missing part cannot be extended, and a second one would make the sheet invalid
+		if commentsName != "" && byName[commentsName] == nil || vmlID != "" && byName[vmlName] == nil {
+			fp.logger.Warnf("Not adding source comments to %s: it refers to a missing comments or drawing part", f.Name)
+			continue
+		}
+
+		// Cells that already have a comment keep it
+		commented := make(map[string]bool)
+		if cf, ok := byName[commentsName]; ok {
+			existing, err := readZipFile(cf)
+			if err != nil {
+				return nil, fmt.Errorf("failed to process file %s: %w", commentsName, err)
+			}
+			for _, m := range commentRefRegex.FindAllStringSubmatch(existing, -1) {
+				commented[m[1]] = true
+			}
+		}
+		var comments []sourceComment
+		for _, cell := range textextractor.SharedStringCells(sheet) {
+			if cell.Index >= len(src) || src[cell.Index] == dst[cell.Index] || commented[cell.Ref] {
+				continue
+			}
+			col, row, ok := textextractor.CellPosition(cell.Ref)
+			if !ok {
+				continue
+			}
+			commented[cell.Ref] = true
+			comments = append(comments, sourceComment{ref: cell.Ref, col: col, row: row, text: src[cell.Index]})
+		}
+		if len(comments) == 0 {
+			continue
+		}
+		total += len(comments)
+
+		var newRels []string
+		if _, ok := byName[commentsName]; ok {
+			edits.add(commentsName, func(content string) string { return appendComments(content, comments) })
+		} else {
+			commentsName = unusedPartName(byName, edits, "xl/comments", ".xml")
+			edits.added = append(edits.added, addedPart{commentsName, newCommentsPart(comments)})
+			newRels = append(newRels, relationship(commentsRelType, commentsName, f.Name))
+			edits.add(contentTypesPart, func(content string) string {
+				return addContentType(content, fmt.Sprintf(`<Override PartName="/%s" ContentType="%s"/>`, commentsName, commentsContentType))
+			})
+		}
+		if _, ok := byName[vmlName]; ok {
+			edits.add(vmlName, func(content string) string { return appendNoteShapes(content, comments) })
+		} else {
+			vmlName = unusedPartName(byName, edits, "xl/drawings/vmlDrawing", ".vml")
+			edits.added = append(edits.added, addedPart{vmlName, newVMLDrawing(vmlBlock, comments)})
+			vmlBlock++
+			vmlID = nextRelID(rels, len(newRels))
+			newRels = append(newRels, relationship(vmlDrawingRelType, vmlName, f.Name))
+			edits.add(f.Name, func(content string) string { return textextractor.AddLegacyDrawing(content, vmlID) })
+			edits.add(contentTypesPart, addVMLContentType)
+		}
+
+		if len(newRels) > 0 {
+			if rels == "" {
+				edits.added = append(edits.added, addedPart{relsName, newRelsPart(rels, newRels)})
+			} else {
+				edits.add(relsName, func(content string) string { return newRelsPart(content, newRels) })
+			}
+		}
+	}
+	if total == 0 {
+		return nil, nil
+	}
+	fp.logger.Infof("Keeping the source text of %d cells as comments", total)
+	return edits, nil
+}
+
+// relAttr returns the value of an attribute of a relationship element.
+func relAttr(element, name string) string {
+	m := regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `="([^"]*)"`).FindStringSubmatch(element)
+	if m == nil {
+		return ""
+	}
+	return html.UnescapeString(m[1])
+}
+
+// relTarget returns the part name targeted by the first relationship of source that matches,
+// or "" if there is none.
+func relTarget(rels, source string, match func(element string) bool) string {
+	for _, element := range relationshipRegex.FindAllString(rels, -1) {
+		if match(element) && relAttr(element, "TargetMode") != "External" {
+			target := relAttr(element, "Target")
+			if strings.HasPrefix(target, "/") {
+				return strings.TrimPrefix(target, "/")
+			}
+			return path.Join(path.Dir(source), target)
+		}
+	}
+	return ""
+}
+
+// nextRelID returns an unused relationship id, skipping the given number of ids about to be added.
+func nextRelID(rels string, pending int) string {
+	n := 0
+	for _, m := range relIDNumberRegex.FindAllStringSubmatch(rels, -1) {
+		if v, err := strconv.Atoi(m[1]); err == nil {
+			n = max(n, v)
+		}
+	}
+	return fmt.Sprintf("rId%d", n+pending+1)
+}
+
+// relationship returns a relationship element, without its id, from source to the target part.
+// newRelsPart assigns the ids.
+func relationship(relType, target, source string) string {
+	rel, err := relativePath(path.Dir(source), target)
+	if err != nil {
+		rel = "/" + target
+	}
+	return fmt.Sprintf(`Type="%s" Target="%s"`, relType, rel)
+}
+
+// relativePath returns target relative to the directory dir, both given as part names.
+func relativePath(dir, target string) (string, error) {
+	from, to := strings.Split(dir, "/"), strings.Split(target, "/")
+	i := 0
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+	if i == 0 {
+		return "", fmt.Errorf("no common directory")
+	}
+	return strings.Repeat("../", len(from)-i) + strings.Join(to[i:], "/"), nil
+}
+
+// newRelsPart adds the relationships to a relationships part, or creates the part if rels is empty.
+func newRelsPart(rels string, added []string) string {
+	if rels == "" {
+		rels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+			`<Relationships xmlns="` + relsNamespace + `"></Relationships>`
+	}
+	var sb strings.Builder
+	for i, rel := range added {
+		fmt.Fprintf(&sb, `<Relationship Id="%s" %s/>`, nextRelID(rels, i), rel)
+	}
+	end := strings.LastIndex(rels, "</Relationships>")
+	if end < 0 {
+		return rels
+	}
+	return rels[:end] + sb.String() + rels[end:]
+}
+
+// unusedPartName returns the first name prefix + n + ext that is neither in the document nor added.
+func unusedPartName(byName map[string]*zip.File, edits *partEdits, prefix, ext string) string {
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("%s%d%s", prefix, n, ext)
+		if _, ok := byName[name]; ok {
+			continue
+		}
+		taken := false
+		for _, part := range edits.added {
+			taken = taken || part.name == name
+		}
+		if !taken {
+			return name
+		}
+	}
+}
+
+// addContentType adds an element to the content types part.
+func addContentType(content, element string) string {
+	end := strings.LastIndex(content, "</Types>")
+	if end < 0 {
+		return content
+	}
+	return content[:end] + element + content[end:]
+}
+
+// addVMLContentType declares the content type of VML drawings unless it is declared already.
+func addVMLContentType(content string) string {
+	if vmlDefaultRegex.MatchString(content) {
+		return content
+	}
+	return addContentType(content, `<Default Extension="vml" ContentType="`+vmlContentType+`"/>`)
+}
+
+// commentElements returns the comment elements of the source comments, written by author.
+func commentElements(prefix string, author int, comments []sourceComment) string {
+	var sb strings.Builder
+	for _, c := range comments {
+		fmt.Fprintf(&sb, `<%[1]scomment ref="%[2]s" authorId="%[3]d"><%[1]stext><%[1]st xml:space="preserve">%[4]s</%[1]st></%[1]stext></%[1]scomment>`,
+			prefix, c.ref, author, html.EscapeString(c.text))
+	}
+	return sb.String()
+}
+
+// newCommentsPart returns a comments part holding the source comments.
+func newCommentsPart(comments []sourceComment) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<comments xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><authors><author>` + sourceCommentAuthor +
+		`</author></authors><commentList>` + commentElements("", 0, comments) + `</commentList></comments>`
+}
+
+// appendComments adds the source comments and their author to an existing comments part.
+func appendComments(content string, comments []sourceComment) string {
+	authors, list := authorsEndRegex.FindStringSubmatchIndex(content), commentListRegex.FindStringSubmatchIndex(content)
+	if authors == nil || list == nil {
+		return content
+	}
+	prefix := ""
+	if list[2] >= 0 {
+		prefix = content[list[2]:list[3]]
+	}
+	author := len(authorRegex.FindAllStringIndex(content[:authors[0]], -1))
+	return content[:authors[0]] + "<" + prefix + "author>" + sourceCommentAuthor + "</" + prefix + "author>" +
+		content[authors[0]:list[0]] + commentElements(prefix, author, comments) + content[list[0]:]
+}
+
+// nextVMLBlock returns the first block of shape ids (o:idmap) not used by the VML drawings of the document.
+func nextVMLBlock(files []*zip.File) int {
+	block := 1
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name, ".vml") {
+			continue
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			continue
+		}
+		for _, m := range vmlIDMapRegex.FindAllStringSubmatch(content, -1) {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				block = max(block, n+1)
+			}
+		}
+	}
+	return block
+}
+
+// noteShapes returns the hidden comment boxes of the source comments, numbered from id.
+func noteShapes(id int, comments []sourceComment) string {
+	var sb strings.Builder
+	for i, c := range comments {
+		// The box is anchored to the right of the cell, as Excel places new comments
+		fmt.Fprintf(&sb, `<v:shape id="_x0000_s%d" type="#_x0000_t202" style="position:absolute;margin-left:0;margin-top:0;width:144pt;height:72pt;z-index:%d;visibility:hidden" fillcolor="#ffffe1" o:insetmode="auto">`+
+			`<v:fill color2="#ffffe1"/><v:shadow on="t" color="black" obscured="t"/><v:path o:connecttype="none"/>`+
+			`<v:textbox style="mso-direction-alt:auto"><div style="text-align:left"></div></v:textbox>`+
+			`<x:ClientData ObjectType="Note"><x:MoveWithCells/><x:SizeWithCells/><x:Anchor>%d, 15, %d, 10, %d, 15, %d, 4</x:Anchor>`+
+			`<x:AutoFill>False</x:AutoFill><x:Row>%d</x:Row><x:Column>%d</x:Column></x:ClientData></v:shape>`,
+			id+i, i+1, c.col+1, max(c.row-1, 0), c.col+3, max(c.row-1, 0)+4, c.row, c.col)
+	}
+	return sb.String()
+}
+
+// newVMLDrawing returns a VML drawing holding the comment boxes of the source comments, using
+// the shape ids of block.
+func newVMLDrawing(block int, comments []sourceComment) string {
+	return `<xml xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office" xmlns:x="urn:schemas-microsoft-com:office:excel">` +
+		fmt.Sprintf(`<o:shapelayout v:ext="edit"><o:idmap v:ext="edit" data="%d"/></o:shapelayout>`, block) +
+		vmlNoteShapeType + noteShapes(block*1024+1, comments) + `</xml>`
+}
+
+// appendNoteShapes adds the comment boxes of the source comments to an existing VML drawing.
+func appendNoteShapes(content string, comments []sourceComment) string {
+	end := strings.LastIndex(content, "</xml>")
+	if end < 0 {
+		return content
+	}
+	id := 0
+	for _, m := range vmlShapeIDRegex.FindAllStringSubmatch(content, -1) {
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			id = max(id, n)
+		}
+	}
+	shapes := noteShapes(id+1, comments)
+	if !strings.Contains(content, `id="_x0000_t202"`) {
+		shapes = vmlNoteShapeType + shapes
+	}
+	return content[:end] + shapes + content[end:]
+}
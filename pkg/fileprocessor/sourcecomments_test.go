@@ -0,0 +1,96 @@
+package fileprocessor
+
+import (
+	"archive/zip"
+	"context"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testCommentRegex matches a comment with a single text element: its cell, author and text.
+var testCommentRegex = regexp.MustCompile(`<comment ref="([^"]*)" authorId="(\d+)"><text><t[^>]*>([^<]*)</t></text></comment>`)
+
+// testComments returns the texts of the comments of a comments part by cell, each prefixed with
+// the name of its author and a colon.
+func testComments(content string) map[string]string {
+	authors := regexp.MustCompile(`<author>([^<]*)</author>`).FindAllStringSubmatch(content, -1)
+	comments := make(map[string]string)
+	for _, m := range testCommentRegex.FindAllStringSubmatch(content, -1) {
+		author := "?"
+		if i, err := strconv.Atoi(m[2]); err == nil && i < len(authors) {
+			author = authors[i][1]
+		}
+		comments[m[1]] = author + ":" + html.UnescapeString(m[3])
+	}
+	return comments
+}
+
+func TestSourceComments(t *testing.T) {
+	const rels = `xmlns="http://schemas.openxmlformats.org/package/2006/relationships"`
+	const relType = `http://schemas.openxmlformats.org/officeDocument/2006/relationships/`
+	cell := func(ref, index string) string { return `<c r="` + ref + `" t="s"><v>` + index + `</v></c>` }
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.xlsx"), filepath.Join(dir, "out.xlsx")
+	writeArchive(t, input, []testPart{
+		{zip.FileHeader{Name: "[Content_Types].xml"}, `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="xml" ContentType="application/xml"/><Default Extension="vml" ContentType="application/vnd.openxmlformats-officedocument.vmlDrawing"/></Types>`},
+		{zip.FileHeader{Name: "xl/workbook.xml"}, `<workbook ` + testMain + ` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Data" sheetId="1" r:id="rId1"/><sheet name="Notes" sheetId="2" r:id="rId2"/></sheets></workbook>`},
+		{zip.FileHeader{Name: "xl/_rels/workbook.xml.rels"}, `<Relationships ` + rels + `><Relationship Id="rId1" Type="` + relType + `worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="` + relType + `worksheet" Target="worksheets/sheet2.xml"/></Relationships>`},
+		// The first sheet has a comment on A2 already
+		{zip.FileHeader{Name: "xl/worksheets/sheet1.xml"}, `<worksheet ` + testMain + ` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheetData><row r="1">` + cell("A1", "0") + `</row><row r="2">` + cell("A2", "1") + `</row></sheetData><legacyDrawing r:id="rId1"/></worksheet>`},
+		{zip.FileHeader{Name: "xl/worksheets/_rels/sheet1.xml.rels"}, `<Relationships ` + rels + `><Relationship Id="rId1" Type="` + relType + `vmlDrawing" Target="../drawings/vmlDrawing1.vml"/><Relationship Id="rId2" Type="` + relType + `comments" Target="../comments1.xml"/></Relationships>`},
+		{zip.FileHeader{Name: "xl/comments1.xml"}, `<comments ` + testMain + `><authors><author>张三</author></authors><commentList><comment ref="A2" authorId="0"><text><t>请核对</t></text></comment></commentList></comments>`},
+		{zip.FileHeader{Name: "xl/drawings/vmlDrawing1.vml"}, `<xml xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office" xmlns:x="urn:schemas-microsoft-com:office:excel"><o:shapelayout v:ext="edit"><o:idmap v:ext="edit" data="1"/></o:shapelayout><v:shape id="_x0000_s1025" type="#_x0000_t202"><x:ClientData ObjectType="Note"><x:Row>1</x:Row><x:Column>0</x:Column></x:ClientData></v:shape></xml>`},
+		// The second sheet has no comments
+		{zip.FileHeader{Name: "xl/worksheets/sheet2.xml"}, `<worksheet ` + testMain + `><sheetData><row r="1">` + cell("A1", "2") + `</row><row r="2">` + cell("B2", "1") + `</row></sheetData></worksheet>`},
+		{zip.FileHeader{Name: "xl/sharedStrings.xml"}, `<sst ` + testMain + `><si><t>收入</t></si><si><t>成本</t></si><si><t>成本 &amp; 利润</t></si></sst>`},
+	})
+
+	fp := NewFileProcessor()
+	fp.SetSourceComments(true)
+	if err := fp.ProcessFile(context.Background(), input, output, prefixTranslator{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The cells show the translations and have comments holding the source text; the existing
+	// comment is kept, translated, and its cell gets no second comment
+	if got, want := readArchivePart(t, output, "xl/sharedStrings.xml"), `<sst `+testMain+`><si><t>T 收入</t></si><si><t>T 成本</t></si><si><t>T 成本 &amp; 利润</t></si></sst>`; got != want {
+		t.Errorf("got shared strings\n%s\nwant\n%s", got, want)
+	}
+	checkComments := func(name string, want map[string]string) {
+		t.Helper()
+		content := readArchivePart(t, output, name)
+		got := testComments(content)
+		if len(testCommentRegex.FindAllString(content, -1)) != len(want) || len(got) != len(want) {
+			t.Errorf("got comments %q in %s, want %q", got, name, want)
+			return
+		}
+		for ref, text := range want {
+			if got[ref] != text {
+				t.Errorf("got comment %q on %s in %s, want %q", got[ref], ref, name, text)
+			}
+		}
+	}
+	checkComments("xl/comments1.xml", map[string]string{"A1": "Source:收入", "A2": "张三:T 请核对"})
+	checkComments("xl/comments2.xml", map[string]string{"A1": "Source:成本 & 利润", "B2": "Source:成本"})
+
+	// The new comment of the first sheet gets a box in its drawing, the second sheet a new drawing
+	if vml := readArchivePart(t, output, "xl/drawings/vmlDrawing1.vml"); strings.Count(vml, "<v:shape ") != 2 || !strings.Contains(vml, `id="_x0000_s1026"`) {
+		t.Errorf("got drawing\n%s\nwant a second comment box", vml)
+	}
+	sheet2Rels := readArchivePart(t, output, "xl/worksheets/_rels/sheet2.xml.rels")
+	for _, target := range []string{`Target="../comments2.xml"`, `Target="../drawings/vmlDrawing2.vml"`} {
+		if !strings.Contains(sheet2Rels, target) {
+			t.Errorf("got relationships of the second sheet\n%s\nwant %s", sheet2Rels, target)
+		}
+	}
+	if sheet2 := readArchivePart(t, output, "xl/worksheets/sheet2.xml"); !strings.Contains(sheet2, "<legacyDrawing ") {
+		t.Errorf("got second sheet\n%s\nwant a legacy drawing", sheet2)
+	}
+	if types := readArchivePart(t, output, "[Content_Types].xml"); !strings.Contains(types, `PartName="/xl/comments2.xml"`) {
+		t.Errorf("got content types\n%s\nwant the new comments part", types)
+	}
+}
@@ -0,0 +1,66 @@
+package fileprocessor
+
+import (
+	"context"
+	"exceltranslator/pkg/translator"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// processTextFile translates an RTF or CSV document, which is a single text file rather than a zip archive.
+func (fp *FileProcessor) processTextFile(ctx context.Context, inputPath string, outputPath string, trans translator.Translator) error {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		fp.logger.Errorf("Failed to open source file %s: %v", inputPath, err)
+		return stageError(StageOpen, "", fmt.Errorf("failed to open source file: %w", err))
+	}
+
+	name := filepath.Base(inputPath)
+	fp.textParts = nil
+	newContent, err := fp.translatePart(ctx, name, string(data), trans)
+	if err != nil {
+		return fmt.Errorf("failed to process file %s: %w", name, err)
+	}
+	if err := fp.writeText(nil); err != nil {
+		fp.logger.Errorf("Failed to write text output: %v", err)
+		return stageError(StageWrite, "", fmt.Errorf("failed to write text output: %w", err))
+	}
+
+	// Ensure output directory exists
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		fp.logger.Errorf("Failed to create output directory %s: %v", filepath.Dir(outputPath), err)
+		return stageError(StageWrite, "", fmt.Errorf("failed to create output directory: %w", err))
+	}
+	if err := os.WriteFile(outputPath, []byte(newContent), 0644); err != nil {
+		fp.logger.Errorf("Failed to write output file %s: %v", outputPath, err)
+		return stageError(StageWrite, "", fmt.Errorf("failed to write output file: %w", err))
+	}
+	fp.logger.Tracef("Finished processing file: %s", inputPath)
+	return nil
+}
+
+// extractTextFileTexts extracts the translatable texts of an RTF or CSV document.
+func (fp *FileProcessor) extractTextFileTexts(inputPath string) ([]PartTexts, error) {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		fp.logger.Errorf("Failed to open source file %s: %v", inputPath, err)
+		return nil, stageError(StageOpen, "", fmt.Errorf("failed to open source file: %w", err))
+	}
+
+	name := filepath.Base(inputPath)
+	_, items, err := fp.extractor.Extract(string(data), name)
+	if err != nil {
+		fp.logger.Errorf("Extraction failed for %s: %v", name, err)
+		return nil, stageError(StageExtract, name, fmt.Errorf("extraction failed for %s: %w", name, err))
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	texts := make([]string, len(items))
+	for i, item := range items {
+		texts[i] = item.Text
+	}
+	return []PartTexts{{Name: name, Texts: texts}}, nil
+}
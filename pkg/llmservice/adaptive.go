@@ -0,0 +1,118 @@
+package llmservice
+
+import (
+	"context"
+	"exceltranslator/pkg/logger"
+	"sync"
+	"time"
+)
+
+const (
+	// slowLatencyFactor marks a request as slow when it takes this many times the average latency.
+	slowLatencyFactor = 3
+	// latencyWeight is the weight of the latest request in the average latency.
+	latencyWeight = 0.2
+	// decreaseCooldown keeps the failures of one burst of requests from halving the limit repeatedly.
+	decreaseCooldown = time.Second
+)
+
+// adaptiveLimiter caps the requests in flight and adapts the cap to the health of the endpoint
+// (AIMD): every successful request raises it by 1/limit, about one per round of requests, up to
+// max; a temporary failure or an unusually slow response halves it, down to one request.
+// A nil limiter does not limit.
+type adaptiveLimiter struct {
+	mu        sync.Mutex
+	max       int
+	limit     float64
+	inflight  int
+	latency   time.Duration // Moving average of the latency of successful requests
+	decreased time.Time     // Time of the last decrease, see decreaseCooldown
+	wake      chan struct{} // Closed and replaced when a request finishes
+	logger    *logger.Logger
+}
+
+// newAdaptiveLimiter returns a limiter starting at max requests in flight, or nil if max is zero.
+func newAdaptiveLimiter(max int, log *logger.Logger) *adaptiveLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &adaptiveLimiter{max: max, limit: float64(max), wake: make(chan struct{}), logger: log}
+}
+
+// acquire waits until a request may be sent or ctx is done.
+func (l *adaptiveLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		if l.inflight < int(l.limit) {
+			l.inflight++
+			l.mu.Unlock()
+			return nil
+		}
+		wake := l.wake
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// release frees the slot taken by acquire.
+func (l *adaptiveLimiter) release() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	l.wakeAll()
+}
+
+// record adapts the limit to the outcome of a request. congested reports a failure that
+// signals an overloaded endpoint, such as a rate limit, a server error or a timeout;
+// otherwise the request succeeded in the given time.
+func (l *adaptiveLimiter) record(congested bool, latency time.Duration) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !congested {
+		slow := l.latency > 0 && latency > slowLatencyFactor*l.latency
+		if l.latency == 0 {
+			l.latency = latency
+		} else {
+			l.latency += time.Duration(latencyWeight * float64(latency-l.latency))
+		}
+		if !slow {
+			limit := min(l.limit+1/l.limit, float64(l.max))
+			if int(limit) > int(l.limit) {
+				l.logger.Debugf("Endpoint recovered, raising concurrency to %d", int(limit))
+				defer l.wakeAll()
+			}
+			l.limit = limit
+			return
+		}
+	}
+
+	if time.Since(l.decreased) < decreaseCooldown {
+		return
+	}
+	l.decreased = time.Now()
+	if limit := max(l.limit/2, 1); int(limit) < int(l.limit) {
+		l.logger.Debugf("Endpoint under stress, lowering concurrency to %d", int(limit))
+		l.limit = limit
+	}
+}
+
+// wakeAll lets waiting requests check the limit again. The caller must hold mu.
+func (l *adaptiveLimiter) wakeAll() {
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
@@ -0,0 +1,59 @@
+package llmservice
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"exceltranslator/pkg/logger"
+)
+
+func TestAdaptiveLimiterBacksOff(t *testing.T) {
+	l := newAdaptiveLimiter(16, logger.NewLogger(100))
+	latency := 100 * time.Millisecond
+
+	// Rounds of 16 requests with a rising share of failures; each round is a separate burst
+	limits := []int{int(l.limit)}
+	for _, failures := range []int{0, 1, 4, 8, 16} {
+		l.decreased = time.Time{} // The cooldown of the previous burst has passed
+		for i := range 16 {
+			l.record(i >= 16-failures, latency)
+		}
+		limits = append(limits, int(l.limit))
+	}
+	// The limit stays at its maximum without failures and drops as they rise, although the
+	// successes of a burst raise it a little and a burst halves it only once
+	if want := []int{16, 16, 8, 4, 3, 1}; !slices.Equal(limits, want) {
+		t.Errorf("limits = %v, want %v", limits, want)
+	}
+
+	// Only one request is let through now
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.acquire(ctx); err == nil {
+		t.Error("a second request was let through at a limit of 1")
+	}
+	l.release()
+
+	// Successes raise the limit again by about one per round
+	for range 3 {
+		l.record(false, latency)
+	}
+	if got := int(l.limit); got != 3 {
+		t.Errorf("limit after 3 successes = %d, want 3", got)
+	}
+}
+
+func TestAdaptiveLimiterSlowResponses(t *testing.T) {
+	l := newAdaptiveLimiter(8, logger.NewLogger(100))
+	l.record(false, 100*time.Millisecond)
+	// A response far slower than the average counts as congestion
+	l.record(false, time.Second)
+	if got := int(l.limit); got != 4 {
+		t.Errorf("limit after a slow response = %d, want 4", got)
+	}
+}
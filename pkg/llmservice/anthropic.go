@@ -0,0 +1,174 @@
+package llmservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"exceltranslator/pkg/logger"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultAnthropicBaseURL is the endpoint of the Anthropic API, to which "/messages" is appended.
+	DefaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	// anthropicVersion is the API version sent in the anthropic-version header.
+	anthropicVersion = "2023-06-01"
+	// anthropicMaxTokens caps the length of a translation; the Messages API requires a limit.
+	anthropicMaxTokens = 8192
+)
+
+// NewAnthropicService creates an LLMService for Anthropic Claude models, using the Messages API.
+// BaseURL defaults to DefaultAnthropicBaseURL. The prompt is sent as the system prompt and the
+// text as the user message; retries, caching and the token budget work as with the OpenAI API.
+func NewAnthropicService(config LLMServiceConfig, log *logger.Logger) *LLMService {
+	if config.BaseURL == "" {
+		config.BaseURL = DefaultAnthropicBaseURL
+	}
+	s := NewLLMService(config, log)
+	timeout := config.RequestTimeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	s.anthropic = &anthropicClient{
+		url:    strings.TrimRight(config.BaseURL, "/") + "/messages",
+		apiKey: config.APIKey,
+		model:  config.Model,
+		http:   &http.Client{Timeout: timeout},
+	}
+	return s
+}
+
+// anthropicClient sends requests to the Anthropic Messages API.
+type anthropicClient struct {
+	url    string
+	apiKey string // Empty sends no x-api-key header, e.g. for proxies adding it
+	model  string
+	http   *http.Client
+}
+
+// anthropicMessage is a message of a Messages API request.
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// anthropicRequest is the body of a Messages API request.
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+}
+
+// anthropicResponse is the part of a Messages API response that is used.
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int64 `json:"input_tokens"`
+		OutputTokens int64 `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// AnthropicError is an error response of the Anthropic API. It is treated like an OpenAI API
+// error by the retries: 429, 5xx and 529 (overloaded) are retried, other 4xx are not.
+type AnthropicError struct {
+	StatusCode int
+	Type       string // e.g. authentication_error, not_found_error or overloaded_error
+	Message    string
+	Header     http.Header // Headers of the response, e.g. Retry-After
+}
+
+func (e *AnthropicError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("anthropic: HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("anthropic: HTTP %d %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+// translate sends one Messages API request and returns the text of the answer and the tokens used.
+func (c *anthropicClient) translate(ctx context.Context, system, text string) (string, int64, error) {
+	body, err := json.Marshal(anthropicRequest{
+		Model:     c.model,
+		MaxTokens: anthropicMaxTokens,
+		System:    system,
+		Messages:  []anthropicMessage{{Role: "user", Content: text}},
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", anthropicVersion)
+	if c.apiKey != "" {
+		req.Header.Set("x-api-key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &AnthropicError{StatusCode: resp.StatusCode, Header: resp.Header}
+		var errBody struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &errBody) == nil {
+			apiErr.Type, apiErr.Message = errBody.Error.Type, errBody.Error.Message
+		}
+		return "", 0, apiErr
+	}
+
+	var result anthropicResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", 0, fmt.Errorf("invalid response: %w", err)
+	}
+	tokens := result.Usage.InputTokens + result.Usage.OutputTokens
+	var sb strings.Builder
+	found := false
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+			found = true
+		}
+	}
+	if !found {
+		return "", tokens, fmt.Errorf("no text content found in response (stop reason %q)", result.StopReason)
+	}
+	return sb.String(), tokens, nil
+}
+
+// doAnthropicRequest performs the API request with the Anthropic Messages API.
+func (s *LLMService) doAnthropicRequest(ctx context.Context, prompt, text string) (string, error) {
+	trimmed := strings.TrimSpace(text)
+
+	s.logger.Tracef("Sending request to Anthropic for trimmed: %s", trimmed)
+
+	s.requests.Add(1)
+	result, tokens, err := s.anthropic.translate(ctx, prompt, trimmed)
+	s.addTokens(tokens)
+	if err != nil {
+		s.logger.Errorf("Failed to create message: %v", err)
+		return "", fmt.Errorf("failed to create message: %w", err)
+	}
+	s.logger.Tracef("Received translation result: %s", s.TruncateLog(result, 200))
+	return result, nil
+}
@@ -0,0 +1,149 @@
+package llmservice
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// batchInstruction is appended to the prompt when several texts are sent in one request.
+const batchInstruction = "Each segment below starts with a marker like [[1]]. Translate every segment separately " +
+	"and reply with each translation preceded by its marker, in the same order, without any other text."
+
+// batchMarkerRegex matches the numbered segment markers of batched requests and responses.
+var batchMarkerRegex = regexp.MustCompile(`\[\[(\d+)\]\]`)
+
+// TranslateBatch translates several texts, packing up to BatchSize uncached texts into one request.
+// Cached texts are not sent again. If the model does not answer with one segment per text,
+// the texts of that request are translated one by one.
+func (s *LLMService) TranslateBatch(ctx context.Context, texts []string) ([]string, error) {
+	results := make([]string, len(texts))
+
+	// 1. Only cache misses are sent to the model
+	var misses []int
+	for i, text := range texts {
+		if translated, ok := s.cached(text); ok {
+			results[i] = translated
+			continue
+		}
+		misses = append(misses, i)
+	}
+	s.logger.Tracef("Batch of %d texts, %d cache misses", len(texts), len(misses))
+
+	size := max(s.config.BatchSize, 1)
+	for start := 0; start < len(misses); start += size {
+		chunk := misses[start:min(start+size, len(misses))]
+		batch := make([]string, len(chunk))
+		for j, i := range chunk {
+			batch[j] = texts[i]
+		}
+
+		translated, err := s.translateChunk(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range chunk {
+			results[i] = translated[j]
+		}
+	}
+	return results, nil
+}
+
+// translateChunk translates texts in a single request, falling back to one request per text.
+func (s *LLMService) translateChunk(ctx context.Context, texts []string) ([]string, error) {
+	if len(texts) > 1 {
+		results, ok, err := s.requestBatch(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return results, nil
+		}
+	}
+
+	results := make([]string, len(texts))
+	for i, text := range texts {
+		translated, err := s.Translate(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = translated
+	}
+	return results, nil
+}
+
+// requestBatch sends texts as numbered segments of one request and caches the translations.
+// It returns false if the response cannot be split into one translation per text.
+func (s *LLMService) requestBatch(ctx context.Context, texts []string) ([]string, bool, error) {
+	masked := make([]string, len(texts))
+	tokens := make([][]string, len(texts))
+	var sb strings.Builder
+	for i, text := range texts {
+		masked[i] = text
+		if s.protector.enabled() {
+			masked[i], tokens[i] = s.protector.mask(text)
+		}
+		fmt.Fprintf(&sb, "[[%d]]\n%s\n", i+1, strings.TrimSpace(masked[i]))
+	}
+	body := sb.String()
+
+	result, err := s.requestWithRetry(ctx, s.promptFor(body)+" "+batchInstruction, body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	segments, ok := splitBatchResponse(result, len(texts))
+	if !ok {
+		s.logger.Warnf("Batch response does not contain %d segments, translating them one by one", len(texts))
+		return nil, false, nil
+	}
+
+	results := make([]string, len(texts))
+	for i, text := range texts {
+		translated := segments[i]
+		if len(tokens[i]) > 0 {
+			restored, complete := unmask(translated, tokens[i])
+			if !complete {
+				s.logger.Warnf("Some protected characters were dropped by the model: %s", s.TruncateLog(text, 80))
+			}
+			translated = restored
+		}
+		translated = s.glossary.enforce(text, translated)
+
+		if strings.TrimSpace(translated) == "" && strings.TrimSpace(text) != "" {
+			if results[i], err = s.handleEmptyResponse(text); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		s.store(text, translated)
+		s.logger.Debugf("Translated text:\n%5s: %s\n%5s: %s",
+			"Orig", s.TruncateLog(text, 80), "Trans", s.TruncateLog(translated, 200))
+		results[i] = translated
+	}
+	return results, true, nil
+}
+
+// splitBatchResponse splits a batched response at its markers.
+// It returns false unless the markers are exactly 1..n in order.
+func splitBatchResponse(response string, n int) ([]string, bool) {
+	markers := batchMarkerRegex.FindAllStringSubmatchIndex(response, -1)
+	if len(markers) != n {
+		return nil, false
+	}
+
+	segments := make([]string, n)
+	for i, m := range markers {
+		if num, err := strconv.Atoi(response[m[2]:m[3]]); err != nil || num != i+1 {
+			return nil, false
+		}
+		end := len(response)
+		if i+1 < n {
+			end = markers[i+1][0]
+		}
+		segments[i] = strings.TrimSpace(response[m[1]:end])
+	}
+	return segments, true
+}
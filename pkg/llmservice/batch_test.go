@@ -0,0 +1,96 @@
+package llmservice
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSplitBatchResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		n        int
+		want     []string
+	}{
+		{"one per line", "[[1]]\n苹果\n[[2]]\n香蕉\n", 2, []string{"苹果", "香蕉"}},
+		{"same line", "[[1]] 苹果 [[2]] 香蕉", 2, []string{"苹果", "香蕉"}},
+		{"preamble", "Here you go:\n[[1]]\n苹果", 1, []string{"苹果"}},
+		{"multiline segment", "[[1]]\n第一行\n第二行\n[[2]]\nB", 2, []string{"第一行\n第二行", "B"}},
+		{"empty segment", "[[1]]\n[[2]]\nB", 2, []string{"", "B"}},
+		{"missing segment", "[[1]]\nA", 2, nil},
+		{"extra segment", "[[1]]\nA\n[[2]]\nB\n[[3]]\nC", 2, nil},
+		{"out of order", "[[2]]\nB\n[[1]]\nA", 2, nil},
+		{"wrong numbers", "[[1]]\nA\n[[3]]\nC", 2, nil},
+		{"no markers", "A\nB", 2, nil},
+	}
+	for _, tt := range tests {
+		got, ok := splitBatchResponse(tt.response, tt.n)
+		if ok != (tt.want != nil) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: splitBatchResponse = %q, %v, want %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+// batchReply answers batched requests with one marked segment per text, upper-casing the texts,
+// and single texts with the text upper-cased.
+func batchReply(text string) string {
+	if !strings.Contains(text, "[[1]]") {
+		return strings.ToUpper(text)
+	}
+	return regexp.MustCompile(`(\[\[\d+\]\])\n([^\n]*)`).ReplaceAllStringFunc(text, strings.ToUpper)
+}
+
+func TestTranslateBatch(t *testing.T) {
+	server := newFakeServer(t, 1, batchReply)
+	s := newTestService(server, LLMServiceConfig{BatchSize: 2})
+
+	got, err := s.TranslateBatch(context.Background(), []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A", "B", "C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// A batch of two texts and a single one
+	if n := server.requests.Load(); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+
+	// Cached texts are not sent again
+	got, err = s.TranslateBatch(context.Background(), []string{"b", "d", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"B", "D", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if n := server.requests.Load(); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+}
+
+func TestTranslateBatchFallsBackToSingleTexts(t *testing.T) {
+	// The model drops the second segment of batches
+	server := newFakeServer(t, 1, func(text string) string {
+		if strings.Contains(text, "[[1]]") {
+			return "[[1]]\nX"
+		}
+		return strings.ToUpper(text)
+	})
+	s := newTestService(server, LLMServiceConfig{BatchSize: 3})
+
+	got, err := s.TranslateBatch(context.Background(), []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A", "B", "C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// The batch and one request per text
+	if n := server.requests.Load(); n != 4 {
+		t.Errorf("got %d requests, want 4", n)
+	}
+}
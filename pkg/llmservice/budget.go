@@ -0,0 +1,36 @@
+package llmservice
+
+import "exceltranslator/pkg/translator"
+
+// ErrBudgetExceeded is returned instead of sending a request once the token budget
+// (MaxTokens) is used up. The translator keeps the source text of the remaining texts, so the
+// document is still saved with the translations made so far.
+var ErrBudgetExceeded = translator.ErrBudgetExceeded
+
+// Tokens returns the number of tokens used by the requests so far, as reported by the API.
+func (s *LLMService) Tokens() int64 {
+	return s.tokens.Load()
+}
+
+// addTokens counts the tokens used by a request towards the budget and the TPM limit.
+func (s *LLMService) addTokens(tokens int64) {
+	s.tokens.Add(tokens)
+	s.throttle.used(tokens)
+}
+
+// TokensLeft returns the part of the token budget that is still unused, or -1 without a budget.
+func (s *LLMService) TokensLeft() int64 {
+	if s.config.MaxTokens <= 0 {
+		return -1
+	}
+	return max(s.config.MaxTokens-s.tokens.Load(), 0)
+}
+
+// checkBudget returns ErrBudgetExceeded if the token budget is used up. Requests already in
+// flight are not stopped, so the budget may be exceeded by their usage.
+func (s *LLMService) checkBudget() error {
+	if s.config.MaxTokens > 0 && s.tokens.Load() >= s.config.MaxTokens {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package llmservice
+
+import (
+	"container/list"
+	"sync"
+)
+
+// translationCache is a concurrency-safe cache of translations with optional LRU eviction.
+type translationCache struct {
+	mu       sync.Mutex
+	capacity int                      // Maximum number of entries; 0 means unlimited
+	disabled bool                     // If true, nothing is stored
+	entries  map[string]*list.Element // Source text -> element in order
+	order    *list.List               // Most recently used entries at the front
+}
+
+// cacheEntry is a single cached translation.
+type cacheEntry struct {
+	text       string
+	translated string
+}
+
+// newTranslationCache creates a cache holding at most size entries.
+// A size of 0 means unlimited and a negative size disables caching.
+func newTranslationCache(size int) *translationCache {
+	return &translationCache{
+		capacity: max(size, 0),
+		disabled: size < 0,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the cached translation of text, if any.
+func (c *translationCache) Get(text string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[text]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).translated, true
+}
+
+// Put stores the translation of text, evicting the least recently used entry if the cache is full.
+func (c *translationCache) Put(text, translated string) {
+	if c.disabled {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[text]; ok {
+		elem.Value.(*cacheEntry).translated = translated
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[text] = c.order.PushFront(&cacheEntry{text: text, translated: translated})
+	if c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).text)
+	}
+}
+
+// Len returns the number of cached translations.
+func (c *translationCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
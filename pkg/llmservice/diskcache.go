@@ -0,0 +1,95 @@
+package llmservice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"exceltranslator/pkg/logger"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// diskCache is a translation cache persisted as a JSON file so that translations
+// survive across runs. Entries are keyed by a hash of the model, prompt and source text.
+type diskCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]string
+	dirty   bool // Entries were added since the last flush
+}
+
+// loadDiskCache loads the cache file at path. A missing or corrupt file starts an empty cache.
+func loadDiskCache(path string, log *logger.Logger) *diskCache {
+	c := &diskCache{path: path, entries: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warnf("Failed to read translation cache %s, starting empty: %v", path, err)
+		}
+		return c
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		log.Warnf("Ignoring corrupt translation cache %s: %v", path, err)
+		c.entries = make(map[string]string)
+		return c
+	}
+	log.Debugf("Loaded %d cached translations from %s", len(c.entries), path)
+	return c
+}
+
+// diskCacheKey returns the cache key of text translated by model with prompt.
+// The prompt carries the target language, so switching either does not return stale results.
+func diskCacheKey(model, prompt, text string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + prompt + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached translation for key, if any.
+func (c *diskCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	translated, ok := c.entries[key]
+	return translated, ok
+}
+
+// Put stores the translation for key. It is written to disk on the next Flush.
+func (c *diskCache) Put(key, translated string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[key]; ok && old == translated {
+		return
+	}
+	c.entries[key] = translated
+	c.dirty = true
+}
+
+// Flush writes the cache file if entries were added. The file is replaced atomically
+// so that an interrupted write does not corrupt the existing cache.
+func (c *diskCache) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode translation cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create translation cache directory: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write translation cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to replace translation cache: %w", err)
+	}
+	c.dirty = false
+	return nil
+}
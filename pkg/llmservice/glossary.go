@@ -0,0 +1,172 @@
+package llmservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// glossaryEntry is one term of a glossary with its required translation.
+type glossaryEntry struct {
+	source string
+	target string
+}
+
+// glossary enforces consistent translations of terms. Entries are sorted longest source first
+// so that a longer term wins over a term it contains (用户名 over 用户).
+type glossary []glossaryEntry
+
+// newGlossary builds a glossary from source -> target terms, ignoring empty sources.
+func newGlossary(terms map[string]string) glossary {
+	var g glossary
+	for source, target := range terms {
+		if source != "" {
+			g = append(g, glossaryEntry{source: source, target: target})
+		}
+	}
+	sort.Slice(g, func(i, j int) bool {
+		if len(g[i].source) != len(g[j].source) {
+			return len(g[i].source) > len(g[j].source)
+		}
+		return g[i].source < g[j].source
+	})
+	return g
+}
+
+// LoadGlossary reads a glossary file, a JSON object mapping source terms to their translations.
+func LoadGlossary(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read glossary: %w", err)
+	}
+	var terms map[string]string
+	if err := json.Unmarshal(data, &terms); err != nil {
+		return nil, fmt.Errorf("failed to parse glossary %s: %w", path, err)
+	}
+	return terms, nil
+}
+
+// SaveGlossary writes a glossary file that LoadGlossary can read.
+func SaveGlossary(path string, terms map[string]string) error {
+	data, err := json.MarshalIndent(terms, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode glossary: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create glossary directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write glossary: %w", err)
+	}
+	return nil
+}
+
+// matching returns the entries whose source term occurs in text.
+func (g glossary) matching(text string) glossary {
+	var found glossary
+	for _, e := range g {
+		if indexTerm(text, e.source, 0) >= 0 {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// instruction returns the prompt instruction listing the terms found in text, or "" if there are none.
+func (g glossary) instruction(text string) string {
+	found := g.matching(text)
+	if len(found) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(found))
+	for i, e := range found {
+		pairs[i] = fmt.Sprintf("%q -> %q", e.source, e.target)
+	}
+	return "Always translate these terms as given: " + strings.Join(pairs, ", ") + "."
+}
+
+// enforce replaces the terms of source that the model left untranslated in translated
+// with their glossary translations. Translations already in place are kept as they are, also
+// when they contain the source term, e.g. "AI Assistant" for "AI".
+func (g glossary) enforce(source, translated string) string {
+	found := g.matching(source)
+	if len(found) == 0 {
+		return translated
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(translated); {
+		replaced := false
+		for _, e := range found {
+			if e.target != "" && strings.HasPrefix(translated[i:], e.target) {
+				sb.WriteString(e.target)
+				i += len(e.target)
+				replaced = true
+				break
+			}
+		}
+		if replaced {
+			continue
+		}
+		for _, e := range found {
+			if strings.HasPrefix(translated[i:], e.source) && atTermBoundary(translated, i, i+len(e.source)) {
+				sb.WriteString(e.target)
+				i += len(e.source)
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			_, size := utf8.DecodeRuneInString(translated[i:])
+			sb.WriteString(translated[i : i+size])
+			i += size
+		}
+	}
+	return sb.String()
+}
+
+// indexTerm returns the index of the first occurrence of term in s at or after from that
+// respects word boundaries, or -1. Matching is case-sensitive.
+func indexTerm(s, term string, from int) int {
+	for from <= len(s) {
+		k := strings.Index(s[from:], term)
+		if k < 0 {
+			return -1
+		}
+		start := from + k
+		if atTermBoundary(s, start, start+len(term)) {
+			return start
+		}
+		_, size := utf8.DecodeRuneInString(s[start:])
+		from = start + size
+	}
+	return -1
+}
+
+// atTermBoundary reports whether s[start:end] is a whole term. Latin letters and digits at
+// the edges of the term must not continue into the surrounding text; CJK terms match anywhere.
+func atTermBoundary(s string, start, end int) bool {
+	first, _ := utf8.DecodeRuneInString(s[start:end])
+	last, _ := utf8.DecodeLastRuneInString(s[start:end])
+	if isLatinWordChar(first) {
+		if r, _ := utf8.DecodeLastRuneInString(s[:start]); isLatinWordChar(r) {
+			return false
+		}
+	}
+	if isLatinWordChar(last) {
+		if r, _ := utf8.DecodeRuneInString(s[end:]); isLatinWordChar(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLatinWordChar reports whether r is part of a word of Latin-script text.
+func isLatinWordChar(r rune) bool {
+	return r == '_' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+		(r >= 0xC0 && r <= 0x24F && r != 0xD7 && r != 0xF7)
+}
@@ -0,0 +1,34 @@
+package llmservice
+
+import "testing"
+
+func TestGlossaryEnforce(t *testing.T) {
+	g := newGlossary(map[string]string{"AI": "AI Assistant", "报表": "Report", "销售": "Sales"})
+	tests := []struct {
+		name       string
+		source     string
+		translated string
+		want       string
+	}{
+		{"untranslated term", "AI 功能", "AI features", "AI Assistant features"},
+		{"target already in place", "AI 功能", "AI Assistant features", "AI Assistant features"},
+		{"target and untranslated term", "AI 和 AI", "AI Assistant and AI", "AI Assistant and AI Assistant"},
+		{"term inside a word", "AI 功能", "AIM features", "AIM features"},
+		{"CJK term", "销售报表", "销售 Report", "Sales Report"},
+		{"term not in source", "功能", "AI features", "AI features"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.enforce(tt.source, tt.translated); got != tt.want {
+				t.Errorf("enforce(%q, %q) = %q, want %q", tt.source, tt.translated, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGlossaryEnforceEmptyTarget(t *testing.T) {
+	g := newGlossary(map[string]string{"内部": ""})
+	if got := g.enforce("内部资料", "内部 material"); got != " material" {
+		t.Errorf("got %q, want %q", got, " material")
+	}
+}
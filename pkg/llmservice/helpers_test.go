@@ -0,0 +1,63 @@
+package llmservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"exceltranslator/pkg/logger"
+)
+
+// fakeServer is an OpenAI-compatible chat completions endpoint answering with reply.
+type fakeServer struct {
+	*httptest.Server
+	requests atomic.Int64
+
+	// reject, if set before the first request, may answer request n (counting from 1) with an
+	// error instead of the reply, reporting whether it did
+	reject func(n int64, w http.ResponseWriter) bool
+}
+
+// newFakeServer starts a server answering every request with reply(text), where text is the
+// user message without the prompt, and using tokens tokens.
+func newFakeServer(t *testing.T, tokens int, reply func(text string) string) *fakeServer {
+	t.Helper()
+	s := &fakeServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := s.requests.Add(1)
+		if s.reject != nil && s.reject(n, w) {
+			return
+		}
+		var req struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) == 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_, text, _ := strings.Cut(req.Messages[len(req.Messages)-1].Content, "\n\n")
+		w.Header().Set("Content-Type", "application/json")
+		content, _ := json.Marshal(reply(text))
+		fmt.Fprintf(w, `{"id":"1","object":"chat.completion","created":0,"model":"test","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%s}}],"usage":{"prompt_tokens":%d,"completion_tokens":0,"total_tokens":%d}}`, content, tokens, tokens)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newTestService returns a service sending its requests to the server. Failed requests are not
+// retried unless config sets ClientMaxRetries.
+func newTestService(s *fakeServer, config LLMServiceConfig) *LLMService {
+	config.BaseURL = s.URL
+	config.Model = "test"
+	if config.ClientMaxRetries == 0 {
+		config.ClientMaxRetries = -1
+	}
+	return NewLLMService(config, logger.NewLogger(100))
+}
@@ -2,49 +2,261 @@ package llmservice
 
 import (
 	"context"
+	"errors"
 	"exceltranslator/pkg/logger" // Import the logger package
 	"fmt"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
+	"golang.org/x/sync/singleflight"
 )
 
+// Policies for handling an empty model response to a non-empty input.
+const (
+	EmptyResponseFail         = "fail"          // Return ErrEmptyResponse
+	EmptyResponseKeepOriginal = "keep_original" // Keep the source text untranslated
+	EmptyResponseBlank        = "blank"         // Accept the empty translation
+)
+
+// ErrEmptyResponse is returned when the model answers a non-empty input with empty content
+// and the empty response policy is EmptyResponseFail.
+var ErrEmptyResponse = errors.New("empty translation in response")
+
+// defaultClientMaxRetries is the number of retries of a failed request when none is configured.
+const defaultClientMaxRetries = 3
+
+// defaultRateLimitRetries is the number of retries after a Retry-After delay when none is configured.
+const defaultRateLimitRetries = 3
+
+// defaultRequestTimeout is the time limit of a single request when none is configured.
+const defaultRequestTimeout = 60 * time.Second
+
 // LLMServiceConfig holds the configuration for the LLM service.
 type LLMServiceConfig struct {
-	BaseURL string
-	APIKey  string
-	Model   string
-	Prompt  string // Base prompt for translation
+	BaseURL       string
+	APIKey        string // Empty sends no Authorization header, e.g. for local servers
+	Model         string
+	Prompt        string // Base prompt for translation
+	TargetLang    string // Language to translate into; when set, "Translate to <TargetLang>." precedes Prompt
+	EmptyResponse string // Policy for empty responses; defaults to EmptyResponseKeepOriginal
+
+	// ClientMaxRetries is the number of retries of a request that failed with a network error,
+	// a timeout, 408, 409, 429 or 5xx, with exponential backoff. Other 4xx responses, including
+	// a rejected API key, are not retried. Zero uses the default (3) and a negative value disables retries.
+	ClientMaxRetries int
+
+	// RetryBackoff is the delay before the first retry, doubled for every further retry
+	// with random jitter. Zero uses the default (500ms).
+	RetryBackoff time.Duration
+
+	// RequestTimeout limits the time of a single request attempt. Zero uses the default (60s).
+	RequestTimeout time.Duration
+
+	// RateLimitRetries is how many times a request rejected with 429 and a Retry-After header
+	// is retried after waiting the requested delay. Zero uses the default (3) and a negative
+	// value disables these retries.
+	RateLimitRetries int
+
+	ProtectEmoji   bool     // Mask emoji before translation and restore them afterwards
+	ProtectSymbols bool     // Mask all symbol characters, not only emoji
+	ProtectPhrases []string // Mask these phrases (e.g. "©", "®", "™") so that they are kept verbatim
+
+	// BatchSize is the maximum number of texts TranslateBatch packs into one request.
+	// Zero or one sends each text in its own request.
+	BatchSize int
+
+	// CacheSize limits the number of cached translations (least recently used are evicted).
+	// Zero means unlimited and a negative value disables the cache.
+	CacheSize int
+
+	// CacheFile is the path of a JSON file that keeps translations across runs.
+	// Empty disables the persistent cache. Call Flush to save new translations.
+	CacheFile string
+
+	// Glossary maps source terms to their required translations. Terms found in a text are
+	// listed in the prompt, and terms the model leaves untranslated are replaced afterwards.
+	// Matching is case-sensitive and respects word boundaries for Latin text. See LoadGlossary.
+	Glossary map[string]string
+
+	// MaxTokens caps the tokens used by all requests of the service, as reported by the API.
+	// Once it is reached no further requests are sent and ErrBudgetExceeded is returned.
+	// Zero means no limit.
+	MaxTokens int64
+
+	// AdaptiveConcurrency caps the requests in flight at this number and lowers the cap while
+	// requests fail temporarily or respond unusually slowly, raising it again as they recover.
+	// Zero leaves the concurrency to the caller.
+	AdaptiveConcurrency int
+
+	// RequestsPerMinute and TokensPerMinute keep the requests within the rate limits of the
+	// provider's plan, delaying requests instead of having them rejected with 429. Tokens are
+	// counted as reported by the API. Zero means no limit.
+	RequestsPerMinute int
+	TokensPerMinute   int64
 }
 
 // LLMService provides translation capabilities using an OpenAI-compatible API.
 type LLMService struct {
-	config LLMServiceConfig
-	client *openai.Client
-	cache  map[string]string // Cache for translated text
-	mu     sync.RWMutex      // Mutex for cache access
-	logger *logger.Logger    // Logger instance
+	config       LLMServiceConfig
+	maxRetries   int           // Retries of a failed request
+	rateRetries  int           // Retries after the delay requested by a 429 response
+	retryBackoff time.Duration // Delay before the first retry
+	prompt       string        // Prompt including the target language instruction
+	scope        string        // Prompt and glossary, identifying translations in the persistent cache
+	protector    protector
+	glossary     glossary
+	client       *openai.Client
+	anthropic    *anthropicClient   // Sends the requests instead of client, see NewAnthropicService
+	cache        *translationCache  // Cache for translated text
+	disk         *diskCache         // Optional persistent cache, nil if disabled
+	inflight     singleflight.Group // Deduplicates concurrent requests for the same text
+	requests     atomic.Int64       // Requests sent to the API, including retries
+	tokens       atomic.Int64       // Tokens used by the requests, see MaxTokens
+	limiter      *adaptiveLimiter   // Adapts the requests in flight, nil if disabled
+	throttle     *throttle          // Keeps the requests within RPM and TPM limits, nil if disabled
+	logger       *logger.Logger     // Logger instance
 }
 
 // NewLLMService creates a new LLMService instance.
 func NewLLMService(config LLMServiceConfig, log *logger.Logger) *LLMService {
 	baseURL := config.BaseURL
 
-	client := openai.NewClient(
+	maxRetries := config.ClientMaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultClientMaxRetries
+	} else if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	rateRetries := config.RateLimitRetries
+	if rateRetries == 0 {
+		rateRetries = defaultRateLimitRetries
+	} else if rateRetries < 0 {
+		rateRetries = 0
+	}
+
+	retryBackoff := config.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = defaultRetryBackoff
+	}
+
+	timeout := config.RequestTimeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+
+	opts := []option.RequestOption{
 		option.WithBaseURL(baseURL),
 		option.WithAPIKey(config.APIKey),
-		option.WithRequestTimeout(60*time.Second),
-		option.WithMaxRetries(3),
-	)
+		option.WithRequestTimeout(timeout),
+		option.WithMaxRetries(0), // Retries are handled by requestWithRetry
+	}
+	if config.APIKey == "" {
+		// Servers without authentication may reject an empty bearer token
+		opts = append(opts, option.WithHeaderDel("authorization"))
+	}
+	client := openai.NewClient(opts...)
+
+	var disk *diskCache
+	if config.CacheFile != "" {
+		disk = loadDiskCache(config.CacheFile, log)
+	}
+
+	prompt := composePrompt(config.TargetLang, config.Prompt)
+	terms := newGlossary(config.Glossary)
 
 	return &LLMService{
-		config: config,
-		client: &client,
-		cache:  make(map[string]string), // Initialize the cache map
-		logger: log,                     // Assign the logger
+		config:       config,
+		maxRetries:   maxRetries,
+		rateRetries:  rateRetries,
+		retryBackoff: retryBackoff,
+		prompt:       prompt,
+		scope:        cacheScope(prompt, terms),
+		protector:    protector{emoji: config.ProtectEmoji, symbols: config.ProtectSymbols, phrases: newPhrases(config.ProtectPhrases)},
+		glossary:     terms,
+		client:       &client,
+		cache:        newTranslationCache(config.CacheSize), // Initialize the cache
+		disk:         disk,
+		limiter:      newAdaptiveLimiter(config.AdaptiveConcurrency, log),
+		throttle:     newThrottle(config.RequestsPerMinute, config.TokensPerMinute),
+		logger:       log, // Assign the logger
+	}
+}
+
+// composePrompt prepends the target language instruction to the prompt.
+func composePrompt(targetLang, prompt string) string {
+	if targetLang == "" {
+		return prompt
+	}
+	instruction := "Translate to " + targetLang + "."
+	if prompt == "" {
+		return instruction
+	}
+	return instruction + " " + prompt
+}
+
+// cacheScope returns the prompt identifying translations in the persistent cache. The glossary
+// is included so that editing it does not return translations made with the old terms.
+func cacheScope(prompt string, g glossary) string {
+	if len(g) == 0 {
+		return prompt
+	}
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	for _, e := range g {
+		sb.WriteString("\x00" + e.source + "\x00" + e.target)
+	}
+	return sb.String()
+}
+
+// Requests returns the number of requests sent to the API so far, including retries.
+func (s *LLMService) Requests() int64 {
+	return s.requests.Load()
+}
+
+// Flush saves new translations to the persistent cache file, if one is configured.
+func (s *LLMService) Flush() error {
+	if s.disk == nil {
+		return nil
+	}
+	return s.disk.Flush()
+}
+
+// cached returns the cached translation of text from the in-memory or persistent cache.
+func (s *LLMService) cached(text string) (string, bool) {
+	if translated, ok := s.cache.Get(text); ok {
+		return translated, true
+	}
+	if s.disk == nil {
+		return "", false
+	}
+	translated, ok := s.disk.Get(diskCacheKey(s.config.Model, s.scope, text))
+	if ok {
+		s.cache.Put(text, translated)
+	}
+	return translated, ok
+}
+
+// Cached reports whether a translation of text is cached, so translating it makes no request.
+func (s *LLMService) Cached(text string) bool {
+	if _, ok := s.cache.Get(text); ok {
+		return true
+	}
+	if s.disk == nil {
+		return false
+	}
+	_, ok := s.disk.Get(diskCacheKey(s.config.Model, s.scope, text))
+	return ok
+}
+
+// store adds a successful translation to the caches.
+func (s *LLMService) store(text, translated string) {
+	s.cache.Put(text, translated)
+	if s.disk != nil {
+		s.disk.Put(diskCacheKey(s.config.Model, s.scope, text), translated)
 	}
 }
 
@@ -59,10 +271,7 @@ func (s *LLMService) TruncateLog(text string, limit int) string {
 // Translate translates the given text using the configured LLM with retries.
 func (s *LLMService) Translate(ctx context.Context, text string) (string, error) {
 	// 1. Check cache first
-	s.mu.RLock()
-
-	if translated, ok := s.cache[text]; ok {
-		s.mu.RUnlock()
+	if translated, ok := s.cached(text); ok {
 		s.logger.Tracef(
 			"Cache hit for text: %s -> %s",
 			s.TruncateLog(text, 80),
@@ -70,15 +279,42 @@ func (s *LLMService) Translate(ctx context.Context, text string) (string, error)
 		)
 		return translated, nil // Cache hit
 	}
-	s.mu.RUnlock()
 	s.logger.Tracef("Cache miss for text: %s", text)
 
-	translatedResult, translateErr := s.doTranslateRequest(ctx, text)
+	// 2. Concurrent requests for the same text share one API call
+	for {
+		result, err, shared := s.inflight.Do(text, func() (any, error) {
+			if translated, ok := s.cached(text); ok {
+				return translated, nil // Finished by another caller since the cache check
+			}
+			return s.translateUncached(ctx, text)
+		})
+		// The call was made with another caller's context; try again if only that one was cancelled
+		if shared && err != nil && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+			continue
+		}
+		if shared {
+			s.logger.Tracef("Shared in-flight translation of text: %s", s.TruncateLog(text, 80))
+		}
+		if err != nil {
+			return "", err
+		}
+		return result.(string), nil
+	}
+}
+
+// translateUncached requests the translation of text and caches the result.
+func (s *LLMService) translateUncached(ctx context.Context, text string) (string, error) {
+	translatedResult, translateErr := s.translateProtected(ctx, text)
+	if translateErr == nil {
+		translatedResult = s.glossary.enforce(text, translatedResult)
+	}
+	if translateErr == nil && strings.TrimSpace(translatedResult) == "" && strings.TrimSpace(text) != "" {
+		return s.handleEmptyResponse(text)
+	}
 	if translateErr == nil {
 		// Store in cache after successful translation
-		s.mu.Lock()
-		s.cache[text] = translatedResult
-		s.mu.Unlock()
+		s.store(text, translatedResult)
 		s.logger.Debugf("Translated text:\n%5s: %s\n%5s: %s",
 			"Orig", s.TruncateLog(text, 80), "Trans", s.TruncateLog(translatedResult, 200))
 		return translatedResult, nil
@@ -86,22 +322,78 @@ func (s *LLMService) Translate(ctx context.Context, text string) (string, error)
 	return "", translateErr
 }
 
-// doTranslateRequest performs the API request using the openai-go library.
-func (s *LLMService) doTranslateRequest(ctx context.Context, text string) (string, error) {
+// translateProtected masks protected characters, requests the translation and restores them.
+func (s *LLMService) translateProtected(ctx context.Context, text string) (string, error) {
+	if !s.protector.enabled() {
+		return s.requestWithRetry(ctx, s.promptFor(text), text)
+	}
+
+	masked, tokens := s.protector.mask(text)
+	if len(tokens) == 0 {
+		return s.requestWithRetry(ctx, s.promptFor(text), text)
+	}
+
+	result, err := s.requestWithRetry(ctx, s.promptFor(masked), masked)
+	if err != nil {
+		return "", err
+	}
+	restored, complete := unmask(result, tokens)
+	if !complete {
+		s.logger.Warnf("Some protected characters were dropped by the model: %s", s.TruncateLog(text, 80))
+	}
+	return restored, nil
+}
+
+// handleEmptyResponse applies the configured policy to an empty model response.
+// The result is not cached so that a later run can try again.
+func (s *LLMService) handleEmptyResponse(text string) (string, error) {
+	switch s.config.EmptyResponse {
+	case EmptyResponseFail:
+		s.logger.Errorf("Empty translation for text: %s", s.TruncateLog(text, 80))
+		return "", ErrEmptyResponse
+	case EmptyResponseBlank:
+		s.logger.Warnf("Empty translation for text, leaving it blank: %s", s.TruncateLog(text, 80))
+		return "", nil
+	default:
+		s.logger.Warnf("Empty translation for text, keeping original: %s", s.TruncateLog(text, 80))
+		return text, nil
+	}
+}
+
+// promptFor returns the prompt for translating text, with extra instructions when needed.
+func (s *LLMService) promptFor(text string) string {
+	prompt := s.prompt
+	if strings.Contains(text, "⟦") {
+		prompt += " " + placeholderInstruction
+	}
+	if terms := s.glossary.instruction(text); terms != "" {
+		prompt += " " + terms
+	}
+	return prompt
+}
+
+// doTranslateRequest performs the API request using the openai-go library, or the Messages API
+// for services created by NewAnthropicService.
+func (s *LLMService) doTranslateRequest(ctx context.Context, prompt, text string) (string, error) {
+	if s.anthropic != nil {
+		return s.doAnthropicRequest(ctx, prompt, text)
+	}
 	trimmed := strings.TrimSpace(text)
 
 	s.logger.Tracef("Sending request to LLM for trimmed: %s", trimmed)
 
 	params := openai.ChatCompletionNewParams{
 		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.UserMessage(s.config.Prompt + "\n\n" + trimmed),
+			openai.UserMessage(prompt + "\n\n" + trimmed),
 		},
 		Model:    s.config.Model,
 		Metadata: map[string]string{"enable_thinking": "false"},
 	}
 
+	s.requests.Add(1)
 	chatCompletion, err := s.client.Chat.Completions.New(ctx, params)
 	if err == nil {
+		s.addTokens(chatCompletion.Usage.TotalTokens)
 		if len(chatCompletion.Choices) == 0 {
 			s.logger.Warnf("No translation choices found in LLM response.")
 			return "", fmt.Errorf("no translation choices found in response")
@@ -0,0 +1,87 @@
+package llmservice
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentTranslationsShareOneRequest(t *testing.T) {
+	server := newFakeServer(t, 1, func(text string) string {
+		time.Sleep(50 * time.Millisecond) // Keep the request in flight while the others start
+		return strings.ToUpper(text)
+	})
+	s := newTestService(server, LLMServiceConfig{})
+
+	var wg sync.WaitGroup
+	results := make([]string, 20)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			translated, err := s.Translate(context.Background(), "hello")
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = translated
+		}()
+	}
+	wg.Wait()
+
+	for _, translated := range results {
+		if translated != "HELLO" {
+			t.Errorf("got %q, want HELLO", translated)
+		}
+	}
+	if n := server.requests.Load(); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestEmptyResponsePolicies(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   string
+		err    error
+	}{
+		{EmptyResponseFail, "", ErrEmptyResponse},
+		{EmptyResponseKeepOriginal, "你好", nil},
+		{EmptyResponseBlank, "", nil},
+		{"", "你好", nil}, // The default keeps the original
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			// The model answers every request with empty content
+			server := newFakeServer(t, 1, func(string) string { return " \n" })
+			s := newTestService(server, LLMServiceConfig{EmptyResponse: tt.policy})
+			for i := range 2 {
+				got, err := s.Translate(context.Background(), "你好")
+				if !errors.Is(err, tt.err) || got != tt.want {
+					t.Fatalf("Translate = %q, %v; want %q, %v", got, err, tt.want, tt.err)
+				}
+				// The result is not cached, the text is requested again
+				if n := server.requests.Load(); n != int64(i+1) {
+					t.Errorf("got %d requests, want %d", n, i+1)
+				}
+			}
+
+			// An empty segment of a batch gets the same policy, the others are translated
+			server = newFakeServer(t, 1, func(text string) string { return "[[1]]\nHELLO\n[[2]]\n " })
+			s = newTestService(server, LLMServiceConfig{EmptyResponse: tt.policy, BatchSize: 2})
+			got, err := s.TranslateBatch(context.Background(), []string{"hello", "你好"})
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("TranslateBatch error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if want := []string{"HELLO", tt.want}; err != nil || !slices.Equal(got, want) {
+				t.Errorf("TranslateBatch = %q, %v; want %q", got, err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package llmservice
+
+import (
+	"exceltranslator/pkg/logger"
+	"time"
+)
+
+// DefaultOllamaBaseURL is the OpenAI-compatible endpoint of a local Ollama server.
+const DefaultOllamaBaseURL = "http://localhost:11434/v1"
+
+// ollamaRequestTimeout allows for local models that are slow to load or to answer.
+const ollamaRequestTimeout = 5 * time.Minute
+
+// NewOllamaService creates an LLMService for a local Ollama server, using its OpenAI-compatible API.
+// BaseURL defaults to DefaultOllamaBaseURL and no API key is needed.
+func NewOllamaService(config LLMServiceConfig, log *logger.Logger) *LLMService {
+	if config.BaseURL == "" {
+		config.BaseURL = DefaultOllamaBaseURL
+	}
+	if config.RequestTimeout == 0 {
+		config.RequestTimeout = ollamaRequestTimeout
+	}
+	return NewLLMService(config, log)
+}
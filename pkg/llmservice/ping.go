@@ -0,0 +1,80 @@
+package llmservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Errors returned by Ping, besides ErrAuthentication. Use errors.Is to tell them apart;
+// the underlying error is wrapped as well.
+var (
+	ErrInvalidURL   = errors.New("cannot reach the API, check the base URL")
+	ErrUnknownModel = errors.New("model not found, check the model name")
+	ErrTimeout      = errors.New("connection timed out, check the network and the base URL")
+)
+
+// pingText is the text translated to check the connection.
+const pingText = "hello"
+
+// Ping checks the base URL, API key and model by translating a short text with a single request,
+// bypassing the cache and retries. It returns nil if the provider answered, or an error wrapping
+// ErrAuthentication, ErrInvalidURL, ErrUnknownModel or ErrTimeout when the cause is known.
+func (s *LLMService) Ping(ctx context.Context) error {
+	_, err := s.doTranslateRequest(ctx, s.prompt, pingText)
+	if err == nil {
+		return nil
+	}
+	if cause := pingCause(err); cause != nil {
+		return fmt.Errorf("%w: %w", cause, err)
+	}
+	return err
+}
+
+// pingCause classifies a failed request, returning nil if the cause is not known.
+func pingCause(err error) error {
+	if code, _, ok := statusError(err); ok {
+		switch {
+		case code == http.StatusUnauthorized || code == http.StatusForbidden:
+			return ErrAuthentication
+		case mentionsModel(err):
+			// OpenAI answers 404 with model_not_found, Anthropic 404 with not_found_error, other providers 400
+			if code == http.StatusNotFound || code == http.StatusBadRequest {
+				return ErrUnknownModel
+			}
+		case code == http.StatusNotFound || code == http.StatusMethodNotAllowed:
+			return ErrInvalidURL
+		}
+		return nil
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrTimeout
+	}
+	// Unknown host, connection refused or a malformed URL
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	if errors.As(err, &dnsErr) || errors.As(err, &opErr) || strings.Contains(err.Error(), "unsupported protocol scheme") {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// mentionsModel reports whether an API error is about the requested model.
+func mentionsModel(err error) bool {
+	var text string
+	var apiErr *openai.Error
+	var anthropicErr *AnthropicError
+	if errors.As(err, &apiErr) {
+		text = apiErr.Code + " " + apiErr.Message
+	} else if errors.As(err, &anthropicErr) {
+		text = anthropicErr.Message
+	}
+	return strings.Contains(strings.ToLower(text), "model")
+}
@@ -0,0 +1,132 @@
+package llmservice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// placeholderInstruction is appended to the prompt when the text contains placeholders.
+const placeholderInstruction = "Keep placeholders like ⟦0⟧ exactly as they are."
+
+// protector masks characters that models tend to drop or describe in words (emoji, symbols)
+// and phrases that must stay verbatim (legal marks, product names) with numbered placeholders
+// before translation, and restores them afterwards.
+type protector struct {
+	emoji   bool     // Protect emoji sequences
+	symbols bool     // Protect all symbol characters, not only emoji
+	phrases []string // Protect these phrases, longest first; see newPhrases
+}
+
+// newPhrases returns the non-empty distinct phrases sorted longest first, so that the longest
+// phrase at a position is protected as a whole.
+func newPhrases(list []string) []string {
+	var phrases []string
+	seen := make(map[string]bool)
+	for _, phrase := range list {
+		if phrase == "" || seen[phrase] {
+			continue
+		}
+		seen[phrase] = true
+		phrases = append(phrases, phrase)
+	}
+	sort.SliceStable(phrases, func(i, j int) bool { return len(phrases[i]) > len(phrases[j]) })
+	return phrases
+}
+
+// enabled reports whether any protection is configured.
+func (p protector) enabled() bool {
+	return p.emoji || p.symbols || len(p.phrases) > 0
+}
+
+// phraseAt returns the protected phrase starting at text[i:], or "" if there is none.
+// Like glossary terms, Latin phrases only match whole words.
+func (p protector) phraseAt(text string, i int) string {
+	for _, phrase := range p.phrases {
+		if strings.HasPrefix(text[i:], phrase) && atTermBoundary(text, i, i+len(phrase)) {
+			return phrase
+		}
+	}
+	return ""
+}
+
+// mask replaces protected character sequences with placeholders like ⟦0⟧.
+// It returns the masked text and the original sequences in placeholder order.
+func (p protector) mask(text string) (string, []string) {
+	var sb strings.Builder
+	var tokens []string
+	var current strings.Builder
+
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "⟦%d⟧", len(tokens))
+		tokens = append(tokens, current.String())
+		current.Reset()
+	}
+
+	for i := 0; i < len(text); {
+		// A phrase gets a placeholder of its own
+		if phrase := p.phraseAt(text, i); phrase != "" {
+			flush()
+			current.WriteString(phrase)
+			flush()
+			i += len(phrase)
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		// Joiners and modifiers only belong to a sequence that has already started
+		if p.protects(r) || (current.Len() > 0 && isEmojiModifier(r)) {
+			current.WriteRune(r)
+			continue
+		}
+		flush()
+		sb.WriteRune(r)
+	}
+	flush()
+
+	return sb.String(), tokens
+}
+
+// protects reports whether the rune starts or continues a protected sequence.
+func (p protector) protects(r rune) bool {
+	if p.emoji && isEmoji(r) {
+		return true
+	}
+	return p.symbols && unicode.IsSymbol(r)
+}
+
+// unmask restores the placeholders in the translated text.
+// It reports false if any placeholder is missing from the translation.
+func unmask(text string, tokens []string) (string, bool) {
+	complete := true
+	for i, token := range tokens {
+		placeholder := fmt.Sprintf("⟦%d⟧", i)
+		if !strings.Contains(text, placeholder) {
+			complete = false
+			continue
+		}
+		text = strings.Replace(text, placeholder, token, 1)
+	}
+	return text, complete
+}
+
+// isEmoji reports whether the rune is in one of the emoji blocks.
+func isEmoji(r rune) bool {
+	return (r >= 0x1F000 && r <= 0x1FAFF) || // Emoticons, pictographs, transport, flags, etc.
+		(r >= 0x2600 && r <= 0x27BF) || // Miscellaneous symbols and dingbats
+		(r >= 0x2B00 && r <= 0x2BFF) // Miscellaneous symbols and arrows
+}
+
+// isEmojiModifier reports whether the rune modifies or joins the preceding emoji.
+func isEmojiModifier(r rune) bool {
+	return r == 0x200D || // Zero width joiner
+		r == 0xFE0F || // Variation selector-16
+		r == 0x20E3 || // Combining enclosing keycap
+		(r >= 0xE0020 && r <= 0xE007F) // Tag characters
+}
@@ -0,0 +1,121 @@
+package llmservice
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMaskEmoji(t *testing.T) {
+	tests := []struct {
+		text   string
+		masked string
+		tokens []string
+	}{
+		{"✅ 已完成", "⟦0⟧ 已完成", []string{"✅"}},
+		{"销售额📈增长", "销售额⟦0⟧增长", []string{"📈"}},
+		{"⚠️注意：库存不足🔥🔥", "⟦0⟧注意：库存不足⟦1⟧", []string{"⚠️", "🔥🔥"}},
+		{"👨‍👩‍👧 家庭套餐", "⟦0⟧ 家庭套餐", []string{"👨‍👩‍👧"}},
+		{"🇨🇳中国区", "⟦0⟧中国区", []string{"🇨🇳"}},
+		{"收入（万元）", "收入（万元）", nil},
+	}
+	p := protector{emoji: true}
+	for _, tt := range tests {
+		masked, tokens := p.mask(tt.text)
+		if masked != tt.masked || !slices.Equal(tokens, tt.tokens) {
+			t.Errorf("mask(%q) = %q, %q, want %q, %q", tt.text, masked, tokens, tt.masked, tt.tokens)
+		}
+		if restored, ok := unmask(masked, tokens); !ok || restored != tt.text {
+			t.Errorf("unmask(%q) = %q, %v, want %q", masked, restored, ok, tt.text)
+		}
+	}
+}
+
+func TestUnmaskDroppedPlaceholder(t *testing.T) {
+	restored, ok := unmask("Done ⟦1⟧", []string{"✅", "🔥"})
+	if ok || restored != "Done 🔥" {
+		t.Errorf("unmask = %q, %v, want %q, false", restored, ok, "Done 🔥")
+	}
+}
+
+func TestTranslateProtectsEmoji(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	words := strings.NewReplacer("已完成", "Done", "销售额", "Sales ", "增长", " growth")
+	server := newFakeServer(t, 1, func(text string) string {
+		mu.Lock()
+		sent = append(sent, text)
+		mu.Unlock()
+		return words.Replace(text)
+	})
+	s := newTestService(server, LLMServiceConfig{ProtectEmoji: true})
+
+	for text, want := range map[string]string{
+		"✅ 已完成":  "✅ Done",
+		"销售额📈增长": "Sales 📈 growth",
+	} {
+		got, err := s.Translate(context.Background(), text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Translate(%q) = %q, want %q", text, got, want)
+		}
+	}
+	for _, text := range sent {
+		if strings.ContainsAny(text, "✅📈") {
+			t.Errorf("emoji sent to the model: %q", text)
+		}
+	}
+}
+
+func TestMaskPhrases(t *testing.T) {
+	tests := []struct {
+		text   string
+		masked string
+		tokens []string
+	}{
+		{"Brand® 新品", "Brand⟦0⟧ 新品", []string{"®"}},
+		// The longest phrase wins
+		{"© Acme Corp. 版权所有", "⟦0⟧ ⟦1⟧ 版权所有", []string{"©", "Acme Corp."}},
+		// Latin phrases only match whole words
+		{"Acmes 公司", "Acmes 公司", nil},
+		{"Acme 公司", "⟦0⟧ 公司", []string{"Acme"}},
+	}
+	p := protector{phrases: newPhrases([]string{"®", "Acme", "©", "Acme Corp.", "®"})}
+	for _, tt := range tests {
+		masked, tokens := p.mask(tt.text)
+		if masked != tt.masked || !slices.Equal(tokens, tt.tokens) {
+			t.Errorf("mask(%q) = %q, %q, want %q, %q", tt.text, masked, tokens, tt.masked, tt.tokens)
+		}
+	}
+}
+
+func TestTranslateProtectsPhrases(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	// The model drops the registered trademark sign
+	words := strings.NewReplacer("新品上市", "new arrival", "®", "")
+	server := newFakeServer(t, 1, func(text string) string {
+		mu.Lock()
+		sent = append(sent, text)
+		mu.Unlock()
+		return words.Replace(text)
+	})
+	s := newTestService(server, LLMServiceConfig{ProtectPhrases: []string{"®", "™"}})
+
+	got, err := s.Translate(context.Background(), "Brand® 新品上市")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Brand® new arrival"; got != want {
+		t.Errorf("Translate = %q, want %q", got, want)
+	}
+	for _, text := range sent {
+		if strings.Contains(text, "®") {
+			t.Errorf("protected phrase sent to the model: %q", text)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package llmservice
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// Supported translation providers.
+const (
+	ProviderOpenAI    = "openai"    // OpenAI-compatible chat completion API (default)
+	ProviderOllama    = "ollama"    // Local Ollama server through its OpenAI-compatible API
+	ProviderAnthropic = "anthropic" // Anthropic Messages API for Claude models
+	ProviderPseudo    = "pseudo"    // Pseudo-localization without any API calls
+)
+
+// PseudoService is a translation engine that pseudo-localizes text instead of translating it.
+// The source is wrapped in markers and padded by roughly a third of its length, which makes
+// untranslated strings and layout overflow easy to spot during localization QA.
+type PseudoService struct{}
+
+// NewPseudoService creates a new PseudoService instance.
+func NewPseudoService() *PseudoService {
+	return &PseudoService{}
+}
+
+// Translate wraps the text in pseudo-localization markers, e.g. "[!!! 原文 ~~~]".
+func (s *PseudoService) Translate(ctx context.Context, text string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	padding := strings.Repeat("~", utf8.RuneCountInString(text)/3+1)
+	return "[!!! " + text + " " + padding + "]", nil
+}
+
+// Ping always succeeds, as pseudo-localization makes no API calls.
+func (s *PseudoService) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
@@ -0,0 +1,54 @@
+package llmservice
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRetryAfter caps the delay honored from a Retry-After header.
+const maxRetryAfter = 5 * time.Minute
+
+// retryAfter returns the delay requested by a 429 (Too Many Requests) response.
+// It reports false if err is not a rate limit error carrying a Retry-After header.
+func retryAfter(err error) (time.Duration, bool) {
+	code, header, ok := statusError(err)
+	if !ok || code != http.StatusTooManyRequests || header == nil {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil {
+		delay = time.Duration(ms * float64(time.Millisecond))
+	} else if v := header.Get("Retry-After"); v == "" {
+		return 0, false
+	} else if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		// Retry-After is either a number of seconds or an HTTP date
+		delay = time.Duration(secs * float64(time.Second))
+	} else if t, err := http.ParseTime(v); err == nil {
+		delay = time.Until(t)
+	} else {
+		return 0, false
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay, true
+}
+
+// sleepContext waits for the given duration or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,149 @@
+package llmservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+const (
+	// defaultRetryBackoff is the delay before the first retry when none is configured.
+	defaultRetryBackoff = 500 * time.Millisecond
+	// maxRetryBackoff caps the delay between two retries.
+	maxRetryBackoff = 30 * time.Second
+	// rateLimitBackoffFactor lengthens the delay after a 429 response without Retry-After,
+	// giving the provider's rate limit window time to pass.
+	rateLimitBackoffFactor = 4
+)
+
+// ErrAuthentication is returned when the provider rejects the API key (HTTP 401 or 403).
+// Such requests are not retried.
+var ErrAuthentication = errors.New("authentication failed, check the API key")
+
+// statusError returns the HTTP status code and response headers of a request the provider
+// rejected, or false if err is not an API error, e.g. a network error or a timeout.
+func statusError(err error) (int, http.Header, bool) {
+	var anthropicErr *AnthropicError
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.StatusCode, anthropicErr.Header, true
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		var header http.Header
+		if apiErr.Response != nil {
+			header = apiErr.Response.Header
+		}
+		return apiErr.StatusCode, header, true
+	}
+	return 0, nil, false
+}
+
+// retryable reports whether a failed request may succeed when sent again, and whether
+// it was rejected by a rate limit. Other 4xx responses are permanent failures.
+func retryable(err error) (retry, rateLimited bool) {
+	code, _, ok := statusError(err)
+	if !ok {
+		return true, false // Network errors and timeouts of a single attempt
+	}
+	switch {
+	case code == http.StatusTooManyRequests:
+		return true, true
+	case code == http.StatusRequestTimeout || code == http.StatusConflict || code >= 500:
+		return true, false
+	}
+	return false, false
+}
+
+// backoff returns the delay before retry number attempt (starting at 0): the base delay doubled
+// for every previous attempt, capped at maxRetryBackoff, with random jitter of up to half the delay
+// so that concurrent requests do not retry in lockstep.
+func backoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 0; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryBackoff)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// authError wraps the rejection of the API key in ErrAuthentication.
+func authError(err error) error {
+	if code, _, ok := statusError(err); ok && (code == http.StatusUnauthorized || code == http.StatusForbidden) {
+		return fmt.Errorf("%w (HTTP %d): %w", ErrAuthentication, code, err)
+	}
+	return err
+}
+
+// limitedRequest performs one attempt of the translation request within the rate limits and the
+// adaptive concurrency limit, reporting temporary failures to the limiter as congestion.
+func (s *LLMService) limitedRequest(ctx context.Context, prompt, text string) (string, error) {
+	tokens := estimateTokens(prompt, text)
+	if err := s.throttle.wait(ctx, tokens); err != nil {
+		return "", err
+	}
+	defer s.throttle.done(tokens)
+	if err := s.limiter.acquire(ctx); err != nil {
+		return "", err
+	}
+	start := time.Now()
+	result, err := s.doTranslateRequest(ctx, prompt, text)
+	latency := time.Since(start)
+	s.limiter.release()
+
+	// Cancelled requests and permanent failures say nothing about the load of the endpoint
+	if err == nil {
+		s.limiter.record(false, latency)
+	} else if congested, _ := retryable(err); congested && ctx.Err() == nil {
+		s.limiter.record(true, latency)
+	}
+	return result, err
+}
+
+// requestWithRetry performs the translation request, retrying failures that may be temporary.
+// A 429 response with a Retry-After header waits exactly the requested delay (up to
+// RateLimitRetries times); other temporary failures are retried up to ClientMaxRetries times
+// with exponential backoff, waiting longer after a rate limit. No attempt is made once the token
+// budget is used up.
+func (s *LLMService) requestWithRetry(ctx context.Context, prompt, text string) (string, error) {
+	limitedRetries, retries := 0, 0
+	for {
+		if err := s.checkBudget(); err != nil {
+			return "", err
+		}
+		result, err := s.limitedRequest(ctx, prompt, text)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return "", err // Cancelled by the caller, not a failure of the attempt
+		}
+
+		if delay, ok := retryAfter(err); ok && limitedRetries < s.rateRetries {
+			limitedRetries++
+			s.logger.Warnf("Rate limited by provider, retrying in %v (attempt %d/%d)", delay, limitedRetries, s.rateRetries)
+			if err := sleepContext(ctx, delay); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		retry, rateLimited := retryable(err)
+		if !retry || retries >= s.maxRetries {
+			return "", authError(err)
+		}
+		delay := backoff(s.retryBackoff, retries)
+		if rateLimited {
+			delay = min(delay*rateLimitBackoffFactor, maxRetryAfter)
+		}
+		retries++
+		s.logger.Warnf("Request failed, retrying in %v (attempt %d/%d): %v", delay.Round(time.Millisecond), retries, s.maxRetries, err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return "", err
+		}
+	}
+}
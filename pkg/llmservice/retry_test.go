@@ -0,0 +1,131 @@
+package llmservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// rateLimitFirst answers the first request with 429 and a Retry-After-Ms header of delay.
+func rateLimitFirst(delay string) func(n int64, w http.ResponseWriter) bool {
+	return func(n int64, w http.ResponseWriter) bool {
+		if n > 1 {
+			return false
+		}
+		w.Header().Set("Retry-After-Ms", delay)
+		http.Error(w, `{"error":{"message":"rate limited"}}`, http.StatusTooManyRequests)
+		return true
+	}
+}
+
+func TestRetryAfterIsWaited(t *testing.T) {
+	server := newFakeServer(t, 1, func(text string) string { return "T " + text })
+	server.reject = rateLimitFirst("300")
+	// RateLimitRetries is left zero, as in a configuration without the setting
+	s := newTestService(server, LLMServiceConfig{})
+
+	start := time.Now()
+	translated, err := s.Translate(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("retried after %v, want at least the requested 300ms", elapsed)
+	}
+	if translated != "T hello" {
+		t.Errorf("got %q, want %q", translated, "T hello")
+	}
+	if n := server.requests.Load(); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+}
+
+func TestRetryAfterDisabled(t *testing.T) {
+	server := newFakeServer(t, 1, func(text string) string { return "T " + text })
+	server.reject = rateLimitFirst("300")
+	s := newTestService(server, LLMServiceConfig{RateLimitRetries: -1})
+
+	if _, err := s.Translate(context.Background(), "hello"); err == nil {
+		t.Error("got no error with rate limit retries disabled")
+	}
+	if n := server.requests.Load(); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+// unavailable answers every request with 503.
+func unavailable(n int64, w http.ResponseWriter) bool {
+	http.Error(w, `{"error":{"message":"overloaded"}}`, http.StatusServiceUnavailable)
+	return true
+}
+
+func TestClientMaxRetries(t *testing.T) {
+	for _, retries := range []int{-1, 1, 4} {
+		server := newFakeServer(t, 1, func(text string) string { return "T " + text })
+		server.reject = unavailable
+		s := newTestService(server, LLMServiceConfig{ClientMaxRetries: retries, RetryBackoff: time.Millisecond})
+
+		if _, err := s.Translate(context.Background(), "hello"); err == nil {
+			t.Errorf("%d retries: got no error from an unavailable endpoint", retries)
+		}
+		// The first attempt and the configured retries
+		if n, want := server.requests.Load(), int64(max(retries, 0)+1); n != want {
+			t.Errorf("%d retries: got %d requests, want %d", retries, n, want)
+		}
+	}
+}
+
+func TestRetryRecovers(t *testing.T) {
+	server := newFakeServer(t, 1, func(text string) string { return "T " + text })
+	server.reject = func(n int64, w http.ResponseWriter) bool { return n <= 2 && unavailable(n, w) }
+	s := newTestService(server, LLMServiceConfig{ClientMaxRetries: 3, RetryBackoff: time.Millisecond})
+
+	translated, err := s.Translate(context.Background(), "hello")
+	if err != nil || translated != "T hello" {
+		t.Errorf("got %q, %v; want %q", translated, err, "T hello")
+	}
+	if n := server.requests.Load(); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+}
+
+func TestDownEndpointFailsInTime(t *testing.T) {
+	// An endpoint refusing connections and one that never answers
+	refused := newFakeServer(t, 1, func(text string) string { return text })
+	refused.Close()
+	hanging := newFakeServer(t, 1, func(text string) string { return text })
+	release := make(chan struct{})
+	hanging.reject = func(n int64, w http.ResponseWriter) bool { <-release; return true }
+	t.Cleanup(func() { close(release) })
+
+	for name, server := range map[string]*fakeServer{"refused": refused, "hanging": hanging} {
+		s := newTestService(server, LLMServiceConfig{
+			ClientMaxRetries: 2,
+			RetryBackoff:     20 * time.Millisecond,
+			RequestTimeout:   100 * time.Millisecond,
+		})
+		start := time.Now()
+		if _, err := s.Translate(context.Background(), "hello"); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+		// Three attempts of at most 100ms and two delays of at most 20ms and 40ms
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s: failed after %v, want a bounded time", name, elapsed)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base := 100 * time.Millisecond
+	for attempt, want := range []time.Duration{base, 2 * base, 4 * base, 8 * base} {
+		for range 20 {
+			if got := backoff(base, attempt); got < want/2 || got > want {
+				t.Errorf("backoff(%v, %d) = %v, want between %v and %v", base, attempt, got, want/2, want)
+			}
+		}
+	}
+	if got := backoff(base, 100); got > maxRetryBackoff {
+		t.Errorf("backoff(%v, 100) = %v, want at most %v", base, got, maxRetryBackoff)
+	}
+}
@@ -0,0 +1,136 @@
+package llmservice
+
+import (
+	"context"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// throttleBurst is the part of a per-minute limit that may be used at once, so that a full
+// minute's worth of requests is not sent in the first second.
+const throttleBurst = 10
+
+// bucket is a token bucket refilled at a constant rate. Its level may become negative when more
+// is taken than it holds, e.g. the tokens of a long response; nothing more is taken until it is
+// refilled to above zero.
+type bucket struct {
+	rate     float64 // Refill per second
+	capacity float64
+	level    float64
+	last     time.Time // Time of the last refill
+}
+
+// newBucket returns a full bucket for a per-minute limit.
+func newBucket(perMinute int64, now time.Time) bucket {
+	capacity := max(float64(perMinute)/throttleBurst, 1)
+	return bucket{rate: float64(perMinute) / 60, capacity: capacity, level: capacity, last: now}
+}
+
+// refill adds what was refilled since the last call.
+func (b *bucket) refill(now time.Time) {
+	b.level = min(b.level+now.Sub(b.last).Seconds()*b.rate, b.capacity)
+	b.last = now
+}
+
+// wait returns how long it takes until the bucket holds at least n.
+func (b *bucket) wait(n float64) time.Duration {
+	if b.level >= n {
+		return 0
+	}
+	return time.Duration((n - b.level) / b.rate * float64(time.Second))
+}
+
+// throttle keeps the requests within the provider's requests per minute (RPM) and tokens per
+// minute (TPM) limits. The tokens of a request are only known from its response, so an estimate
+// is reserved while it is in flight and replaced by its usage afterwards. A nil throttle does not
+// limit.
+type throttle struct {
+	mu       sync.Mutex
+	requests *bucket // nil without an RPM limit
+	tokens   *bucket // nil without a TPM limit
+}
+
+// newThrottle returns a throttle for the given limits, or nil if both are zero.
+func newThrottle(requestsPerMinute int, tokensPerMinute int64) *throttle {
+	if requestsPerMinute <= 0 && tokensPerMinute <= 0 {
+		return nil
+	}
+	now := time.Now()
+	t := &throttle{}
+	if requestsPerMinute > 0 {
+		b := newBucket(int64(requestsPerMinute), now)
+		t.requests = &b
+	}
+	if tokensPerMinute > 0 {
+		b := newBucket(tokensPerMinute, now)
+		t.tokens = &b
+	}
+	return t
+}
+
+// estimateTokens roughly estimates the tokens of a request, counting a token per character of the
+// prompt and the text and as many for the translation. It errs on the high side for Latin text.
+func estimateTokens(prompt, text string) int64 {
+	return int64(utf8.RuneCountInString(prompt) + 2*utf8.RuneCountInString(text))
+}
+
+// wait waits until a request estimated to use the given tokens may be sent within the limits, or
+// until ctx is done, and reserves the tokens. Call done with the same estimate once the request
+// has finished.
+func (t *throttle) wait(ctx context.Context, tokens int64) error {
+	if t == nil {
+		return nil
+	}
+	for {
+		t.mu.Lock()
+		now := time.Now()
+		var delay time.Duration
+		if t.requests != nil {
+			t.requests.refill(now)
+			delay = t.requests.wait(1)
+		}
+		if t.tokens != nil {
+			t.tokens.refill(now)
+			// A request larger than the bucket is sent once it is full
+			delay = max(delay, t.tokens.wait(min(float64(tokens), t.tokens.capacity)))
+		}
+		if delay == 0 {
+			if t.requests != nil {
+				t.requests.level--
+			}
+			if t.tokens != nil {
+				t.tokens.level -= float64(tokens)
+			}
+			t.mu.Unlock()
+			return nil
+		}
+		t.mu.Unlock()
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// used takes the tokens used by a request, as reported by the API, from the TPM limit.
+func (t *throttle) used(tokens int64) {
+	if t == nil || t.tokens == nil || tokens <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokens.refill(time.Now())
+	t.tokens.level -= float64(tokens)
+}
+
+// done returns the tokens reserved by wait, which are replaced by the usage passed to used.
+func (t *throttle) done(tokens int64) {
+	if t == nil || t.tokens == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokens.refill(time.Now())
+	t.tokens.level = min(t.tokens.level+float64(tokens), t.tokens.capacity)
+}
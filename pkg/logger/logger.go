@@ -4,8 +4,13 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // LogLevel defines the severity of a log message.
@@ -20,58 +25,202 @@ const (
 )
 
 // Logger is a custom logger that stores messages in memory and prints to stdout.
+//
+// Messages are formatted by the goroutine that logs them, outside of any lock shared with other
+// goroutines, and then queued. Whichever goroutine finds the queue unattended prints, writes to
+// the log file and stores all queued messages at once behind the logger's mutex, so concurrent
+// logging takes the lock once per batch rather than once per message. The queue is bounded: when
+// it is full, logging waits until the messages are stored. Messages are printed, written and
+// stored in the order they were queued.
 type Logger struct {
 	mu          sync.Mutex
-	logMessages []string    // In-memory buffer for logs to be displayed on frontend
-	stdLogger   *log.Logger // Standard library logger for stdout
-	maxLines    int         // Max number of lines to store
-	minLevel    LogLevel    // Minimum level to output/store
+	logMessages []string                 // In-memory buffer for logs to be displayed on frontend
+	stdLogger   *log.Logger              // Standard library logger for stdout
+	fileLogger  *log.Logger              // Optional logger writing to a rotating file, see NewLoggerWithFile
+	maxLines    int                      // Max number of lines to store
+	minLevel    atomic.Int32             // Minimum level to output/store
+	secrets     atomic.Pointer[[]string] // Masked in every message, see Redact
+
+	queue   chan logLine // Messages waiting to be stored, see store
+	storing sync.Mutex   // Held by the goroutine storing the queued messages
+
+	onLog       func(level LogLevel, msg string) // Optional handler receiving each stored line
+	pending     []logLine                        // Lines waiting to be passed to onLog
+	dispatching bool                             // A goroutine is passing pending lines to onLog
+}
+
+// queueSize is the number of messages that may wait to be stored before logging blocks.
+const queueSize = 1024
+
+// logLine is a log message waiting to be stored or passed to the OnLog handler.
+type logLine struct {
+	level  LogLevel
+	msg    string // Message as passed to the OnLog handler
+	entry  string // Message with its level as stored in the buffer
+	output string // Entry with the time and the caller as printed and written to the log file
 }
 
 // NewLogger creates a new Logger instance.
 func NewLogger(maxLines int) *Logger {
-	return &Logger{
-		stdLogger:   log.New(os.Stdout, "", log.Ldate|log.Ltime|log.Lshortfile),
+	l := &Logger{
+		stdLogger:   log.New(os.Stdout, "", 0), // Lines are prefixed by logf, see outputPrefix
 		maxLines:    maxLines,
 		logMessages: make([]string, 0, maxLines),
-		minLevel:    DEBUG, // Default to DEBUG
+		queue:       make(chan logLine, queueSize),
+	}
+	l.minLevel.Store(int32(DEBUG)) // Default to DEBUG
+	return l
+}
+
+// NewLoggerWithFile creates a Logger that also writes every message to the file at path. The file
+// is rotated when it reaches 1 MB, keeping the last 3 files (path, path.1 and path.2). header,
+// e.g. the configuration of the run, is written at the top of every new file and once per Logger
+// when appending to an existing file. An empty path disables the file.
+func NewLoggerWithFile(maxLines int, path, header string) (*Logger, error) {
+	l := NewLogger(maxLines)
+	if path == "" {
+		return l, nil
+	}
+	file, err := newRotatingFile(path, header)
+	if err != nil {
+		return nil, err
 	}
+	l.fileLogger = log.New(file, "", 0)
+	return l, nil
+}
+
+// SetSecrets sets the secrets, e.g. the API key, that are masked in every message.
+// Anything that looks like an API key is masked regardless, see Redact.
+func (l *Logger) SetSecrets(secrets ...string) {
+	l.secrets.Store(&secrets)
 }
 
 // SetLevel updates the minimum log level.
 func (l *Logger) SetLevel(level LogLevel) {
+	l.minLevel.Store(int32(level))
+}
+
+// SetOnLog sets a handler that receives every message as it is logged, e.g. to stream the log
+// to a GUI instead of polling GetLogs. Messages below the minimum level are not passed on.
+// nil removes the handler.
+//
+// The handler is called without holding the logger's lock, one message at a time and in the
+// order the messages were logged, so it may log again: such messages are passed to it after it
+// returns. It may be called from any goroutine that logs or reads the logs.
+func (l *Logger) SetOnLog(fn func(level LogLevel, msg string)) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.minLevel = level
+	l.onLog = fn
 }
 
 // GetLevel returns the current minimum log level.
 func (l *Logger) GetLevel() LogLevel {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	return l.minLevel
+	return LogLevel(l.minLevel.Load())
 }
 
 // logf formats according to a format specifier and writes to the logger.
 func (l *Logger) logf(level LogLevel, format string, v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	if levelRank(level) < levelRank(l.minLevel) {
+	if levelRank(level) < levelRank(l.GetLevel()) {
 		return
 	}
 
-	msg := fmt.Sprintf(format, v...)
+	var secrets []string
+	if p := l.secrets.Load(); p != nil {
+		secrets = *p
+	}
+	msg := Redact(fmt.Sprintf(format, v...), secrets...)
 	logEntry := fmt.Sprintf("[%s] %s", strings.ToUpper(level.String()), msg)
 
-	// Output to stdout/stderr (depending on log.Logger setup)
-	l.stdLogger.Output(2, logEntry) // Use Output to get correct file/line number
+	output := outputPrefix(time.Now(), 2) + logEntry // Use the caller's file/line number
+
+	l.queue <- logLine{level: level, msg: msg, entry: logEntry, output: output}
+	// Store the queue unless another goroutine is storing it. That goroutine checks the queue
+	// again after it is done, so a message queued meanwhile is not left behind.
+	for len(l.queue) > 0 && l.storing.TryLock() {
+		l.store()
+		l.storing.Unlock()
+		l.dispatch()
+	}
+}
+
+// store prints the queued messages, writes them to the log file and moves them to the buffer and
+// the lines pending for the OnLog handler. The caller must hold l.storing.
+func (l *Logger) store() {
+	for {
+		var lines []logLine
+	drain:
+		for {
+			select {
+			case line := <-l.queue:
+				lines = append(lines, line)
+			default:
+				break drain
+			}
+		}
+		if len(lines) == 0 {
+			return
+		}
+
+		l.mu.Lock()
+		for _, line := range lines {
+			// Output to stdout/stderr (depending on log.Logger setup)
+			l.stdLogger.Print(line.output)
+			if l.fileLogger != nil {
+				l.fileLogger.Print(line.output) // A failing log file does not stop the logging
+			}
+			l.logMessages = append(l.logMessages, line.entry)
+			if l.onLog != nil {
+				l.pending = append(l.pending, line)
+			}
+		}
+		if len(l.logMessages) > l.maxLines {
+			// Truncate from the beginning, keep only the last 'maxLines' entries
+			l.logMessages = l.logMessages[len(l.logMessages)-l.maxLines:]
+		}
+		l.mu.Unlock()
+	}
+}
 
-	l.logMessages = append(l.logMessages, logEntry)
-	if len(l.logMessages) > l.maxLines {
-		// Truncate from the beginning, keep only the last 'maxLines' entries
-		l.logMessages = l.logMessages[len(l.logMessages)-l.maxLines:]
+// outputPrefix returns the prefix a log.Logger with the flags Ldate, Ltime and Lshortfile writes at
+// time t. calldepth selects the caller as in log.Logger.Output: 1 is the caller of outputPrefix.
+func outputPrefix(t time.Time, calldepth int) string {
+	_, file, line, ok := runtime.Caller(calldepth)
+	if !ok {
+		file, line = "???", 0
+	}
+	return t.Format("2006/01/02 15:04:05 ") + filepath.Base(file) + ":" + strconv.Itoa(line) + ": "
+}
+
+// flush stores the messages queued so far, waiting for the goroutine storing them if any.
+func (l *Logger) flush() {
+	l.storing.Lock()
+	l.store()
+	l.storing.Unlock()
+	l.dispatch()
+}
+
+// dispatch passes the pending lines to the OnLog handler outside of the lock. Only one goroutine
+// dispatches at a time; lines logged meanwhile, also by the handler itself, are queued and
+// passed on by that goroutine, which keeps them in order.
+func (l *Logger) dispatch() {
+	l.mu.Lock()
+	if l.dispatching || len(l.pending) == 0 {
+		l.mu.Unlock()
+		return
+	}
+	l.dispatching = true
+	for len(l.pending) > 0 && l.onLog != nil {
+		lines, onLog := l.pending, l.onLog
+		l.pending = nil
+		l.mu.Unlock()
+		for _, line := range lines {
+			onLog(line.level, line.msg)
+		}
+		l.mu.Lock()
 	}
+	l.pending = nil
+	l.dispatching = false
+	l.mu.Unlock()
 }
 
 // Infof logs an info message.
@@ -101,6 +250,7 @@ func (l *Logger) Tracef(format string, v ...interface{}) {
 
 // GetLogs returns the current logs from the buffer as a string slice.
 func (l *Logger) GetLogs() []string {
+	l.flush()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	// Return a copy to prevent external modification
@@ -111,6 +261,7 @@ func (l *Logger) GetLogs() []string {
 
 // Clear removes all in-memory log messages.
 func (l *Logger) Clear() {
+	l.flush()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.logMessages = l.logMessages[:0]
@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentLogOrder checks that the printed lines, the log file, the buffer and the OnLog
+// handler all get the messages of concurrent goroutines in the same order.
+func TestConcurrentLogOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, err := NewLoggerWithFile(10000, path, "=== run ===")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	l.stdLogger.SetOutput(&stdout)
+	var handled []string
+	l.SetOnLog(func(level LogLevel, msg string) { handled = append(handled, "[INFO] "+msg) })
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				l.Infof("goroutine %d message %d", g, i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	logs := l.GetLogs()
+	if len(logs) != 1600 {
+		t.Fatalf("got %d stored messages, want 1600", len(logs))
+	}
+	file, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, got := range map[string][]string{
+		"stdout":   entries(stdout.String()),
+		"log file": entries(string(file)),
+		"OnLog":    handled,
+	} {
+		if !slices.Equal(got, logs) {
+			t.Errorf("%s has %d lines in another order than the %d stored messages", name, len(got), len(logs))
+		}
+	}
+}
+
+// entries returns the logged entries of printed lines, without their time and caller.
+func entries(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if i := strings.Index(line, "[INFO] "); i >= 0 {
+			lines = append(lines, line[i:])
+		}
+	}
+	return lines
+}
+
+func TestOutputNamesCaller(t *testing.T) {
+	l := NewLogger(10)
+	var stdout bytes.Buffer
+	l.stdLogger.SetOutput(&stdout)
+	l.Warnf("disk %s", "full")
+	l.GetLogs()
+	// As with log.Lshortfile, the line names the file and line of the logging method
+	if got := stdout.String(); !strings.Contains(got, " logger.go:") || !strings.HasSuffix(got, ": [WARN] disk full\n") {
+		t.Errorf("got %q, want a line with the date, time, caller and message", got)
+	}
+}
+
+// nopWriter discards what is written. Unlike io.Discard, a log.Logger writing to it still
+// formats its lines, as when it prints them.
+type nopWriter struct{}
+
+func (nopWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+// BenchmarkConcurrentLogging measures logging from many goroutines at once, which takes the
+// logger's mutex once per batch of queued messages rather than once per message. Compare it with
+// BenchmarkConcurrentLoggingLockPerMessage on several CPUs, e.g. with -cpu 1,4,8.
+func BenchmarkConcurrentLogging(b *testing.B) {
+	l := NewLogger(100)
+	l.stdLogger.SetOutput(nopWriter{})
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			l.Infof("message %d with %s", i, "some text")
+			i++
+		}
+	})
+}
+
+// BenchmarkConcurrentLoggingWithHandler is BenchmarkConcurrentLogging with an OnLog handler,
+// as set by the GUI.
+func BenchmarkConcurrentLoggingWithHandler(b *testing.B) {
+	l := NewLogger(100)
+	l.stdLogger.SetOutput(nopWriter{})
+	l.SetOnLog(func(level LogLevel, msg string) { _ = fmt.Sprint(level, msg) })
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Infof("message with %s", "some text")
+		}
+	})
+}
+
+// lockPerMessage logs as Logger did before messages were queued: every message is formatted,
+// printed and stored behind one mutex. It is the reference for the benchmarks above.
+type lockPerMessage struct {
+	mu       sync.Mutex
+	out      *log.Logger
+	messages []string
+}
+
+func (l *lockPerMessage) Infof(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := "[INFO] " + Redact(fmt.Sprintf(format, v...))
+	l.out.Output(2, entry)
+	l.messages = append(l.messages, entry)
+	if len(l.messages) > 100 {
+		l.messages = l.messages[len(l.messages)-100:]
+	}
+}
+
+func BenchmarkConcurrentLoggingLockPerMessage(b *testing.B) {
+	l := &lockPerMessage{out: log.New(nopWriter{}, "", log.Ldate|log.Ltime|log.Lshortfile)}
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			l.Infof("message %d with %s", i, "some text")
+			i++
+		}
+	})
+}
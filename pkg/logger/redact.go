@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+)
+
+// keyPattern matches OpenAI-style API keys, which are masked even when they are not configured.
+var keyPattern = regexp.MustCompile(`sk-[A-Za-z0-9_-]{8,}`)
+
+// Redact masks the secrets and anything that looks like an API key in text. Keys starting
+// with "sk-" become "sk-***", other secrets "***".
+func Redact(text string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, mask(secret))
+		}
+	}
+	return keyPattern.ReplaceAllString(text, "sk-***")
+}
+
+// mask returns the replacement of a secret, keeping the well-known key prefix.
+func mask(secret string) string {
+	if strings.HasPrefix(secret, "sk-") {
+		return "sk-***"
+	}
+	return "***"
+}
+
+// redactedError is an error whose message had secrets masked. It unwraps to the original error,
+// so errors.Is and errors.As keep working.
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// RedactError returns err with the secrets masked in its message, see Redact. Errors without
+// secrets are returned unchanged.
+func RedactError(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+	msg := Redact(err.Error(), secrets...)
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{err: err, msg: msg}
+}
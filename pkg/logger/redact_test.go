@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		text    string
+		secrets []string
+		want    string
+	}{
+		{"key sk-abcdef123456 rejected", []string{"sk-abcdef123456"}, "key sk-*** rejected"},
+		{"key sk-proj_AbC-123456789 rejected", nil, "key sk-*** rejected"}, // Not configured
+		{"token abc123secret in url ?k=abc123secret", []string{"abc123secret"}, "token *** in url ?k=***"},
+		{"sk-short stays", nil, "sk-short stays"},
+		{"nothing to hide", []string{""}, "nothing to hide"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.text, tt.secrets...); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	cause := errors.New("401: invalid key sk-abcdef123456")
+	err := RedactError(cause, "sk-abcdef123456")
+	if err.Error() != "401: invalid key sk-***" || !errors.Is(err, cause) {
+		t.Errorf("got %q, want the key masked and the cause kept", err)
+	}
+	if plain := errors.New("timeout"); RedactError(plain, "sk-abcdef123456") != plain {
+		t.Errorf("RedactError changed an error without secrets")
+	}
+	if RedactError(nil, "sk-abcdef123456") != nil {
+		t.Errorf("RedactError(nil) is not nil")
+	}
+}
+
+func TestLoggerMasksSecrets(t *testing.T) {
+	l := NewLogger(10)
+	l.SetSecrets("my-secret-key")
+	var streamed []string
+	l.SetOnLog(func(_ LogLevel, msg string) { streamed = append(streamed, msg) })
+
+	l.Errorf("request with key %s failed", "my-secret-key")
+	l.Infof("config: api_key = %q", "sk-abcdef123456")
+
+	logs := strings.Join(append(l.GetLogs(), streamed...), "\n")
+	if strings.Contains(logs, "my-secret-key") || strings.Contains(logs, "sk-abcdef123456") {
+		t.Errorf("got logs\n%s\nwant the keys masked", logs)
+	}
+	if !strings.Contains(logs, "request with key *** failed") || !strings.Contains(logs, `api_key = "sk-***"`) {
+		t.Errorf("got logs\n%s\nwant the masked keys", logs)
+	}
+}
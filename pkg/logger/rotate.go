@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	defaultLogFileSize  = 1 << 20 // Size at which the log file is rotated
+	defaultLogFileCount = 3       // Log files kept, including the current one
+)
+
+// rotatingFile appends log lines to a file, moving it to path.1, path.2, ... when it grows
+// beyond maxSize. The file is opened for every write, so no handle is kept open.
+// It is not safe for concurrent use; Logger writes to it behind its mutex, see Logger.store.
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	count   int
+	header  string // Written at the top of every new file and at the first write of a run
+	started bool   // The header has been written for this run
+}
+
+func newRotatingFile(path, header string) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	return &rotatingFile{path: path, maxSize: defaultLogFileSize, count: defaultLogFileCount, header: header}, nil
+}
+
+// Write appends p to the log file, rotating it first if p does not fit.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	info, err := os.Stat(r.path)
+	size := int64(0)
+	if err == nil {
+		size = info.Size()
+	}
+	if size > 0 && size+int64(len(p)) > r.maxSize {
+		r.rotate()
+		size = 0
+	}
+
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if r.header != "" && (size == 0 || !r.started) {
+		if _, err := f.WriteString(r.header); err != nil {
+			return 0, err
+		}
+	}
+	r.started = true
+	return f.Write(p)
+}
+
+// rotate moves the log file to path.1, shifting the older files and dropping the oldest.
+func (r *rotatingFile) rotate() {
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.count-1))
+	for i := r.count - 2; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	os.Rename(r.path, r.path+".1")
+}
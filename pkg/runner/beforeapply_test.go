@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"exceltranslator/pkg/textextractor"
+)
+
+func TestBeforeApply(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.xlsx"), filepath.Join(dir, "out.xlsx")
+	writeWorkbook(t, input, "收入", "成本", "利润", "收入")
+
+	server := newFakeLLM(t, 10, 0)
+	english := map[string]string{"收入": "revenue", "成本": "cost", "利润": "profit"}
+	server.translate = func(text string) string { return english[text] }
+	var reviewed []string
+	cb := testCallbacks(t)
+	cb.OnBeforeApply = func(src, dst string) (string, bool) {
+		reviewed = append(reviewed, src)
+		switch src {
+		case "成本":
+			return "", false // Vetoed, the source text is kept
+		case "利润":
+			return dst, true
+		}
+		return strings.ToUpper(dst), true
+	}
+	if err := RunTranslationWithConfig(context.Background(), input, output, server.config(), cb); err != nil {
+		t.Fatal(err)
+	}
+
+	got := textextractor.SharedStrings(readPart(t, output, "xl/sharedStrings.xml"))
+	if want := []string{"REVENUE", "成本", "profit", "REVENUE"}; !slices.Equal(got, want) {
+		t.Errorf("shared strings = %q, want %q", got, want)
+	}
+	// Every segment is reviewed, in document order
+	if want := []string{"收入", "成本", "利润", "收入"}; !slices.Equal(reviewed, want) {
+		t.Errorf("reviewed %q, want %q", reviewed, want)
+	}
+}
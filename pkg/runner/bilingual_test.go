@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"exceltranslator/pkg/fileprocessor"
+	"exceltranslator/pkg/textextractor"
+)
+
+// TestBilingualWorkbookAddsMirrorSheets checks that a bilingual workbook keeps its sheets and
+// adds a sheet showing the translation of each.
+func TestBilingualWorkbookAddsMirrorSheets(t *testing.T) {
+	const main = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+	const rel = `xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	sheet := `<worksheet ` + main + ` ` + rel + `><sheetViews><sheetView tabSelected="1" workbookViewId="0"/></sheetViews><sheetData>` +
+		`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1"><v>42</v></c><c r="D1" t="str"><f>"合计"</f><v>合计</v></c></row>` +
+		`</sheetData><autoFilter ref="A1:B1"><filterColumn colId="0"><filters><filter val="收入"/></filters></filterColumn></autoFilter>` +
+		`<hyperlinks><hyperlink ref="A1" r:id="rId1" tooltip="打开报表"/></hyperlinks><pageSetup r:id="rId2"/></worksheet>`
+	workbook := `<workbook ` + main + ` ` + rel + `><sheets><sheet name="数据" sheetId="1" r:id="rId1"/><sheet name="备注" sheetId="3" state="hidden" r:id="rId2"/></sheets></workbook>`
+	sst := `<sst ` + main + ` count="3" uniqueCount="2"><si><t>收入</t></si><si><t>成本</t></si></sst>`
+
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.xlsx"), filepath.Join(dir, "out.xlsx")
+	writeZip(t, input,
+		[2]string{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"></Types>`},
+		[2]string{"xl/workbook.xml", workbook},
+		[2]string{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/></Relationships>`},
+		[2]string{"xl/worksheets/sheet1.xml", sheet},
+		[2]string{"xl/worksheets/sheet2.xml", `<worksheet ` + main + `><sheetData><row r="1"><c r="A1" t="s"><v>0</v></c></row></sheetData></worksheet>`},
+		[2]string{"xl/sharedStrings.xml", sst},
+	)
+	cfg := pseudoConfig()
+	cfg.Processor.OutputMode = fileprocessor.OutputBilingual
+	cfg.Extractor.AutoFilters = true
+	cfg.Extractor.HyperlinkTooltips = true
+	cfg.Extractor.FormulaResults = true
+	if err := RunTranslationWithConfig(context.Background(), input, output, cfg, testCallbacks(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The source sheets are kept as they are
+	if got := readPart(t, output, "xl/worksheets/sheet1.xml"); got != sheet {
+		t.Errorf("got sheet\n%s\nwant it unchanged", got)
+	}
+	texts := textextractor.SharedStrings(readPart(t, output, "xl/sharedStrings.xml"))
+	if len(texts) != 4 || texts[0] != "收入" || texts[1] != "成本" || texts[2] == "收入" || texts[3] == "成本" ||
+		strings.Contains(texts[2], "\n") {
+		t.Fatalf("got shared strings %q, want the source strings and a translated copy of each", texts)
+	}
+
+	// The mirror sheets follow the source sheets, named after their translation
+	gotWorkbook := readPart(t, output, "xl/workbook.xml")
+	sheets := textextractor.WorkbookSheets(gotWorkbook)
+	if len(sheets) != 4 || sheets[0].Name != "数据" || sheets[1].Name != "备注" ||
+		sheets[2].Name == "数据" || sheets[2].RelID != "rId3" || sheets[3].RelID != "rId4" || sheets[3].State != "hidden" {
+		t.Fatalf("got sheets %+v, want the source sheets followed by their mirrors", sheets)
+	}
+	if !strings.Contains(gotWorkbook, `sheetId="4"`) || !strings.Contains(gotWorkbook, `sheetId="5"`) {
+		t.Errorf("got workbook %s, want new sheet ids", gotWorkbook)
+	}
+	if rels := readPart(t, output, "xl/_rels/workbook.xml.rels"); !strings.Contains(rels, `Id="rId3"`) || !strings.Contains(rels, `Target="worksheets/sheet3.xml"`) {
+		t.Errorf("got relationships %s, want the mirror sheets", rels)
+	}
+	if types := readPart(t, output, "[Content_Types].xml"); !strings.Contains(types, `PartName="/xl/worksheets/sheet3.xml"`) || !strings.Contains(types, `PartName="/xl/worksheets/sheet4.xml"`) {
+		t.Errorf("got content types %s, want the mirror sheets", types)
+	}
+
+	mirror := readPart(t, output, "xl/worksheets/sheet3.xml")
+	want := map[string]int{"A1": 2, "B1": 3}
+	for _, cell := range textextractor.SharedStringCells(mirror) {
+		if cell.Index != want[cell.Ref] {
+			t.Errorf("got mirror cell %s showing %d, want %d", cell.Ref, cell.Index, want[cell.Ref])
+		}
+	}
+	for _, dropped := range []string{"tabSelected", "<hyperlinks>", "r:id", `val="收入"`, "<v>合计</v>", "\n"} {
+		if strings.Contains(mirror, dropped) {
+			t.Errorf("got mirror sheet %s, want no %q", mirror, dropped)
+		}
+	}
+	if !strings.Contains(mirror, "<v>42</v>") || !strings.Contains(mirror, `<f>"合计"</f>`) {
+		t.Errorf("got mirror sheet %s, want the numbers and formulas of the sheet", mirror)
+	}
+}
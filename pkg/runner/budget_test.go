@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"exceltranslator/pkg/textextractor"
+	"exceltranslator/pkg/translator"
+)
+
+func TestBudgetStopsEarlyAndSavesPartialOutput(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "a.xlsx"), filepath.Join(dir, "b.xlsx")
+	texts := []string{"收入", "成本", "利润", "费用", "税金", "现金"}
+	writeWorkbook(t, input, texts...)
+
+	// Every request uses 6 tokens, so the budget is used up after the second
+	server := newFakeLLM(t, 6, 0)
+	cfg := server.config()
+	cfg.LLM.MaxTokensBudget = 10
+	cfg.Translator.Concurrency = 1
+
+	var stats Stats
+	cb := testCallbacks(t)
+	cb.OnStats = func(s Stats) { stats = s }
+	err := RunTranslationWithConfig(context.Background(), input, output, cfg, cb)
+	if !errors.Is(err, translator.ErrBudgetExceeded) {
+		t.Fatalf("got error %v, want ErrBudgetExceeded", err)
+	}
+	if n := server.requests.Load(); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+
+	// The translations made before the budget ran out are saved, the other texts are kept
+	got := textextractor.SharedStrings(readPart(t, output, "xl/sharedStrings.xml"))
+	if len(got) != len(texts) {
+		t.Fatalf("got shared strings %q", got)
+	}
+	translated := 0
+	for i, text := range got {
+		switch text {
+		case "T " + texts[i]:
+			translated++
+		case texts[i]:
+		default:
+			t.Errorf("got %q for %q", text, texts[i])
+		}
+	}
+	if translated != 2 || !strings.HasPrefix(got[0], "T ") {
+		t.Errorf("got shared strings %q, want the first two translated", got)
+	}
+	if stats.TokensLeft != 0 {
+		t.Errorf("got %d tokens left, want 0", stats.TokensLeft)
+	}
+}
@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"exceltranslator/pkg/config"
+	"exceltranslator/pkg/textextractor"
+)
+
+// writeSelectionWorkbook writes a workbook whose sheet shows 收入 in the bold cell A1 and the
+// plain cell B1, and 成本 in the bold cell A2.
+func writeSelectionWorkbook(t *testing.T, path string) {
+	t.Helper()
+	const main = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+	writeZip(t, path,
+		[2]string{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		[2]string{"xl/workbook.xml", `<workbook ` + main + ` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		[2]string{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+		[2]string{"xl/styles.xml", `<styleSheet ` + main + `><fonts count="2"><font><sz val="11"/></font><font><b/><sz val="11"/></font></fonts><cellXfs count="2"><xf fontId="0"/><xf fontId="1"/></cellXfs></styleSheet>`},
+		[2]string{"xl/worksheets/sheet1.xml", `<worksheet ` + main + `><sheetData><row r="1"><c r="A1" s="1" t="s"><v>0</v></c><c r="B1" t="s"><v>0</v></c></row><row r="2"><c r="A2" s="1" t="s"><v>1</v></c></row></sheetData></worksheet>`},
+		[2]string{"xl/sharedStrings.xml", `<sst ` + main + ` count="3" uniqueCount="2"><si><t>收入</t></si><si><t>成本</t></si></sst>`},
+	)
+}
+
+// readPart returns the content of a part of a zip archive.
+func readPart(t *testing.T, path, name string) string {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		b, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	t.Fatalf("%s has no part %s", path, name)
+	return ""
+}
+
+// TestCellSelectionKeepsSharedStringsOfOtherCells checks that a shared string shown in both a
+// selected cell and another cell is only translated in the selected cell.
+func TestCellSelectionKeepsSharedStringsOfOtherCells(t *testing.T) {
+	tests := []struct {
+		name  string
+		apply func(cfg *config.AppConfig)
+	}{
+		{"cell style", func(cfg *config.AppConfig) { cfg.Extractor.CellStyle.Bold = true }},
+		{"ranges", func(cfg *config.AppConfig) { cfg.Extractor.Ranges = []string{"Data!A:A"} }},
+		{"cell style and ranges", func(cfg *config.AppConfig) {
+			cfg.Extractor.CellStyle.Bold = true
+			cfg.Extractor.Ranges = []string{"A1:B2"}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input, output := filepath.Join(dir, "in.xlsx"), filepath.Join(dir, "out.xlsx")
+			writeSelectionWorkbook(t, input)
+			cfg := pseudoConfig()
+			tt.apply(cfg)
+			if err := RunTranslationWithConfig(context.Background(), input, output, cfg, testCallbacks(t)); err != nil {
+				t.Fatal(err)
+			}
+
+			sst := readPart(t, output, "xl/sharedStrings.xml")
+			texts := textextractor.SharedStrings(sst)
+			if len(texts) != 3 {
+				t.Fatalf("got shared strings %q, want the two strings and a copy of 收入", texts)
+			}
+			if !strings.Contains(sst, `uniqueCount="3"`) || !strings.Contains(sst, `count="3"`) {
+				t.Errorf("got %s, want count 3 and uniqueCount 3", sst)
+			}
+			if texts[0] != "收入" {
+				t.Errorf("got %q for the string shown in B1, want it untranslated", texts[0])
+			}
+			if texts[1] == "成本" || texts[2] == "收入" {
+				t.Errorf("got %q, want the strings of the selected cells translated", texts)
+			}
+
+			want := map[string]int{"A1": 2, "B1": 0, "A2": 1}
+			for _, cell := range textextractor.SharedStringCells(readPart(t, output, "xl/worksheets/sheet1.xml")) {
+				if cell.Index != want[cell.Ref] {
+					t.Errorf("got %s showing shared string %d, want %d", cell.Ref, cell.Index, want[cell.Ref])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPhaseConcurrency(t *testing.T) {
+	const main = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+	var sst, shapes strings.Builder
+	for i := range 12 {
+		fmt.Fprintf(&sst, "<si><t>单元格%d</t></si>", i)
+		fmt.Fprintf(&shapes, "<a:p><a:r><a:t>形状%d</a:t></a:r></a:p>", i)
+	}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "a.xlsx")
+	writeZip(t, input,
+		[2]string{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		[2]string{"xl/sharedStrings.xml", `<sst ` + main + `>` + sst.String() + `</sst>`},
+		[2]string{"xl/drawings/drawing1.xml", `<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">` +
+			`<xdr:sp><xdr:txBody>` + shapes.String() + `</xdr:txBody></xdr:sp></xdr:wsDr>`},
+	)
+
+	// The requests in flight and the most at once, by phase
+	var mu sync.Mutex
+	inFlight, peak := map[string]int{}, map[string]int{}
+	server := newFakeLLM(t, 1, 0)
+	server.translate = func(text string) string {
+		phase := "cell"
+		if strings.HasPrefix(text, "形状") {
+			phase = "shape"
+		}
+		mu.Lock()
+		inFlight[phase]++
+		peak[phase] = max(peak[phase], inFlight[phase])
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight[phase]--
+		mu.Unlock()
+		return "T " + text
+	}
+
+	cfg := server.config()
+	cfg.Translator.Concurrency = 6
+	cfg.Translator.PhaseConcurrency.Cell = 3
+	cfg.Translator.PhaseConcurrency.Shape = 1
+	if err := RunTranslationWithConfig(context.Background(), input, filepath.Join(dir, "b.xlsx"), cfg, testCallbacks(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each phase reaches its own limit and no more
+	if peak["cell"] != 3 || peak["shape"] != 1 {
+		t.Errorf("peak requests in flight = %v, want 3 for cells and 1 for shapes", peak)
+	}
+	if n := server.peak.Load(); n != 3 {
+		t.Errorf("peak requests in flight overall = %d, want 3", n)
+	}
+}
@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"exceltranslator/pkg/config"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// TestTOMLTunablesReachPipeline checks that the concurrency and cache settings of a TOML
+// configuration, as passed to cmd/lib, are honored.
+func TestTOMLTunablesReachPipeline(t *testing.T) {
+	server := newFakeLLM(t, 1, 20*time.Millisecond)
+	blob := fmt.Sprintf(`
+[llm]
+base_url = '%s'
+model = 'test'
+client_max_retries = -1
+cache_size = -1
+
+[translator]
+concurrency = 4
+`, server.URL)
+	var cfg config.AppConfig
+	if err := toml.Unmarshal([]byte(blob), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Translator.Concurrency != 4 || cfg.LLM.CacheSize != -1 {
+		t.Fatalf("got concurrency %d and cache size %d, want 4 and -1", cfg.Translator.Concurrency, cfg.LLM.CacheSize)
+	}
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "a.xlsx")
+	writeWorkbook(t, input, "一", "二", "三", "四", "五", "六", "七", "八")
+	engine := NewEngine(&cfg)
+	for i := range 2 {
+		if err := engine.Translate(context.Background(), input, filepath.Join(dir, fmt.Sprintf("out%d.xlsx", i)), testCallbacks(t)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if peak := server.peak.Load(); peak < 2 || peak > 4 {
+		t.Errorf("got at most %d requests in flight, want 2 to 4", peak)
+	}
+	// Without a cache the second run sends every text again
+	if n := server.requests.Load(); n != 16 {
+		t.Errorf("got %d requests, want 16", n)
+	}
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeStampedWorkbook writes a workbook with a translated and a copied part, both modified at mtime.
+func writeStampedWorkbook(t *testing.T, path string, mtime time.Time) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, part := range [][2]string{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		{"xl/sharedStrings.xml", `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>收入</t></si></sst>`},
+		{"docProps/app.xml", `<Properties/>`},
+	} {
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: part[0], Method: zip.Deflate, Modified: mtime})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(part[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// translateTwice translates the same workbook saved at two different times and returns both outputs.
+func translateTwice(t *testing.T, deterministic bool) (first, second []byte) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := pseudoConfig()
+	cfg.Processor.Deterministic = deterministic
+
+	var outputs [][]byte
+	for i, mtime := range []time.Time{
+		time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC),
+		time.Date(2025, 7, 15, 18, 5, 42, 0, time.UTC),
+	} {
+		in := filepath.Join(dir, "in"+string(rune('a'+i))+".xlsx")
+		out := filepath.Join(dir, "out"+string(rune('a'+i))+".xlsx")
+		writeStampedWorkbook(t, in, mtime)
+		if err := RunTranslationWithConfig(context.Background(), in, out, cfg, testCallbacks(t)); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, data)
+	}
+	return outputs[0], outputs[1]
+}
+
+func TestDeterministicOutput(t *testing.T) {
+	first, second := translateTwice(t, true)
+	if !bytes.Equal(first, second) {
+		t.Error("deterministic outputs of the same workbook saved at different times differ")
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range r.File {
+		if !f.Modified.Equal(time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s modified at %v, want 1980-01-01", f.Name, f.Modified)
+		}
+	}
+
+	// Without the option the outputs keep the times of the source entries
+	if first, second := translateTwice(t, false); bytes.Equal(first, second) {
+		t.Error("outputs without deterministic mode are identical; the test inputs do not differ")
+	}
+}
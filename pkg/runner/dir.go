@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"exceltranslator/pkg/config"
+	"exceltranslator/pkg/translator"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PhaseFiles 是目录翻译时报告整体进度的 OnProgress 阶段名，done 和 total 为文件数。
+const PhaseFiles = "files"
+
+// supportedExtensions 是目录翻译时处理的文件扩展名。
+var supportedExtensions = map[string]bool{".xlsx": true, ".docx": true, ".rtf": true, ".pptx": true, ".csv": true, ".tsv": true}
+
+// RunTranslationDir 翻译目录中的所有文件，使用配置文件中的配置。
+func RunTranslationDir(ctx context.Context, inputDir, outputDir string, overwrite bool, cb TranslationCallbacks) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	return RunTranslationDirWithConfig(ctx, inputDir, outputDir, cfg, overwrite, cb)
+}
+
+// RunTranslationDirWithConfig 翻译 inputDir 及其子目录中的 xlsx/docx/pptx/rtf/csv/tsv 文件，
+// 输出到 outputDir 中相同的相对路径。
+// 每个文件的进度通过回调报告，整体进度以 PhaseFiles 阶段报告；单个文件失败时继续翻译其余文件，
+// 最后返回所有失败的合并错误。输出文件已存在时跳过，除非 overwrite 为 true。
+// 启用 processor.resume 时，已完成的文件记录在输出目录的 ResumeFileName 中，中断后再次运行时跳过，
+// 即使 overwrite 为 true；全部完成后删除该文件。
+// OnComplete 只在全部文件处理完成后调用一次，OnStats 也只调用一次，报告所有文件的合计。
+func RunTranslationDirWithConfig(ctx context.Context, inputDir, outputDir string, cfg *config.AppConfig, overwrite bool, cb TranslationCallbacks) error {
+	files, err := collectFiles(inputDir, outputDir)
+	if err != nil {
+		err = fmt.Errorf("failed to list input directory: %w", err)
+		cb.OnError("runner", err)
+		if cb.OnStats != nil {
+			cb.OnStats(Stats{})
+		}
+		cb.OnComplete(err)
+		return err
+	}
+
+	// 续翻模式下未配置 cache_file 时，已完成的文本保存在输出目录中，中断的文件继续时不再重复请求
+	resumeCache := ""
+	if cfg.Processor.Resume && cfg.LLM.CacheFile == "" {
+		resumeCache = filepath.Join(outputDir, ResumeCacheFileName)
+		resumeCfg := *cfg
+		resumeCfg.LLM.CacheFile = resumeCache
+		cfg = &resumeCfg
+	}
+
+	// 所有文件共用一个 Engine 及其译文缓存，相同的文本只翻译一次；缓存大小受 cache_size 限制
+	engine := NewEngine(cfg)
+	fileCb := cb
+	fileCb.OnComplete = func(error) {}
+	// 日志回调在整个目录翻译期间有效，包括文件之间的日志
+	fileCb.OnLog = nil
+	if cb.OnLog != nil {
+		engine.logger.SetOnLog(cb.OnLog)
+		defer engine.logger.SetOnLog(nil)
+	}
+	var resume *resumeState
+	if cfg.Processor.Resume {
+		resume = loadResume(outputDir, engine.logger)
+	}
+	var total Stats
+	start := time.Now()
+	fileCb.OnStats = func(stats Stats) {
+		total.add(stats)
+	}
+
+	var errs []error
+	for i, rel := range files {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+
+		inputFile, outputFile := filepath.Join(inputDir, rel), filepath.Join(outputDir, rel)
+		info, statErr := os.Stat(inputFile)
+		overBudget := false
+		if resume != nil && statErr == nil && resume.done(rel, info) {
+			engine.logger.Infof("Skipping %s, completed by an earlier run", rel)
+		} else if _, err := os.Stat(outputFile); err == nil && !overwrite {
+			engine.logger.Infof("Skipping %s, output already exists", rel)
+		} else if err := engine.Translate(ctx, inputFile, outputFile, fileCb); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rel, err))
+			// 预算用完时输出已保存了翻译完成的部分，其余情况删除不完整的输出，避免下次运行时被当作已完成而跳过
+			overBudget = errors.Is(err, translator.ErrBudgetExceeded)
+			if !overBudget {
+				os.Remove(outputFile)
+			}
+		} else if resume != nil && statErr == nil {
+			if err := resume.markDone(rel, info); err != nil {
+				engine.logger.Warnf("Failed to record progress: %v", err)
+			}
+		}
+
+		if cb.OnProgress != nil {
+			cb.OnProgress(PhaseFiles, i+1, len(files))
+		}
+		// 预算由所有文件共用，用完后不再处理其余文件
+		if overBudget {
+			if rest := len(files) - i - 1; rest > 0 {
+				engine.logger.Warnf("Token budget used up, %d remaining files were not translated", rest)
+			}
+			break
+		}
+	}
+
+	err = errors.Join(errs...)
+	// 全部完成后不再需要进度文件，下次运行重新开始
+	if resume != nil && err == nil {
+		resume.remove()
+		if resumeCache != "" {
+			os.Remove(resumeCache)
+		}
+	}
+	if cb.OnStats != nil {
+		total.Elapsed = time.Since(start)
+		cb.OnStats(total)
+	}
+	cb.OnComplete(err)
+	return err
+}
+
+// collectFiles 返回 inputDir 中待翻译文件的相对路径，跳过 Office 临时文件和位于输入目录内的输出目录。
+func collectFiles(inputDir, outputDir string) ([]string, error) {
+	absOutput, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	// 输出会覆盖正在读取的输入文件
+	if absInput, err := filepath.Abs(inputDir); err == nil && absInput == absOutput {
+		return nil, fmt.Errorf("output directory must differ from input directory")
+	}
+
+	var files []string
+	err = filepath.WalkDir(inputDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if abs, err := filepath.Abs(path); err == nil && abs == absOutput && path != inputDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), "~$") || !supportedExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		rel, err := filepath.Rel(inputDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	return files, err
+}
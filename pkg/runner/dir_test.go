@@ -0,0 +1,40 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"exceltranslator/pkg/textextractor"
+)
+
+func TestDirSharesCache(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+	writeWorkbook(t, filepath.Join(input, "a.xlsx"), "收入", "成本", "利润")
+	// The second file repeats the texts of the first and adds one
+	writeWorkbook(t, filepath.Join(input, "b.xlsx"), "利润", "收入", "成本", "税费")
+
+	server := newFakeLLM(t, 10, 0)
+	cfg := server.config()
+	cfg.LLM.BatchSize = 1
+	var stats Stats
+	cb := testCallbacks(t)
+	cb.OnStats = func(s Stats) { stats = s }
+	if err := RunTranslationDirWithConfig(context.Background(), input, output, cfg, false, cb); err != nil {
+		t.Fatal(err)
+	}
+
+	// One request per distinct text of the folder: the second file only sends its new text
+	if n := server.requests.Load(); n != 4 {
+		t.Errorf("got %d requests, want 4", n)
+	}
+	got := textextractor.SharedStrings(readPart(t, filepath.Join(output, "b.xlsx"), "xl/sharedStrings.xml"))
+	if want := []string{"T 利润", "T 收入", "T 成本", "T 税费"}; !slices.Equal(got, want) {
+		t.Errorf("b.xlsx = %q, want %q", got, want)
+	}
+	if stats.Segments != 7 || stats.CacheHits != 3 || stats.APICalls != 4 {
+		t.Errorf("stats = %+v, want 7 segments, 3 cache hits and 4 calls", stats)
+	}
+}
@@ -0,0 +1,441 @@
+package runner
+
+import (
+	"context"
+	"exceltranslator/pkg/config"
+	"exceltranslator/pkg/fileprocessor"
+	"exceltranslator/pkg/llmservice"
+	"exceltranslator/pkg/logger"
+	"exceltranslator/pkg/textextractor"
+	"exceltranslator/pkg/translator"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// PhaseDocument 是翻译单个文件时报告整体进度的 OnProgress 阶段名，done 和 total 为整个文档的文本项数。
+const PhaseDocument = "document"
+
+// Engine 封装一份配置对应的翻译引擎和文件处理流程，供各前端共用。
+// 同一个 Engine 翻译多个文件时共享 LLM 服务及其译文缓存。
+type Engine struct {
+	cfg    *config.AppConfig
+	logger *logger.Logger
+	llm    translator.TranslationEngine
+}
+
+// newLogger 创建 Engine 的日志记录器，启用 log.file 时同时写入配置目录中的日志文件，
+// 每次运行的日志以脱敏后的配置开头。日志文件不可用时仅输出到标准输出。
+func newLogger(cfg *config.AppConfig) *logger.Logger {
+	if !cfg.Log.File {
+		return logger.NewLogger(100) // Max 100 lines for in-memory log
+	}
+	path, err := config.DefaultLogPath()
+	if err == nil {
+		var logInstance *logger.Logger
+		if logInstance, err = logger.NewLoggerWithFile(100, path, "=== "+time.Now().Format(time.RFC3339)+" ===\n"+cfg.Redacted()); err == nil {
+			return logInstance
+		}
+	}
+	logInstance := logger.NewLogger(100)
+	logInstance.Warnf("Not writing the log file: %v", err)
+	return logInstance
+}
+
+// NewEngine 根据配置创建 Engine。
+func NewEngine(cfg *config.AppConfig) *Engine {
+	// Initialize logger
+	logInstance := newLogger(cfg)
+	logInstance.SetSecrets(cfg.LLM.APIKey)
+
+	// Initialize LLM service
+	engine := newTranslationEngine(cfg, logInstance)
+
+	// 仅翻译 CJK 文本时，目标语言若也是 CJK，原文中的非 CJK 文本都不会被翻译
+	if cfg.Extractor.CJKOnly && textextractor.IsCJKLanguage(cfg.LLM.TargetLang) {
+		logInstance.Warnf("cjk_only is enabled while translating into %s; text without CJK characters is skipped", cfg.LLM.TargetLang)
+	}
+	// 无法识别目标语言的文字时，不会跳过任何文本
+	if cfg.Extractor.SkipSameLanguage && textextractor.LanguageScript(cfg.LLM.TargetLang) == "" {
+		logInstance.Warnf("skip_same_language has no effect: the script of target language %q is not known", cfg.LLM.TargetLang)
+	}
+
+	return &Engine{
+		cfg:    cfg,
+		logger: logInstance,
+		llm:    engine,
+	}
+}
+
+// newTranslationEngine 根据配置创建翻译引擎：LLM 服务或伪翻译。
+func newTranslationEngine(cfg *config.AppConfig, logInstance *logger.Logger) translator.TranslationEngine {
+	llmCfg := llmservice.LLMServiceConfig{
+		BaseURL:       cfg.LLM.BaseURL,
+		APIKey:        cfg.LLM.APIKey,
+		Model:         cfg.LLM.Model,
+		Prompt:        cfg.LLM.Prompt,
+		TargetLang:    cfg.LLM.TargetLang,
+		EmptyResponse: cfg.LLM.EmptyResponse,
+
+		ClientMaxRetries: cfg.LLM.ClientMaxRetries,
+		RetryBackoff:     time.Duration(cfg.LLM.RetryBackoffMs) * time.Millisecond,
+		RateLimitRetries: cfg.LLM.RateLimitRetries,
+		ProtectEmoji:     cfg.LLM.ProtectEmoji,
+		ProtectSymbols:   cfg.LLM.ProtectSymbols,
+		ProtectPhrases:   cfg.LLM.ProtectPhrases,
+		CacheSize:        cfg.LLM.CacheSize,
+		BatchSize:        cfg.LLM.BatchSize,
+		CacheFile:        cfg.LLM.CacheFile,
+		MaxTokens:        cfg.LLM.MaxTokensBudget,
+
+		RequestsPerMinute: cfg.LLM.RequestsPerMinute,
+		TokensPerMinute:   cfg.LLM.TokensPerMinute,
+	}
+	// 自适应并发的上限为翻译器配置的最大并发数
+	if cfg.LLM.AdaptiveConcurrency {
+		llmCfg.AdaptiveConcurrency = max(cfg.Translator.Concurrency, cfg.Translator.PhaseConcurrency.Max(), 1)
+	}
+	// 术语表读取失败时仅记录警告，不影响翻译
+	if cfg.LLM.GlossaryFile != "" {
+		glossary, err := llmservice.LoadGlossary(cfg.LLM.GlossaryFile)
+		if err != nil {
+			logInstance.Warnf("Ignoring glossary: %v", err)
+		} else {
+			logInstance.Debugf("Loaded %d glossary terms from %s", len(glossary), cfg.LLM.GlossaryFile)
+			llmCfg.Glossary = glossary
+		}
+	}
+	switch cfg.LLM.Provider {
+	case llmservice.ProviderPseudo:
+		logInstance.Infof("Using pseudo-localization, no API calls will be made.")
+		return llmservice.NewPseudoService()
+	case llmservice.ProviderOllama:
+		return llmservice.NewOllamaService(llmCfg, logInstance)
+	case llmservice.ProviderAnthropic:
+		return llmservice.NewAnthropicService(llmCfg, logInstance)
+	default:
+		return llmservice.NewLLMService(llmCfg, logInstance)
+	}
+}
+
+// Translate 翻译 inputFile 并写入 outputFile，通过回调报告状态。
+// 返回及回调中的错误都已屏蔽 API 密钥，见 logger.Redact。
+func (e *Engine) Translate(ctx context.Context, inputFile, outputFile string, cb TranslationCallbacks) error {
+	if onError := cb.OnError; onError != nil {
+		cb.OnError = func(stage string, err error) { onError(stage, e.redact(err)) }
+	}
+	if onComplete := cb.OnComplete; onComplete != nil {
+		cb.OnComplete = func(err error) { onComplete(e.redact(err)) }
+	}
+	return e.redact(e.translate(ctx, inputFile, outputFile, cb))
+}
+
+// redact 屏蔽错误信息中的 API 密钥，接口返回的错误可能包含请求的详细信息
+func (e *Engine) redact(err error) error {
+	return logger.RedactError(err, e.cfg.LLM.APIKey)
+}
+
+func (e *Engine) translate(ctx context.Context, inputFile, outputFile string, cb TranslationCallbacks) error {
+	cfg := e.cfg
+	logInstance := e.logger
+	if cb.OnLog != nil {
+		logInstance.SetOnLog(cb.OnLog)
+		defer logInstance.SetOnLog(nil)
+	}
+
+	// 统计信息在 OnComplete 之前报告，失败时也报告
+	stats := newStatsCollector(e.llm)
+	complete := func(err error) {
+		if cb.OnStats != nil {
+			cb.OnStats(stats.finish())
+		}
+		cb.OnComplete(err)
+	}
+
+	// 输出扩展名与输入格式不一致时生成的文件无法打开
+	outputFile, err := checkOutputFormat(inputFile, outputFile, cfg.Processor.FormatMismatch, logInstance)
+	if err != nil {
+		cb.OnError("runner", err)
+		complete(err)
+		return err
+	}
+
+	// Create LocalTranslator with context, engine, and callbacks
+	translatorCallbacks := translator.TranslationCallbacks{
+		OnTranslated: cb.OnTranslated,
+		OnProgress:   cb.OnProgress,
+		OnError:      cb.OnError,
+		OnComplete:   cb.OnComplete,
+		OnFlagged:    cb.OnFlagged,
+
+		Hooks:     cb.Hooks,
+		Ordered:   cb.Ordered,
+		OnSegment: cb.OnSegment,
+	}
+	// Optionally log every segment next to the output for traceability
+	if cfg.Processor.WriteTranslog {
+		logFile, err := createSideFile(TranslogPath(outputFile))
+		if err != nil {
+			logInstance.Errorf("Failed to create translation log: %v", err)
+			cb.OnError("fileprocessor", fmt.Errorf("failed to create translation log: %w", err))
+			complete(err)
+			return err
+		}
+		defer logFile.Close()
+
+		translog := newTranslogWriter(logFile)
+		onSegment := cb.OnSegment
+		translatorCallbacks.OnSegment = func(result translator.SegmentResult) {
+			translog.write(result)
+			if onSegment != nil {
+				onSegment(result)
+			}
+		}
+		defer func() {
+			if translog.err != nil {
+				logInstance.Warnf("Failed to write translation log: %v", translog.err)
+			}
+		}()
+	}
+
+	onSegment := translatorCallbacks.OnSegment
+	translatorCallbacks.OnSegment = func(result translator.SegmentResult) {
+		stats.record(result)
+		if onSegment != nil {
+			onSegment(result)
+		}
+	}
+
+	// Initialize File Processor
+	fp := fileprocessor.NewFileProcessorWithLogger(logInstance)
+	fp.SetExtractorConfig(extractorConfig(cfg))
+	fp.SetOutputMode(cfg.Processor.OutputMode)
+	fp.SetSourceComments(cfg.Processor.SourceComments)
+	fp.SetDeterministic(cfg.Processor.Deterministic)
+	// 宽松模式下跳过的部件通过 OnError 报告，翻译继续进行
+	if cfg.Processor.Lenient {
+		fp.SetLenient(func(err error) { cb.OnError("fileprocessor", err) })
+	}
+	fp.SetBeforeApply(cb.OnBeforeApply)
+	// 工作表名称对照在文件处理成功后写入，供用户编写脚本还原名称
+	var sheetRenames map[string]string
+	if cfg.Processor.WriteSheetMap {
+		fp.SetOnSheetRenames(func(renames map[string]string) { sheetRenames = renames })
+	}
+
+	// 先提取整个文档的文本统计总数，使进度按整个文档单调递增，而不是按每个部件重新计数；
+	// 节流按合并后的整个文档进度进行
+	if cb.OnProgress != nil {
+		translatorCallbacks.OnProgress = documentProgress(fp, inputFile, cb.ProgressThrottle.Wrap(cb.OnProgress), logInstance)
+	}
+	if cb.OnSheetProgress != nil {
+		translatorCallbacks.OnSegment = sheetProgress(fp, inputFile, translatorCallbacks.OnSegment, cb.OnSheetProgress, logInstance)
+	}
+
+	trans := translator.NewTranslator(ctx, e.llm, translatorCallbacks)
+	trans.SetConcurrency(cfg.Translator.Concurrency)
+	trans.SetConcurrencyFunc(func(fileName string) int {
+		return cfg.Translator.PhaseConcurrency.Of(textextractor.Phase(fileName))
+	})
+	trans.SetBatchSize(cfg.LLM.BatchSize)
+	trans.SetWidthMode(cfg.Translator.FullWidth)
+	trans.SetTrailingPunct(cfg.Translator.TrailingPunct)
+	trans.SetLengthLimit(translator.LengthLimit{
+		MaxRatio:  cfg.Translator.MaxLengthRatio,
+		MaxLength: cfg.Translator.MaxLength,
+	})
+	if cfg.Translator.PreviewLimit > 0 {
+		logInstance.Warnf("Preview mode: only the first %d distinct texts are translated", cfg.Translator.PreviewLimit)
+		trans.SetPreviewLimit(cfg.Translator.PreviewLimit)
+	}
+
+	// Optionally write the translated text next to the output for proofreading
+	if cfg.Processor.TextOut {
+		textFile, err := createSideFile(TextOutputPath(outputFile))
+		if err != nil {
+			logInstance.Errorf("Failed to create text output: %v", err)
+			cb.OnError("fileprocessor", fmt.Errorf("failed to create text output: %w", err))
+			complete(err)
+			return err
+		}
+		defer textFile.Close()
+		fp.SetTextOutput(textFile)
+	}
+
+	// Process file using the LocalTranslator
+	processingErr := fp.ProcessFile(ctx, inputFile, outputFile, trans)
+
+	// Save new translations to the persistent cache, also when processing failed part way
+	if service, ok := e.llm.(*llmservice.LLMService); ok {
+		if err := service.Flush(); err != nil {
+			logInstance.Warnf("Failed to save translation cache: %v", err)
+		}
+	}
+
+	if processingErr != nil {
+		logInstance.Errorf("File processing failed: %v", processingErr)
+		cb.OnError("fileprocessor", fmt.Errorf("file processing failed: %w", processingErr))
+		complete(processingErr)
+		return processingErr
+	}
+
+	if cfg.Processor.WriteSheetMap && sheetRenames != nil {
+		if err := writeSheetMap(SheetMapPath(outputFile), sheetRenames); err != nil {
+			logInstance.Warnf("Failed to write sheet name map: %v", err)
+		}
+	}
+
+	// 预算用完时已翻译的部分照常保存，剩余文本保留原文
+	if skipped := trans.BudgetSkipped(); skipped > 0 {
+		err := fmt.Errorf("%w: %d distinct texts were left untranslated in %s", translator.ErrBudgetExceeded, skipped, outputFile)
+		logInstance.Warnf("Token budget used up: %d distinct texts were left untranslated in %s", skipped, outputFile)
+		cb.OnError("translation_engine", err)
+		complete(err)
+		return err
+	}
+	if skipped := trans.PreviewSkipped(); skipped > 0 {
+		logInstance.Warnf("Preview completed: %d distinct texts were left untranslated in %s", skipped, outputFile)
+	}
+	logInstance.Infof("File processing completed successfully.")
+	st := stats.finish()
+	logInstance.Infof("Translated %d segments in %v (%d from cache, %d API calls)", st.Segments, st.Elapsed.Round(time.Millisecond), st.CacheHits, st.APICalls)
+	if st.TokensLeft >= 0 {
+		logInstance.Infof("Used %d tokens, %d of the token budget left", st.Tokens, st.TokensLeft)
+	}
+	complete(nil) // Final progress
+	return nil
+}
+
+// createSideFile 创建输出文件旁的附属文件。附属文件在处理文件之前创建，此时输出目录可能还不存在，
+// 例如翻译目录时的子目录，因此先创建所在目录。
+func createSideFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// documentProgress 返回把各部件的进度合并为整个文档进度的 OnProgress 回调，以 PhaseDocument 阶段报告。
+// 无法预先统计文本数时返回 onProgress 本身，按部件报告进度。
+func documentProgress(fp *fileprocessor.FileProcessor, inputFile string, onProgress func(phase string, done, total int), log *logger.Logger) func(phase string, done, total int) {
+	total, err := fp.CountTexts(inputFile)
+	if err != nil {
+		log.Debugf("Failed to count texts, reporting progress per part: %v", err)
+		return onProgress
+	}
+	if total == 0 {
+		return onProgress
+	}
+
+	var (
+		mu       sync.Mutex
+		done     int
+		partDone = make(map[string]int)
+	)
+	return func(part string, n, _ int) {
+		mu.Lock()
+		defer mu.Unlock()
+		done += n - partDone[part]
+		partDone[part] = n
+		onProgress(PhaseDocument, min(done, total), total)
+	}
+}
+
+// sheetProgress 返回在 onSegment 之外按工作表报告单元格文本进度的 OnSegment 回调。
+// 无法预先统计各工作表的文本数时返回 onSegment 本身，不按工作表报告进度。
+func sheetProgress(fp *fileprocessor.FileProcessor, inputFile string, onSegment func(result translator.SegmentResult), onSheetProgress func(sheet string, done, total int), log *logger.Logger) func(result translator.SegmentResult) {
+	sheets, err := fp.SheetTexts(inputFile)
+	if err != nil {
+		log.Debugf("Failed to count texts by sheet, not reporting progress per sheet: %v", err)
+		return onSegment
+	}
+	if len(sheets.Sheets) == 0 {
+		return onSegment
+	}
+
+	var (
+		mu   sync.Mutex
+		done = make(map[string]int)
+	)
+	return func(result translator.SegmentResult) {
+		if onSegment != nil {
+			onSegment(result)
+		}
+		if result.Err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sheet := sheets.Sheet(result.File, result.Original)
+		if sheet == "" {
+			return
+		}
+		done[sheet]++
+		onSheetProgress(sheet, done[sheet], sheets.Totals[sheet])
+	}
+}
+
+// EventType 表示 Stream 返回的事件类型。
+type EventType int
+
+const (
+	EventTranslated    EventType = iota // 一个文本已翻译：Original、Translated
+	EventProgress                       // 进度更新：Phase、Done、Total
+	EventError                          // 出现错误：Stage、Err
+	EventFlagged                        // 译文超出长度限制：Original、Translated、Reason
+	EventComplete                       // 翻译结束，Err 为 nil 表示成功；之后通道关闭
+	EventSheetProgress                  // 工作表进度更新：Sheet、Done、Total
+)
+
+// Event 是 Stream 返回的翻译事件，字段按 Type 填充。
+type Event struct {
+	Type       EventType
+	Original   string
+	Translated string
+	Phase      string
+	Sheet      string
+	Done       int
+	Total      int
+	Stage      string
+	Reason     string
+	Err        error
+}
+
+// Stream 在后台翻译 inputFile，并以事件通道代替回调报告状态。
+// 通道在翻译结束后关闭，最后一个事件为 EventComplete；调用方应读完通道，
+// 或取消 ctx 放弃剩余事件（此时可能收不到 EventComplete）。
+func (e *Engine) Stream(ctx context.Context, inputFile, outputFile string) <-chan Event {
+	events := make(chan Event, 64)
+	send := func(ev Event) {
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		defer close(events)
+		err := e.Translate(ctx, inputFile, outputFile, TranslationCallbacks{
+			OnTranslated: func(original, translated string) {
+				send(Event{Type: EventTranslated, Original: original, Translated: translated})
+			},
+			OnProgress: func(phase string, done, total int) {
+				send(Event{Type: EventProgress, Phase: phase, Done: done, Total: total})
+			},
+			OnSheetProgress: func(sheet string, done, total int) {
+				send(Event{Type: EventSheetProgress, Sheet: sheet, Done: done, Total: total})
+			},
+			OnError: func(stage string, err error) {
+				send(Event{Type: EventError, Stage: stage, Err: err})
+			},
+			OnFlagged: func(original, translated, reason string) {
+				send(Event{Type: EventFlagged, Original: original, Translated: translated, Reason: reason})
+			},
+			OnComplete: func(error) {},
+		})
+		send(Event{Type: EventComplete, Err: err})
+	}()
+	return events
+}
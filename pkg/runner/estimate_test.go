@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"context"
+	"exceltranslator/pkg/llmservice"
+	"path/filepath"
+	"testing"
+)
+
+func TestEstimateCallsMatchesRequests(t *testing.T) {
+	for _, batchSize := range []int{0, 2} {
+		dir := t.TempDir()
+		input := filepath.Join(dir, "a.xlsx")
+		writeWorkbook(t, input, "收入", "成本", "收入", "Total", "利润")
+
+		server := newFakeLLM(t, 1, 0)
+		cfg := server.config()
+		cfg.Extractor.CJKOnly = true
+		cfg.LLM.BatchSize = batchSize
+
+		estimate, err := EstimateCalls(input, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := RunTranslationWithConfig(context.Background(), input, filepath.Join(dir, "b.xlsx"), cfg, testCallbacks(t)); err != nil {
+			t.Fatal(err)
+		}
+		if n := server.requests.Load(); int64(estimate) != n || n == 0 {
+			t.Errorf("batch size %d: estimated %d calls, got %d requests", batchSize, estimate, n)
+		}
+	}
+}
+
+// writeMultiPartWorkbook writes a workbook with texts in its sheet names, shared strings,
+// a drawing and comments; some texts appear in several parts.
+func writeMultiPartWorkbook(t *testing.T, path string) {
+	const main = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+	writeZip(t, path,
+		[2]string{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		[2]string{"xl/workbook.xml", `<workbook ` + main + ` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="收入" sheetId="1" r:id="rId1"/><sheet name="明细" sheetId="2" r:id="rId2"/></sheets></workbook>`},
+		[2]string{"xl/sharedStrings.xml", `<sst ` + main + `><si><t>收入</t></si><si><t>成本</t></si><si><t>利润</t></si><si><t>合计</t></si><si><t>税费</t></si></sst>`},
+		[2]string{"xl/drawings/drawing1.xml", `<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">` +
+			`<xdr:sp><xdr:txBody><a:p><a:r><a:t>利润趋势</a:t></a:r></a:p><a:p><a:r><a:t>成本</a:t></a:r></a:p></xdr:txBody></xdr:sp></xdr:wsDr>`},
+		[2]string{"xl/comments1.xml", `<comments ` + main + `><authors><author>Li</author></authors><commentList>` +
+			`<comment ref="A1" authorId="0"><text><r><t>请核对</t></r></text></comment><comment ref="B1" authorId="0"><text><r><t>已核对</t></r></text></comment></commentList></comments>`},
+	)
+}
+
+// TestEstimateCallsBatchesEachPart checks that the estimate batches the texts of every part
+// separately, as they are translated, and counts texts of earlier parts as cached.
+func TestEstimateCallsBatchesEachPart(t *testing.T) {
+	for _, batchSize := range []int{0, 2, 3, 10} {
+		dir := t.TempDir()
+		input := filepath.Join(dir, "a.xlsx")
+		writeMultiPartWorkbook(t, input)
+
+		server := newFakeLLM(t, 1, 0)
+		cfg := server.config()
+		cfg.LLM.BatchSize = batchSize
+
+		estimate, err := EstimateCalls(input, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := RunTranslationWithConfig(context.Background(), input, filepath.Join(dir, "b.xlsx"), cfg, testCallbacks(t)); err != nil {
+			t.Fatal(err)
+		}
+		if n := server.requests.Load(); int64(estimate) != n {
+			t.Errorf("batch size %d: estimated %d calls, got %d requests", batchSize, estimate, n)
+		}
+		// One batch for each of the four parts, although all nine texts would fit in one
+		if batchSize == 10 && estimate != 4 {
+			t.Errorf("batch size 10: estimated %d calls, want 4", estimate)
+		}
+	}
+}
+
+// TestEstimateCallsSkipsUntranslatedTexts checks that texts which never reach the model are
+// not estimated: pseudo translations and texts with a translation in the persistent cache.
+// Texts that are glossary terms still reach the model.
+func TestEstimateCallsSkipsUntranslatedTexts(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "a.xlsx")
+	writeWorkbook(t, input, "收入", "成本")
+
+	if estimate, err := EstimateCalls(input, pseudoConfig()); err != nil || estimate != 0 {
+		t.Errorf("pseudo: estimated %d calls (%v), want 0", estimate, err)
+	}
+
+	server := newFakeLLM(t, 1, 0)
+	cfg := server.config()
+	cfg.Extractor.CJKOnly = true
+	cfg.LLM.CacheFile = filepath.Join(dir, "cache.json")
+	cfg.LLM.GlossaryFile = filepath.Join(dir, "glossary.json")
+	if err := llmservice.SaveGlossary(cfg.LLM.GlossaryFile, map[string]string{"收入": "Revenue"}); err != nil {
+		t.Fatal(err)
+	}
+	run := func(input string) (estimate int, requests int64) {
+		t.Helper()
+		before := server.requests.Load()
+		estimate, err := EstimateCalls(input, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := RunTranslationWithConfig(context.Background(), input, filepath.Join(dir, "out.xlsx"), cfg, testCallbacks(t)); err != nil {
+			t.Fatal(err)
+		}
+		return estimate, server.requests.Load() - before
+	}
+	if estimate, n := run(input); estimate != 2 || n != 2 {
+		t.Errorf("empty cache: estimated %d calls, got %d requests, want 2", estimate, n)
+	}
+	// Only the new text is translated the second time
+	second := filepath.Join(dir, "b.xlsx")
+	writeWorkbook(t, second, "收入", "成本", "利润")
+	if estimate, n := run(second); estimate != 1 || n != 1 {
+		t.Errorf("filled cache: estimated %d calls, got %d requests, want 1", estimate, n)
+	}
+}
@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputFormatMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		output  string
+		written string // Output file written, "" if the translation fails
+	}{
+		{"default", "", "out.docx", ""},
+		{"error", FormatMismatchError, "out.docx", ""},
+		{"rename", FormatMismatchRename, "out.docx", "out.xlsx"},
+		{"rename without extension", FormatMismatchRename, "out", "out.xlsx"},
+		{"matching extension", FormatMismatchError, "out.XLSX", "out.XLSX"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.xlsx")
+			writeWorkbook(t, input, "收入")
+			cfg := pseudoConfig()
+			cfg.Processor.FormatMismatch = tt.mode
+
+			var reported error
+			cb := testCallbacks(t)
+			cb.OnError = func(_ string, err error) { reported = err }
+			err := RunTranslationWithConfig(context.Background(), input, filepath.Join(dir, tt.output), cfg, cb)
+
+			entries, _ := os.ReadDir(dir)
+			var files []string
+			for _, entry := range entries {
+				files = append(files, entry.Name())
+			}
+			if tt.written == "" {
+				if !errors.Is(err, ErrFormatMismatch) || !errors.Is(reported, ErrFormatMismatch) {
+					t.Errorf("got error %v, reported %v, want ErrFormatMismatch", err, reported)
+				}
+				if len(files) != 1 {
+					t.Errorf("got files %q, want only the input", files)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 2 {
+				t.Fatalf("got files %q, want the input and %s", files, tt.written)
+			}
+			if sst := readPart(t, filepath.Join(dir, tt.written), "xl/sharedStrings.xml"); !strings.Contains(sst, "!!! 收入") {
+				t.Errorf("got shared strings %s, want them translated", sst)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package runner
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"exceltranslator/pkg/config"
+)
+
+// writeZip writes a zip archive with the given parts, in order, to path.
+func writeZip(t *testing.T, path string, parts ...[2]string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, part := range parts {
+		fw, err := w.Create(part[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(part[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeWorkbook writes a minimal workbook whose only texts are the given shared strings.
+func writeWorkbook(t *testing.T, path string, texts ...string) {
+	t.Helper()
+	sst := `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`
+	for _, text := range texts {
+		sst += "<si><t>" + text + "</t></si>"
+	}
+	sst += "</sst>"
+	writeZip(t, path,
+		[2]string{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		[2]string{"xl/sharedStrings.xml", sst},
+	)
+}
+
+// pseudoConfig returns a configuration translating with the offline pseudo provider.
+func pseudoConfig() *config.AppConfig {
+	cfg := config.DefaultConfig()
+	cfg.LLM.Provider = "pseudo"
+	return cfg
+}
+
+// testCallbacks returns callbacks reporting errors to t.
+func testCallbacks(t *testing.T) TranslationCallbacks {
+	return TranslationCallbacks{
+		OnError:    func(source string, err error) { t.Logf("%s: %v", source, err) },
+		OnComplete: func(error) {},
+	}
+}
+
+// fakeLLM is an OpenAI-compatible chat completions endpoint translating every text into
+// "T " followed by the text.
+type fakeLLM struct {
+	*httptest.Server
+	requests atomic.Int64
+	inFlight atomic.Int64
+	peak     atomic.Int64 // Most requests in flight at once
+
+	// translate, if set before the first request, replaces the translation of unbatched texts
+	translate func(text string) string
+}
+
+// newFakeLLM starts a fake endpoint whose responses use tokens tokens and take delay.
+func newFakeLLM(t *testing.T, tokens int, delay time.Duration) *fakeLLM {
+	t.Helper()
+	s := &fakeLLM{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		n := s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		for peak := s.peak.Load(); n > peak && !s.peak.CompareAndSwap(peak, n); peak = s.peak.Load() {
+		}
+		time.Sleep(delay)
+
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) == 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		// The user message is the prompt and the text, separated by an empty line
+		_, text, _ := strings.Cut(req.Messages[len(req.Messages)-1].Content, "\n\n")
+		reply := "T " + text
+		if strings.HasPrefix(text, "[[1]]") {
+			reply = batchMarkerRegex.ReplaceAllString(text, "${0}T ")
+		} else if s.translate != nil {
+			reply = s.translate(text)
+		}
+		content, _ := json.Marshal(reply)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"1","object":"chat.completion","created":0,"model":"test","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%s}}],"usage":{"prompt_tokens":%d,"completion_tokens":0,"total_tokens":%d}}`, content, tokens, tokens)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// batchMarkerRegex matches the segment markers of batched requests, followed by a line break.
+var batchMarkerRegex = regexp.MustCompile(`\[\[\d+\]\]\n`)
+
+// config returns a configuration translating with the fake endpoint.
+func (s *fakeLLM) config() *config.AppConfig {
+	cfg := config.DefaultConfig()
+	cfg.LLM.BaseURL = s.URL
+	cfg.LLM.Model = "test"
+	cfg.LLM.ClientMaxRetries = -1
+	return cfg
+}
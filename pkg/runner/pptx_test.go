@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"archive/zip"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTranslatePresentation(t *testing.T) {
+	const ns = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"`
+	slide := `<p:sld ` + ns + `><p:cSld><p:spTree>` +
+		`<p:sp><p:nvSpPr><p:cNvPr id="2" name="标题 1"/><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr><p:txBody><a:bodyPr/><a:p><a:r><a:t>年度</a:t></a:r><a:r><a:rPr b="1"/><a:t>总结</a:t></a:r></a:p></p:txBody></p:sp>` +
+		`<p:sp><p:nvSpPr><p:cNvPr id="3" name="内容 2"/><p:nvPr><p:ph idx="1"/></p:nvPr></p:nvSpPr><p:txBody><a:bodyPr/><a:p><a:r><a:t>收入增长</a:t></a:r></a:p><a:p><a:r><a:t>成本下降</a:t></a:r></a:p></p:txBody></p:sp>` +
+		`<p:pic><p:nvPicPr><p:cNvPr id="4" name="图片 3"/></p:nvPicPr><p:blipFill><a:blip r:embed="rId2" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"/></p:blipFill></p:pic>` +
+		`</p:spTree></p:cSld></p:sld>`
+	notes := `<p:notes ` + ns + `><p:cSld><p:spTree><p:sp><p:txBody><a:p><a:r><a:t>演讲备注</a:t></a:r></a:p></p:txBody></p:sp></p:spTree></p:cSld></p:notes>`
+	image := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR 收入 binary"
+	video := strings.Repeat("\x00\x01收入", 100)
+
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "deck.pptx"), filepath.Join(dir, "out.pptx")
+	writeZip(t, input,
+		[2]string{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		[2]string{"ppt/presentation.xml", `<p:presentation ` + ns + `/>`},
+		[2]string{"ppt/slides/slide1.xml", slide},
+		[2]string{"ppt/slides/_rels/slide1.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"/>`},
+		[2]string{"ppt/notesSlides/notesSlide1.xml", notes},
+		[2]string{"ppt/media/image1.png", image},
+		[2]string{"ppt/media/media1.mp4", video},
+	)
+	if err := RunTranslationWithConfig(context.Background(), input, output, pseudoConfig(), testCallbacks(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readPart(t, output, "ppt/slides/slide1.xml")
+	for _, text := range []string{"年度总结", "收入增长", "成本下降"} {
+		if !strings.Contains(got, "!!! "+text) {
+			t.Errorf("slide does not contain the translation of %s:\n%s", text, got)
+		}
+	}
+	// Shape names and the picture are kept
+	for _, kept := range []string{`name="标题 1"`, `<p:ph type="title"/>`, `r:embed="rId2"`} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("slide lost %s:\n%s", kept, got)
+		}
+	}
+	if got := readPart(t, output, "ppt/notesSlides/notesSlide1.xml"); !strings.Contains(got, "!!! 演讲备注") {
+		t.Errorf("notes not translated:\n%s", got)
+	}
+
+	// Media parts are copied verbatim, without being recompressed
+	in, out := zipEntries(t, input), zipEntries(t, output)
+	for _, name := range []string{"ppt/media/image1.png", "ppt/media/media1.mp4"} {
+		if readPart(t, output, name) != readPart(t, input, name) {
+			t.Errorf("%s changed", name)
+		}
+		if in[name].CRC32 != out[name].CRC32 || in[name].CompressedSize64 != out[name].CompressedSize64 || in[name].Method != out[name].Method {
+			t.Errorf("%s was rewritten: %+v, want %+v", name, out[name], in[name])
+		}
+	}
+}
+
+// zipEntries returns the headers of the entries of a zip file by name.
+func zipEntries(t *testing.T, path string) map[string]zip.FileHeader {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	entries := make(map[string]zip.FileHeader)
+	for _, f := range r.File {
+		entries[f.Name] = f.FileHeader
+	}
+	return entries
+}
@@ -0,0 +1,32 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"exceltranslator/pkg/textextractor"
+)
+
+func TestPreviewLimit(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.xlsx"), filepath.Join(dir, "out.xlsx")
+	writeWorkbook(t, input, "收入", "成本", "收入", "利润", "税费", "合计", "成本")
+
+	server := newFakeLLM(t, 10, 0)
+	cfg := server.config()
+	cfg.Translator.PreviewLimit = 3
+	if err := RunTranslationWithConfig(context.Background(), input, output, cfg, testCallbacks(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	// One request for each of the first three distinct texts, wherever they occur
+	if n := server.requests.Load(); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+	got := textextractor.SharedStrings(readPart(t, output, "xl/sharedStrings.xml"))
+	if want := []string{"T 收入", "T 成本", "T 收入", "T 利润", "税费", "合计", "T 成本"}; !slices.Equal(got, want) {
+		t.Errorf("shared strings = %q, want %q", got, want)
+	}
+}
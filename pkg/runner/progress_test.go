@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"exceltranslator/pkg/translator"
+)
+
+func TestProgressThrottleAppliesToWholeDocument(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "a.xlsx")
+	var texts []string
+	for i := 0; i < 200; i++ {
+		texts = append(texts, fmt.Sprintf("文本%d", i))
+	}
+	writeWorkbook(t, input, texts...)
+
+	var calls []int
+	cb := testCallbacks(t)
+	cb.ProgressThrottle = translator.ProgressThrottle{MinStep: 0.25}
+	cb.OnProgress = func(phase string, done, total int) {
+		if phase != PhaseDocument || total != 200 {
+			t.Errorf("got progress %s %d/%d, want %s of 200", phase, done, total, PhaseDocument)
+		}
+		calls = append(calls, done)
+	}
+	if err := RunTranslationWithConfig(context.Background(), input, filepath.Join(dir, "b.xlsx"), pseudoConfig(), cb); err != nil {
+		t.Fatal(err)
+	}
+
+	// At most one call per 25% of the document, the last one at 200
+	if len(calls) == 0 || len(calls) > 4 || calls[len(calls)-1] != 200 {
+		t.Fatalf("got calls %v, want at most 4 ending at 200", calls)
+	}
+	for i := 1; i < len(calls); i++ {
+		if calls[i]-calls[i-1] < 50 {
+			t.Errorf("got calls %v, want steps of at least 50", calls)
+		}
+	}
+}
+
+// writeSheetsWorkbook writes a workbook with the sheets Summary, Data and Notes, whose cells
+// show the shared strings a to e. Summary and Data share b, and a is also the sixth string,
+// shown by Notes only.
+func writeSheetsWorkbook(t *testing.T, path string) {
+	t.Helper()
+	const main = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+	const rel = `Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"`
+	sheet := func(indices ...int) string {
+		cells := ""
+		for i, index := range indices {
+			cells += fmt.Sprintf(`<c r="A%d" t="s"><v>%d</v></c>`, i+1, index)
+		}
+		return `<worksheet ` + main + `><sheetData><row r="1">` + cells + `</row></sheetData></worksheet>`
+	}
+	writeZip(t, path,
+		[2]string{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		[2]string{"xl/workbook.xml", `<workbook ` + main + ` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Summary" sheetId="3" r:id="rId3"/><sheet name="Data" sheetId="1" r:id="rId1"/><sheet name="Notes" sheetId="2" r:id="rId2"/></sheets></workbook>`},
+		[2]string{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" ` + rel + ` Target="worksheets/sheet1.xml"/><Relationship Id="rId2" ` + rel + ` Target="worksheets/sheet2.xml"/><Relationship Id="rId3" ` + rel + ` Target="worksheets/sheet3.xml"/></Relationships>`},
+		[2]string{"xl/worksheets/sheet1.xml", sheet(1, 2, 3)},
+		[2]string{"xl/worksheets/sheet2.xml", sheet(4, 5)},
+		[2]string{"xl/worksheets/sheet3.xml", sheet(0, 1)},
+		[2]string{"xl/sharedStrings.xml", `<sst ` + main + `><si><t>a</t></si><si><t>b</t></si><si><t>c</t></si><si><t>d</t></si><si><t>e</t></si><si><t>a</t></si></sst>`},
+	)
+}
+
+// TestSheetProgress checks that every sheet reports progress up to its own total, and that
+// the totals of the sheets sum to the total of the document, whose only texts are cell texts.
+func TestSheetProgress(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "a.xlsx")
+	writeSheetsWorkbook(t, input)
+
+	var (
+		mu       sync.Mutex
+		done     = make(map[string]int)
+		totals   = make(map[string]int)
+		document int
+	)
+	cb := testCallbacks(t)
+	cb.OnProgress = func(phase string, _, total int) {
+		document = total
+	}
+	cb.OnSheetProgress = func(sheet string, n, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if n != done[sheet]+1 {
+			t.Errorf("got %s %d/%d after %d, want one more", sheet, n, total, done[sheet])
+		}
+		done[sheet], totals[sheet] = n, total
+	}
+	cfg := pseudoConfig()
+	cfg.Extractor.KeepSheetNames = true
+	if err := RunTranslationWithConfig(context.Background(), input, filepath.Join(dir, "b.xlsx"), cfg, cb); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{"Summary": 2, "Data": 2, "Notes": 2}
+	sum := 0
+	for sheet, total := range totals {
+		if done[sheet] != total {
+			t.Errorf("got %s %d/%d, want all done", sheet, done[sheet], total)
+		}
+		sum += total
+	}
+	if fmt.Sprint(totals) != fmt.Sprint(want) {
+		t.Errorf("got totals %v, want %v", totals, want)
+	}
+	if sum != document || document != 6 {
+		t.Errorf("got sheet totals summing to %d and a document total of %d, want 6", sum, document)
+	}
+}
@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"exceltranslator/pkg/logger"
+)
+
+// TestErrorsDoNotShowAPIKey checks that the API key is masked in errors and logs when the API
+// echoes it back.
+func TestErrorsDoNotShowAPIKey(t *testing.T) {
+	const key = "sk-test0123456789abcdef"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"invalid key: `+r.Header.Get("Authorization")+`"}}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "a.xlsx")
+	writeWorkbook(t, input, "收入")
+	cfg := pseudoConfig()
+	cfg.LLM.Provider = ""
+	cfg.LLM.BaseURL = server.URL
+	cfg.LLM.APIKey = key
+	cfg.LLM.Model = "test"
+	cfg.LLM.ClientMaxRetries = -1
+
+	var reported []string
+	cb := testCallbacks(t)
+	cb.OnError = func(_ string, err error) { reported = append(reported, err.Error()) }
+	cb.OnLog = func(_ logger.LogLevel, msg string) { reported = append(reported, msg) }
+	err := RunTranslationWithConfig(context.Background(), input, filepath.Join(dir, "b.xlsx"), cfg, cb)
+	if err == nil {
+		t.Fatal("got no error for a rejected key")
+	}
+	reported = append(reported, err.Error())
+
+	all := strings.Join(reported, "\n")
+	if strings.Contains(all, key) {
+		t.Errorf("got\n%s\nwant the key masked", all)
+	}
+	if !strings.Contains(all, "sk-***") {
+		t.Errorf("got\n%s\nwant the masked key", all)
+	}
+	if errors.Unwrap(err) == nil {
+		t.Errorf("got %v, want the cause kept", err)
+	}
+}
@@ -0,0 +1,16 @@
+package runner
+
+import (
+	"exceltranslator/pkg/fileprocessor"
+	"exceltranslator/pkg/logger"
+)
+
+// RepairSheetNames 修复工作簿中无效、过长或重复的工作表名称并保存到 outputFile，不会发起任何翻译请求。
+// 用于修复早期版本翻译生成的、Excel 无法打开的文件；对重命名工作表的引用会一并更新。
+func RepairSheetNames(inputFile, outputFile string) error {
+	logInstance := logger.NewLogger(100)
+
+	fp := fileprocessor.NewFileProcessorWithLogger(logInstance)
+	_, err := fp.RepairSheetNames(inputFile, outputFile)
+	return err
+}
@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"exceltranslator/pkg/logger"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 续翻模式下写在输出目录中的进度文件，删除后下次运行会重新翻译全部文件
+const (
+	ResumeFileName      = ".translation-progress.json"       // 已完成的文件
+	ResumeCacheFileName = ".translation-progress.cache.json" // 未配置 cache_file 时保存已完成的文本
+)
+
+// resumeEntry 记录已完成文件的原文件大小和修改时间，原文件改动后重新翻译
+type resumeEntry struct {
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// resumeState 是目录翻译的进度文件，每完成一个文件立即写入，以便中断后继续。
+type resumeState struct {
+	path  string
+	Files map[string]resumeEntry `json:"files"` // 按相对路径记录的已完成文件
+}
+
+// loadResume 读取 outputDir 中的进度文件，文件不存在或已损坏时从头开始。
+func loadResume(outputDir string, log *logger.Logger) *resumeState {
+	r := &resumeState{path: filepath.Join(outputDir, ResumeFileName), Files: make(map[string]resumeEntry)}
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warnf("Failed to read progress file %s, starting over: %v", r.path, err)
+		}
+		return r
+	}
+	if err := json.Unmarshal(data, r); err != nil || r.Files == nil {
+		log.Warnf("Ignoring corrupt progress file %s", r.path)
+		r.Files = make(map[string]resumeEntry)
+		return r
+	}
+	log.Infof("Resuming: %d files were completed by an earlier run", len(r.Files))
+	return r
+}
+
+// done 判断文件是否已由之前的运行翻译完成且原文件未改动
+func (r *resumeState) done(rel string, info fs.FileInfo) bool {
+	entry, ok := r.Files[rel]
+	return ok && entry.Size == info.Size() && entry.Modified.Equal(info.ModTime())
+}
+
+// markDone 记录文件已完成并写入进度文件；文件以替换方式写入，中断时不会损坏
+func (r *resumeState) markDone(rel string, info fs.FileInfo) error {
+	r.Files[rel] = resumeEntry{Size: info.Size(), Modified: info.ModTime()}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode progress file: %w", err)
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write progress file: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("failed to replace progress file: %w", err)
+	}
+	return nil
+}
+
+// remove 在全部文件完成后删除进度文件
+func (r *resumeState) remove() {
+	os.Remove(r.path)
+}
@@ -1,7 +1,9 @@
 package runner
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"exceltranslator/pkg/config"
 	"exceltranslator/pkg/fileprocessor"
 	"exceltranslator/pkg/llmservice"
@@ -9,14 +11,57 @@ import (
 	"exceltranslator/pkg/textextractor"
 	"exceltranslator/pkg/translator"
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrFormatMismatch 表示输出文件的扩展名与输入文件的格式不一致。
+var ErrFormatMismatch = errors.New("output extension does not match input format")
+
+// 输出扩展名与输入不一致时的处理方式，见 ProcessorConfig.FormatMismatch。
+const (
+	FormatMismatchError  = "error"  // 返回 ErrFormatMismatch（默认）
+	FormatMismatchRename = "rename" // 将输出扩展名改为输入的扩展名并记录警告
 )
 
 // TranslationCallbacks 定义翻译流程中的回调。
 type TranslationCallbacks struct {
 	OnTranslated func(original, translated string)
-	OnProgress   func(phase string, done, total int)
+	OnProgress   func(phase string, done, total int) // 以 PhaseDocument 阶段报告整个文档的进度
 	OnError      func(stage string, err error)
 	OnComplete   func(err error)
+
+	// OnSheetProgress 在工作簿的单元格文本翻译完成后调用，报告所在工作表的名称及该表已完成数和总数，
+	// 各表总数之和为单元格文本数。多个工作表共用的文本计入第一个工作表，见 fileprocessor.SheetTexts
+	OnSheetProgress func(sheet string, done, total int)
+
+	// OnFlagged 在译文超出配置的长度限制时调用，译文仍会写入输出文件
+	OnFlagged func(original, translated, reason string)
+
+	// ProgressThrottle 合并 OnProgress 回调，减少跨 CGo 或主线程调度的开销，默认不节流。
+	// MinStep 是整个文档文本项数的比例，如 0.01 表示每完成 1% 回调一次
+	ProgressThrottle translator.ProgressThrottle
+
+	// Hooks 在每个文本片段翻译前后调用，用于自定义预处理和后处理
+	Hooks translator.SegmentHooks
+
+	// OnSegment 在每个文本项翻译完成或失败后调用
+	OnSegment func(result translator.SegmentResult)
+
+	// OnStats 在 OnComplete 之前调用，报告翻译的文本项数、缓存命中数、请求数和耗时等统计信息，
+	// 翻译失败时也会调用
+	OnStats func(stats Stats)
+
+	// OnBeforeApply 在译文写回文件前调用，可返回修改后的译文；返回 false 时保留原文
+	// 按文档顺序逐项调用，可用于人工审核
+	OnBeforeApply func(src, dst string) (string, bool)
+
+	// OnLog 在翻译期间每记录一条日志时调用，用于在界面中实时显示日志，见 logger.Logger.SetOnLog
+	OnLog func(level logger.LogLevel, msg string)
+
+	// Ordered 使每个文件内的 OnTranslated 和 OnProgress 按原文顺序回调
+	Ordered bool
 }
 
 // RunTranslation 执行翻译流程，通过回调报告状态。
@@ -32,41 +77,136 @@ func RunTranslation(ctx context.Context, inputFile, outputFile string, cb Transl
 
 // RunTranslationWithConfig 执行翻译流程，使用传入的配置。
 func RunTranslationWithConfig(ctx context.Context, inputFile, outputFile string, cfg *config.AppConfig, cb TranslationCallbacks) error {
-	// Initialize logger
-	logInstance := logger.NewLogger(100) // Max 100 lines for in-memory log
-
-	// Initialize LLM service
-	llmCfg := llmservice.LLMServiceConfig{
-		BaseURL: cfg.LLM.BaseURL,
-		APIKey:  cfg.LLM.APIKey,
-		Model:   cfg.LLM.Model,
-		Prompt:  cfg.LLM.Prompt,
+	return NewEngine(cfg).Translate(ctx, inputFile, outputFile, cb)
+}
+
+// pinger 是可以检查接口配置的翻译引擎
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// TestConnection 使用配置中的 API 地址、密钥和模型发送一个简短的翻译请求，检查配置是否可用。
+// 错误可用 errors.Is 与 llmservice.ErrAuthentication、ErrInvalidURL、ErrUnknownModel、ErrTimeout 比较。
+func TestConnection(ctx context.Context, cfg *config.AppConfig) error {
+	e := NewEngine(cfg)
+	engine, ok := e.llm.(pinger)
+	if !ok {
+		return nil
+	}
+	return e.redact(engine.Ping(ctx))
+}
+
+// checkOutputFormat 检查输出文件扩展名是否与输入一致，按 mode 返回错误或修正后的输出路径。
+func checkOutputFormat(inputFile, outputFile, mode string, log *logger.Logger) (string, error) {
+	inExt, outExt := filepath.Ext(inputFile), filepath.Ext(outputFile)
+	if strings.EqualFold(inExt, outExt) {
+		return outputFile, nil
 	}
-	llmService := llmservice.NewLLMService(llmCfg, logInstance)
-
-	// Create LocalTranslator with context, engine, and callbacks
-	translatorCallbacks := translator.TranslationCallbacks{
-		OnTranslated: cb.OnTranslated,
-		OnProgress:   cb.OnProgress,
-		OnError:      cb.OnError,
-		OnComplete:   cb.OnComplete,
+
+	if mode == FormatMismatchRename {
+		fixed := strings.TrimSuffix(outputFile, outExt) + inExt
+		log.Warnf("Output %s does not match the %s input, writing %s instead", outputFile, inExt, fixed)
+		return fixed, nil
 	}
-	trans := translator.NewTranslator(ctx, llmService, translatorCallbacks)
+	return "", fmt.Errorf("%w: %s input, %s output", ErrFormatMismatch, inExt, outputFile)
+}
+
+// extractorConfig 根据应用配置创建文本提取配置
+func extractorConfig(cfg *config.AppConfig) textextractor.ExtractorConfig {
+	extractor := textextractor.ExtractorConfig{
+		CJKOnly:           cfg.Extractor.CJKOnly,
+		SkipSameLanguage:  cfg.Extractor.SkipSameLanguage,
+		TargetLang:        cfg.LLM.TargetLang,
+		SkipSheetNames:    cfg.Extractor.KeepSheetNames,
+		PreserveSheetTag:  cfg.Extractor.PreserveSheetTag,
+		RTL:               cfg.Extractor.RTL || textextractor.IsRTLLanguage(cfg.LLM.TargetLang),
+		KeepRuns:          cfg.Extractor.KeepRuns,
+		DefinedNames:      cfg.Extractor.DefinedNames,
+		AutoFilters:       cfg.Extractor.AutoFilters,
+		HyperlinkTooltips: cfg.Extractor.HyperlinkTooltips,
+		FormulaResults:    cfg.Extractor.FormulaResults,
+		AltText:           cfg.Extractor.AltText,
+		FormControls:      cfg.Extractor.FormControls,
+		CellStyle: textextractor.StyleFilter{
+			FontColor: cfg.Extractor.CellStyle.FontColor,
+			FontName:  cfg.Extractor.CellStyle.FontName,
+			Bold:      cfg.Extractor.CellStyle.Bold,
+			Italic:    cfg.Extractor.CellStyle.Italic,
+		},
+		Ranges:     cfg.Extractor.Ranges,
+		DocxParts:  cfg.Extractor.DocxParts,
+		CSVColumns: cfg.Extractor.CSVColumns,
+	}
+	return textextractor.ApplyScope(extractor, cfg.Extractor.Scope)
+}
+
+// TextOutputPath 返回译文纯文本输出的路径，与输出文件同名，扩展名为 .txt。
+func TextOutputPath(outputFile string) string {
+	return strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + ".txt"
+}
+
+// EstimateCalls 估算翻译输入文件需要调用 LLM 的次数。
+// 仅执行文本提取（包含 CJK 过滤），不会发起任何请求。与翻译时一样，工作表名称先于其他部件翻译，
+// 每个部件的不重复文本按批量大小分别合并；之前的部件已翻译过的文本及持久缓存中已有译文的文本命中缓存，
+// 全部命中的批次不调用 LLM，伪翻译不调用 LLM。重试和批量翻译失败后的逐个请求不计入估算。
+func EstimateCalls(inputFile string, cfg *config.AppConfig) (int, error) {
+	logInstance := logger.NewLogger(100)
 
-	// Initialize File Processor
 	fp := fileprocessor.NewFileProcessorWithLogger(logInstance)
-	fp.SetExtractorConfig(textextractor.ExtractorConfig{CJKOnly: cfg.Extractor.CJKOnly})
-
-	// Process file using the LocalTranslator
-	processingErr := fp.ProcessFile(inputFile, outputFile, trans)
-	if processingErr != nil {
-		logInstance.Errorf("File processing failed: %v", processingErr)
-		cb.OnError("fileprocessor", fmt.Errorf("file processing failed: %w", processingErr))
-		cb.OnComplete(processingErr)
-		return processingErr
+	fp.SetExtractorConfig(extractorConfig(cfg))
+
+	parts, err := fp.ExtractTexts(inputFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to extract texts: %w", err)
+	}
+	if cfg.LLM.Provider == llmservice.ProviderPseudo {
+		return 0, nil
 	}
+	cache, _ := newTranslationEngine(cfg, logInstance).(translator.CachingEngine)
+	slices.SortStableFunc(parts, func(a, b fileprocessor.PartTexts) int {
+		return cmp.Compare(sheetOrder(a.Name), sheetOrder(b.Name))
+	})
 
-	logInstance.Infof("File processing completed successfully.")
-	cb.OnComplete(nil) // Final progress
-	return nil
+	// LLMService 在一次运行中缓存译文，相同文本只会请求一次
+	seen := make(map[string]bool)
+	limit := cfg.Translator.PreviewLimit
+	batchSize := max(cfg.LLM.BatchSize, 1)
+	calls := 0
+	for _, part := range parts {
+		var unique []string
+		inPart := make(map[string]bool)
+		added := 0 // 本部件中首次出现的文本数
+		for _, text := range part.Texts {
+			if inPart[text] {
+				continue
+			}
+			inPart[text] = true
+			if !seen[text] {
+				// 预览模式下只翻译整个文件的前 limit 个不重复文本
+				if limit > 0 && len(seen)+added >= limit {
+					continue
+				}
+				added++
+			}
+			unique = append(unique, text)
+		}
+		for start := 0; start < len(unique); start += batchSize {
+			batch := unique[start:min(start+batchSize, len(unique))]
+			if slices.ContainsFunc(batch, func(text string) bool { return !seen[text] && (cache == nil || !cache.Cached(text)) }) {
+				calls++
+			}
+			for _, text := range batch {
+				seen[text] = true
+			}
+		}
+	}
+	return calls, nil
+}
+
+// sheetOrder 返回部件的翻译顺序：工作表名称为 0，先于其他部件
+func sheetOrder(name string) int {
+	if textextractor.Phase(name) == textextractor.PhaseSheet {
+		return 0
+	}
+	return 1
 }
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"context"
+	"exceltranslator/pkg/config"
+	"exceltranslator/pkg/fileprocessor"
+	"exceltranslator/pkg/logger"
+	"fmt"
+	"os"
+)
+
+// ExportSegments 将输入文件中所有待翻译的文本导出为 JSON 文件，不会发起任何翻译请求。
+// 每个文本带有稳定的 ID（部件名#序号），可用任意外部工具填写 translation 后通过 ImportSegments 写回。
+func ExportSegments(inputFile, jsonFile string, cfg *config.AppConfig) error {
+	logInstance := logger.NewLogger(100)
+
+	fp := fileprocessor.NewFileProcessorWithLogger(logInstance)
+	fp.SetExtractorConfig(extractorConfig(cfg))
+
+	f, err := os.Create(jsonFile)
+	if err != nil {
+		return fmt.Errorf("failed to create segment file: %w", err)
+	}
+	if err := fp.ExportSegments(inputFile, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ImportSegments 将 JSON 文件中的译文写回输入文件并保存到 outputFile。
+// 提取配置须与导出时一致，否则文本 ID 对应不上，返回 fileprocessor.ErrSegmentMismatch。
+func ImportSegments(ctx context.Context, inputFile, jsonFile, outputFile string, cfg *config.AppConfig) error {
+	logInstance := logger.NewLogger(100)
+
+	outputFile, err := checkOutputFormat(inputFile, outputFile, cfg.Processor.FormatMismatch, logInstance)
+	if err != nil {
+		return err
+	}
+
+	fp := fileprocessor.NewFileProcessorWithLogger(logInstance)
+	fp.SetExtractorConfig(extractorConfig(cfg))
+	fp.SetOutputMode(cfg.Processor.OutputMode)
+	fp.SetSourceComments(cfg.Processor.SourceComments)
+	fp.SetDeterministic(cfg.Processor.Deterministic)
+
+	f, err := os.Open(jsonFile)
+	if err != nil {
+		return fmt.Errorf("failed to open segment file: %w", err)
+	}
+	defer f.Close()
+	return fp.ImportSegments(ctx, inputFile, outputFile, f)
+}
@@ -0,0 +1,23 @@
+package runner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SheetMapPath 返回工作表名称对照文件的路径，与输出文件同名，扩展名为 .sheets.json。
+func SheetMapPath(outputFile string) string {
+	return strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + ".sheets.json"
+}
+
+// writeSheetMap 把重命名的工作表写入对照文件：JSON 对象，键为原名称，值为译名，按原名称排序。
+// 没有重命名的工作表时写入空对象。
+func writeSheetMap(path string, renames map[string]string) error {
+	data, err := json.MarshalIndent(renames, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"exceltranslator/pkg/translator"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Stats 汇总一次翻译的统计信息，通过 TranslationCallbacks.OnStats 报告。
+type Stats struct {
+	Segments   int           // 已翻译的文本项数，包括来自缓存的
+	CacheHits  int           // 译文来自缓存、未发起请求的文本项数
+	Failed     int           // 翻译失败的文本项数
+	APICalls   int64         // 向翻译接口发送的请求数，包括重试；翻译引擎不统计请求时为 0
+	Characters int           // 已翻译文本项的原文字符数
+	Elapsed    time.Duration // 翻译耗时
+	Tokens     int64         // 请求消耗的 token 数；翻译引擎不统计用量时为 0
+	TokensLeft int64         // 翻译结束时剩余的 token 预算，未设置预算时为 -1
+}
+
+// add 将 other 累加到 s
+func (s *Stats) add(other Stats) {
+	s.Segments += other.Segments
+	s.CacheHits += other.CacheHits
+	s.Failed += other.Failed
+	s.APICalls += other.APICalls
+	s.Characters += other.Characters
+	s.Elapsed += other.Elapsed
+	s.Tokens += other.Tokens
+	s.TokensLeft = other.TokensLeft // 预算由所有文件共用，取最后一个文件结束时的剩余量
+}
+
+// requestCounter 是统计已发送请求数的翻译引擎
+type requestCounter interface {
+	Requests() int64
+}
+
+// tokenCounter 是统计 token 用量及剩余预算的翻译引擎
+type tokenCounter interface {
+	Tokens() int64
+	TokensLeft() int64
+}
+
+// statsCollector 根据每个文本项的翻译结果统计 Stats，可并发调用。
+type statsCollector struct {
+	mu       sync.Mutex
+	stats    Stats
+	start    time.Time
+	engine   translator.TranslationEngine
+	requests int64 // 开始时引擎已发送的请求数
+	tokens   int64 // 开始时引擎已消耗的 token 数
+}
+
+func newStatsCollector(engine translator.TranslationEngine) *statsCollector {
+	c := &statsCollector{start: time.Now(), engine: engine}
+	if counter, ok := engine.(requestCounter); ok {
+		c.requests = counter.Requests()
+	}
+	if counter, ok := engine.(tokenCounter); ok {
+		c.tokens = counter.Tokens()
+	}
+	return c
+}
+
+// record 统计一个文本项的翻译结果
+func (c *statsCollector) record(result translator.SegmentResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if result.Err != nil {
+		c.stats.Failed++
+		return
+	}
+	c.stats.Segments++
+	c.stats.Characters += utf8.RuneCountInString(result.Original)
+	if result.Cached {
+		c.stats.CacheHits++
+	}
+}
+
+// finish 返回截至目前的统计信息
+func (c *statsCollector) finish() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Elapsed = time.Since(c.start)
+	if counter, ok := c.engine.(requestCounter); ok {
+		stats.APICalls = counter.Requests() - c.requests
+	}
+	stats.TokensLeft = -1
+	if counter, ok := c.engine.(tokenCounter); ok {
+		stats.Tokens = counter.Tokens() - c.tokens
+		stats.TokensLeft = counter.TokensLeft()
+	}
+	return stats
+}
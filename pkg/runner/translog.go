@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"encoding/json"
+	"exceltranslator/pkg/translator"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 翻译日志条目的状态值。
+const (
+	TranslogTranslated = "translated" // 由翻译引擎翻译
+	TranslogCached     = "cached"     // 译文来自缓存，未发起请求
+	TranslogFailed     = "failed"     // 翻译失败
+)
+
+// TranslogPath 返回翻译日志的路径，与输出文件同名，扩展名为 .translog。
+func TranslogPath(outputFile string) string {
+	return strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + ".translog"
+}
+
+// TranslogEntry 是翻译日志（JSON Lines）中的一行，对应一个文本项。
+type TranslogEntry struct {
+	Part        string `json:"part"`
+	Original    string `json:"original"`
+	Translation string `json:"translation,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// translogWriter 将每个文本项的翻译结果写入翻译日志，可并发调用。
+type translogWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error // 第一个写入错误，之后不再写入
+}
+
+func newTranslogWriter(w io.Writer) *translogWriter {
+	return &translogWriter{enc: json.NewEncoder(w)}
+}
+
+// write 写入一个文本项的翻译结果
+func (w *translogWriter) write(result translator.SegmentResult) {
+	entry := TranslogEntry{
+		Part:        result.File,
+		Original:    result.Original,
+		Translation: result.Translated,
+		Status:      TranslogTranslated,
+	}
+	switch {
+	case result.Err != nil:
+		entry.Status = TranslogFailed
+		entry.Error = result.Err.Error()
+	case result.Cached:
+		entry.Status = TranslogCached
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = w.enc.Encode(entry)
+	}
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranslogHasOneEntryPerSegment(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in", "sub", "a.xlsx")
+	writeWorkbook(t, input, "收入", "成本", "收入")
+
+	cfg := pseudoConfig()
+	cfg.Processor.WriteTranslog = true
+	// The output folder does not exist yet: the log is created before the output
+	outDir := filepath.Join(dir, "out")
+	if err := RunTranslationDirWithConfig(context.Background(), filepath.Join(dir, "in"), outDir, cfg, false, testCallbacks(t)); err != nil {
+		t.Fatalf("RunTranslationDirWithConfig: %v", err)
+	}
+
+	f, err := os.Open(TranslogPath(filepath.Join(outDir, "sub", "a.xlsx")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []TranslogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry TranslogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(entries), entries)
+	}
+	count := map[string]int{}
+	for _, entry := range entries {
+		if entry.Part != "xl/sharedStrings.xml" || entry.Translation == "" || entry.Status == TranslogFailed {
+			t.Errorf("unexpected entry %+v", entry)
+		}
+		count[entry.Original]++
+	}
+	if count["收入"] != 2 || count["成本"] != 1 {
+		t.Errorf("got originals %v, want 收入 twice and 成本 once", count)
+	}
+}
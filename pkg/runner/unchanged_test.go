@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestUnchangedCellKept checks that a cell whose translation equals its text keeps its XML and
+// style byte for byte, next to a cell that is translated.
+func TestUnchangedCellKept(t *testing.T) {
+	const main = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+	translated := `<si><t>收入</t></si>`
+	unchanged := `<si><r><rPr><b/><sz val="11"/><color rgb="FFFF0000"/><rFont val="宋体"/><family val="3"/><charset val="134"/></rPr><t xml:space="preserve">编号 </t></r>` +
+		`<r><rPr><sz val="11"/><rFont val="宋体"/></rPr><t>A&amp;B&#x000D;</t></r><phoneticPr fontId="1" type="noConversion"/></si>`
+	sheet := `<worksheet ` + main + `><sheetData><row r="1" spans="1:2"><c r="A1" s="1" t="s"><v>0</v></c><c r="B1" s="2" t="s"><v>1</v></c></row></sheetData></worksheet>`
+	styles := `<styleSheet ` + main + `><fonts count="2"><font><sz val="11"/></font><font><b/><sz val="11"/></font></fonts>` +
+		`<cellXfs count="3"><xf fontId="0"/><xf fontId="1" applyFont="1"/><xf fontId="0" applyAlignment="1"><alignment wrapText="1"/></xf></cellXfs></styleSheet>`
+
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.xlsx"), filepath.Join(dir, "out.xlsx")
+	writeZip(t, input,
+		[2]string{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		[2]string{"xl/workbook.xml", `<workbook ` + main + `><sheets><sheet name="Data" sheetId="1"/></sheets></workbook>`},
+		[2]string{"xl/styles.xml", styles},
+		[2]string{"xl/worksheets/sheet1.xml", sheet},
+		[2]string{"xl/sharedStrings.xml", `<sst ` + main + ` count="2" uniqueCount="2">` + translated + unchanged + `</sst>`},
+	)
+
+	server := newFakeLLM(t, 10, 0)
+	server.translate = func(text string) string {
+		if text == "收入" {
+			return "Revenue"
+		}
+		return text
+	}
+	if err := RunTranslationWithConfig(context.Background(), input, output, server.config(), testCallbacks(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	sst := readPart(t, output, "xl/sharedStrings.xml")
+	if !strings.Contains(sst, `<si><t>Revenue</t></si>`) {
+		t.Errorf("the first cell is not translated:\n%s", sst)
+	}
+	if !strings.Contains(sst, unchanged) {
+		t.Errorf("the unchanged cell was rewritten:\n%s\nwant it to contain\n%s", sst, unchanged)
+	}
+	for name, want := range map[string]string{"xl/worksheets/sheet1.xml": sheet, "xl/styles.xml": styles} {
+		if got := readPart(t, output, name); got != want {
+			t.Errorf("%s changed:\n%s\nwant\n%s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package textextractor
+
+import (
+	"regexp"
+	"sort"
+)
+
+// wordDrawingNamespace is the namespace of the elements placing drawings in Word documents.
+const wordDrawingNamespace = "http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"
+
+// altTextAttrRegex matches the alt text (descr) and title attributes of a drawing, capturing the value.
+var altTextAttrRegex = regexp.MustCompile(`\s(?:descr|title)="([^"]*)"`)
+
+// extractAltText finds the alt text and titles of the images and shapes of a Word document in
+// their wp:docPr properties. The name and id attributes are left as they are.
+func (e *Extractor) extractAltText(content string) []ExtractionItem {
+	wp := namespacePrefix(content, "wp:", wordDrawingNamespace)
+	var items []ExtractionItem
+	for _, tag := range elementRegex(wp, `<%docPr\b[^>]*>`).FindAllStringIndex(content, -1) {
+		for _, m := range altTextAttrRegex.FindAllStringSubmatchIndex(content[tag[0]:tag[1]], -1) {
+			for i := range m {
+				m[i] += tag[0]
+			}
+			if item, ok := e.newItem(content, [][]int{m}); ok {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// withAltText adds the alt text items of a Word document to the text items, keeping them in
+// document order as Apply requires.
+func (e *Extractor) withAltText(content string, items []ExtractionItem) []ExtractionItem {
+	alt := e.extractAltText(content)
+	if len(alt) == 0 {
+		return items
+	}
+	items = append(items, alt...)
+	sort.Slice(items, func(i, j int) bool { return items[i].MatchStart < items[j].MatchStart })
+	return items
+}
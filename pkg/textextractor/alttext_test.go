@@ -0,0 +1,31 @@
+package textextractor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTranslateAltText(t *testing.T) {
+	const wp = `xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"`
+	image := func(text, docPr string) string {
+		return `<w:document ` + testWordNamespaces + ` ` + wp + `><w:body><w:p><w:r><w:t>` + text + `</w:t></w:r><w:r><w:drawing><wp:inline>` +
+			docPr + `<wp:cNvGraphicFramePr/></wp:inline></w:drawing></w:r></w:p></w:body></w:document>`
+	}
+	docPr := `<wp:docPr id="1" name="图片 1" descr="公司&amp;总部大楼" title="封面"/>`
+	translations := map[string]string{"年度报告": "Annual report", "公司&总部大楼": `Head office "A" & tower`, "封面": "Cover"}
+
+	texts, got := translatePart(t, NewExtractor(ExtractorConfig{AltText: true}), "word/document.xml", image("年度报告", docPr), func(s string) string { return translations[s] })
+	if want := []string{"年度报告", "公司&总部大楼", "封面"}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	// The alt text and title are translated and escaped; the name and id are kept
+	want := `<wp:docPr id="1" name="图片 1" descr="Head office &#34;A&#34; &amp; tower" title="Cover"/>`
+	if got != image("Annual report", want) {
+		t.Errorf("got\n%s\nwant\n%s", got, image("Annual report", want))
+	}
+
+	texts, _ = translatePart(t, NewExtractor(ExtractorConfig{}), "word/document.xml", image("年度报告", docPr), func(s string) string { return translations[s] })
+	if want := []string{"年度报告"}; !slices.Equal(texts, want) {
+		t.Errorf("without alt text: texts = %q, want %q", texts, want)
+	}
+}
@@ -0,0 +1,79 @@
+package textextractor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// customFilterRegex matches the opening of a customFilter element.
+var customFilterRegex = regexp.MustCompile(`^<(?:\w+:)?customFilter\b`)
+
+// isAutoFilterPart reports whether the internal file of a workbook may hold an autofilter:
+// worksheets and tables.
+func isAutoFilterPart(name string) bool {
+	return strings.HasSuffix(name, ".xml") &&
+		(strings.Contains(name, "xl/worksheets/sheet") || strings.Contains(name, "xl/tables/table"))
+}
+
+// autoFilterRegex returns the regex matching the text values of autofilter criteria, capturing
+// the value: custom filters (e.g. "begins with 東京") and the values selected in the filter list.
+// Operators, number and date criteria (dateGroupItem, top10, dynamicFilter) are other attributes
+// or elements and are not matched.
+func autoFilterRegex(x string) *regexp.Regexp {
+	return elementRegex(x, `<%(?:customFilter|filter)\b[^>]*?\sval="([^"]*)"[^>]*>`)
+}
+
+// splitWildcards moves the wildcards at either end of a custom filter value ("*東京*") into the
+// item's Prefix and Suffix so that only the text between them is translated. A wildcard
+// escaped with "~" is part of the text.
+func splitWildcards(item ExtractionItem) ExtractionItem {
+	text := item.Text
+	start := 0
+	for start < len(text) && (text[start] == '*' || text[start] == '?') {
+		start++
+	}
+	end := len(text)
+	for end > start && (text[end-1] == '*' || text[end-1] == '?') && !escapedAt(text, end-1) {
+		end--
+	}
+	item.Prefix += text[:start]
+	item.Suffix = text[end:] + item.Suffix
+	item.Text = text[start:end]
+	return item
+}
+
+// escapedAt reports whether the character at i is escaped by an odd number of "~" before it.
+func escapedAt(s string, i int) bool {
+	n := 0
+	for j := i - 1; j >= 0 && s[j] == '~'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+var (
+	// wildcardUnescaper turns the escaped wildcards of a custom filter value into literal text.
+	wildcardUnescaper = strings.NewReplacer("~~", "~", "~*", "*", "~?", "?")
+	// wildcardEscaper escapes the characters of a translation that a custom filter would read
+	// as wildcards.
+	wildcardEscaper = strings.NewReplacer("~", "~~", "*", "~*", "?", "~?")
+)
+
+// splitAutoFilterWildcards applies splitWildcards to the values of custom filters, dropping
+// values that are only wildcards. The text between the wildcards is translated unescaped and
+// its translation is escaped again on Apply. Values selected in a filter list are literal.
+func splitAutoFilterWildcards(content string, items []ExtractionItem) []ExtractionItem {
+	kept := items[:0]
+	for _, item := range items {
+		if customFilterRegex.MatchString(content[item.MatchStart:item.TextStart]) {
+			item = splitWildcards(item)
+			item.Text = wildcardUnescaper.Replace(item.Text)
+			item.wildcards = true
+			if !IsValidTextContent(item.Text) {
+				continue
+			}
+		}
+		kept = append(kept, item)
+	}
+	return kept
+}
@@ -0,0 +1,72 @@
+package textextractor
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTranslateAutoFilter(t *testing.T) {
+	sheet := `<worksheet ` + testSheetNamespace + `><sheetData/><autoFilter ref="A1:C10">` +
+		`<filterColumn colId="0"><filters><filter val="东京"/><filter val="未知?"/></filters></filterColumn>` +
+		`<filterColumn colId="1"><customFilters and="1"><customFilter operator="notEqual" val="*大阪*"/><customFilter val="单价~*数量*"/></customFilters></filterColumn>` +
+		`<filterColumn colId="2"><customFilters><customFilter operator="greaterThan" val="100"/></customFilters></filterColumn>` +
+		`</autoFilter></worksheet>`
+	translations := map[string]string{"东京": "Tokyo", "未知?": "Unknown?", "大阪": "Osaka", "单价*数量": "Price*Qty"}
+	texts, got := translatePart(t, NewExtractor(ExtractorConfig{AutoFilters: true}), "xl/worksheets/sheet1.xml", sheet, func(s string) string { return translations[s] })
+	if want := []string{"东京", "未知?", "大阪", "单价*数量"}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	// Wildcards around custom filter values are kept and wildcards within translations are
+	// escaped; values selected in a list are literal, and number criteria are left as they are
+	want := strings.NewReplacer(
+		`val="东京"`, `val="Tokyo"`,
+		`val="未知?"`, `val="Unknown?"`,
+		`val="*大阪*"`, `val="*Osaka*"`,
+		`val="单价~*数量*"`, `val="Price~*Qty*"`,
+	).Replace(sheet)
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSplitWildcards(t *testing.T) {
+	tests := []struct {
+		value, text, prefix, suffix string
+	}{
+		{"东京", "东京", "", ""},
+		{"*东京*", "东京", "*", "*"},
+		{"?东京??", "东京", "?", "??"},
+		{"*东~*京*", "东~*京", "*", "*"},
+		{"东京~*", "东京~*", "", ""},   // The last wildcard is escaped
+		{"东京~~*", "东京~~", "", "*"}, // The escape is escaped
+		{"东京~?", "东京~?", "", ""},
+		{"**", "", "**", ""},
+	}
+	for _, tt := range tests {
+		item := splitWildcards(ExtractionItem{Text: tt.value})
+		if item.Text != tt.text || item.Prefix != tt.prefix || item.Suffix != tt.suffix {
+			t.Errorf("splitWildcards(%q) = %q, %q, %q; want %q, %q, %q", tt.value, item.Prefix, item.Text, item.Suffix, tt.prefix, tt.text, tt.suffix)
+		}
+	}
+}
+
+func TestWildcardEscaping(t *testing.T) {
+	tests := []struct {
+		escaped, text string
+	}{
+		{"东京", "东京"},
+		{"东~*京", "东*京"},
+		{"东京~?", "东京?"},
+		{"东京~~", "东京~"},
+		{"~~~*", "~*"},
+	}
+	for _, tt := range tests {
+		if got := wildcardUnescaper.Replace(tt.escaped); got != tt.text {
+			t.Errorf("unescape %q = %q, want %q", tt.escaped, got, tt.text)
+		}
+		if got := wildcardEscaper.Replace(tt.text); got != tt.escaped {
+			t.Errorf("escape %q = %q, want %q", tt.text, got, tt.escaped)
+		}
+	}
+}
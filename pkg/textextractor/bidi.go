@@ -0,0 +1,98 @@
+package textextractor
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// lrm is the Unicode LEFT-TO-RIGHT MARK.
+const lrm = "\u200e"
+
+// ltrSpanRegex matches a span of left-to-right content (Latin words, numbers) inside RTL text.
+var ltrSpanRegex = regexp.MustCompile(`[\p{Latin}\d](?:[\p{Latin}\d .,:/%+\-_@#&]*[\p{Latin}\d%])?`)
+
+// lateRunProperties matches run properties that must come after <w:rtl/> in the schema order.
+// "%" stands for the element prefix.
+const lateRunProperties = `<%(?:cs|em|lang|eastAsianLayout|specVanish|oMath)\b`
+
+// rtlLanguages are names and codes of languages written right-to-left.
+var rtlLanguages = []string{"arabic", "hebrew", "persian", "farsi", "urdu", "yiddish", "pashto", "ar", "he", "fa", "ur", "yi", "ps"}
+
+// IsRTLLanguage reports whether the language, given as an English name or a language code
+// (e.g. "Arabic", "he", "fa-IR"), is written right-to-left.
+func IsRTLLanguage(lang string) bool {
+	return matchLanguage(lang, rtlLanguages)
+}
+
+// cjkLanguages are names and codes of CJK languages.
+var cjkLanguages = []string{"chinese", "japanese", "korean", "zh", "ja", "ko"}
+
+// IsCJKLanguage reports whether the language, given as an English name or a language code, is a CJK language.
+func IsCJKLanguage(lang string) bool {
+	return matchLanguage(lang, cjkLanguages)
+}
+
+// matchLanguage reports whether lang names one of the languages, ignoring case,
+// qualifiers such as "Simplified" and region subtags such as "-IR".
+func matchLanguage(lang string, languages []string) bool {
+	for _, word := range strings.FieldsFunc(strings.ToLower(lang), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_' || r == '(' || r == ')'
+	}) {
+		if slices.Contains(languages, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsRTL checks if the string contains any right-to-left (Arabic or Hebrew) characters.
+func containsRTL(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana) {
+			return true
+		}
+	}
+	return false
+}
+
+// addBidiMarks surrounds left-to-right spans of RTL text with LRM marks so that numbers
+// and Latin words keep their order when the cell or run is rendered right-to-left.
+func addBidiMarks(text string) string {
+	if !containsRTL(text) {
+		return text
+	}
+	text = strings.ReplaceAll(text, lrm, "")
+	return ltrSpanRegex.ReplaceAllStringFunc(text, func(span string) string {
+		return lrm + span + lrm
+	})
+}
+
+// markRunRTL adds <w:rtl/> to the properties of the last run started in before,
+// which is the run holding the text element that follows it. w is the element prefix.
+func markRunRTL(before string, w string) string {
+	runs := elementRegex(w, `<%r(?:\s[^>]*)?>`).FindAllStringIndex(before, -1)
+	if len(runs) == 0 {
+		return before
+	}
+	runStart := runs[len(runs)-1][1]
+	run := before[runStart:]
+	if strings.Contains(run, "<"+w+"rtl") {
+		return before
+	}
+
+	rtl := "<" + w + "rtl/>"
+	if loc := elementRegex(w, `<%rPr\s*/>`).FindStringIndex(run); loc != nil {
+		run = run[:loc[0]] + "<" + w + "rPr>" + rtl + "</" + w + "rPr>" + run[loc[1]:]
+	} else if end := strings.Index(run, "</"+w+"rPr>"); end >= 0 {
+		// Keep the schema order of run properties
+		if loc := elementRegex(w, lateRunProperties).FindStringIndex(run[:end]); loc != nil {
+			end = loc[0]
+		}
+		run = run[:end] + rtl + run[end:]
+	} else {
+		run = "<" + w + "rPr>" + rtl + "</" + w + "rPr>" + run
+	}
+	return before[:runStart] + run
+}
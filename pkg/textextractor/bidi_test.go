@@ -0,0 +1,83 @@
+package textextractor
+
+import "testing"
+
+func TestIsRTLLanguage(t *testing.T) {
+	for lang, want := range map[string]bool{
+		"Arabic":             true,
+		"he":                 true,
+		"fa-IR":              true,
+		"Urdu (Pakistan)":    true,
+		"English":            false,
+		"Chinese Simplified": false,
+	} {
+		if got := IsRTLLanguage(lang); got != want {
+			t.Errorf("IsRTLLanguage(%q) = %v, want %v", lang, got, want)
+		}
+	}
+}
+
+func TestAddBidiMarks(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"زادت الإيرادات 15% في 2024", "زادت الإيرادات \u200e15%\u200e في \u200e2024\u200e"},
+		{"تقرير Q1 2024 المالي", "تقرير \u200eQ1 2024\u200e المالي"},
+		{"راجع www.example.com/report للتفاصيل", "راجع \u200ewww.example.com/report\u200e للتفاصيل"},
+		// Existing marks are not doubled
+		{"تقرير \u200eQ1\u200e المالي", "تقرير \u200eQ1\u200e المالي"},
+		// Text without RTL characters is left alone
+		{"Revenue 2024", "Revenue 2024"},
+	}
+	for _, tt := range tests {
+		if got := addBidiMarks(tt.text); got != tt.want {
+			t.Errorf("addBidiMarks(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// mixedRTLTranslations translates the fixtures of the RTL tests into Arabic with numbers and
+// Latin words.
+var mixedRTLTranslations = map[string]string{
+	"2024年收入增长15%": "زادت الإيرادات 15% في 2024",
+	"第一季度收入":       "إيرادات Q1",
+	"合计":           "المجموع",
+	"备注":           "ملاحظات",
+	"Total":        "Total",
+}
+
+func TestApplyRTLCell(t *testing.T) {
+	sst := `<sst ` + testSheetNamespace + `><si><t>2024年收入增长15%</t></si><si><t>Total</t></si></sst>`
+	want := `<sst ` + testSheetNamespace + `><si><t>زادت الإيرادات ` + "\u200e15%\u200e" + ` في ` + "\u200e2024\u200e" + `</t></si><si><t>Total</t></si></sst>`
+	translate := func(s string) string { return mixedRTLTranslations[s] }
+
+	_, got := translatePart(t, NewExtractor(ExtractorConfig{RTL: true}), "xl/sharedStrings.xml", sst, translate)
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// Without the option the translation is written as it is
+	_, got = translatePart(t, NewExtractor(ExtractorConfig{}), "xl/sharedStrings.xml", sst, translate)
+	if want := `<sst ` + testSheetNamespace + `><si><t>زادت الإيرادات 15% في 2024</t></si><si><t>Total</t></si></sst>`; got != want {
+		t.Errorf("without RTL got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestApplyRTLWordRuns(t *testing.T) {
+	body := `<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>第一季度收入</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>合计</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:rPr><w:i/><w:lang w:val="zh-CN"/></w:rPr><w:t>备注</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:rPr/><w:t>Total</w:t></w:r></w:p>`
+	// Right-to-left runs get <w:rtl/> before the properties that follow it in the schema;
+	// unchanged runs are left alone
+	want := `<w:p><w:r><w:rPr><w:b/><w:rtl/></w:rPr><w:t>إيرادات ` + "\u200eQ1\u200e" + `</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:rPr><w:rtl/></w:rPr><w:t>المجموع</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:rPr><w:i/><w:rtl/><w:lang w:val="zh-CN"/></w:rPr><w:t>ملاحظات</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:rPr/><w:t>Total</w:t></w:r></w:p>`
+
+	_, got := translatePart(t, NewExtractor(ExtractorConfig{RTL: true}), "word/document.xml", testDocument(body), func(s string) string { return mixedRTLTranslations[s] })
+	if want := testDocument(want); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+package textextractor
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// refAttrRegex matches the reference attribute of a cell, capturing the reference.
+var refAttrRegex = regexp.MustCompile(`(?:^|\s)r="([A-Z]+[0-9]+)"`)
+
+// CellText is the shared string shown in a worksheet cell.
+type CellText struct {
+	Ref   string // Cell reference, e.g. "B3"
+	Index int    // Index of the shared string
+	Style int    // Index of the cell format (s attribute), 0 if none is set
+}
+
+// SharedStrings returns the plain text of every item of a shared strings part, in index order.
+// Formatted runs are joined; phonetic annotations are left out.
+func SharedStrings(content string) []string {
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	content = removePhoneticAnnotations(content)
+	text := elementRegex(x, `(?s)<%t(?:\s[^>]*[^/>])?>(.*?)</%t>`)
+
+	var items []string
+	for _, si := range elementRegex(x, `(?s)<%si\b[^>]*?(?:/>|>(.*?)</%si>)`).FindAllStringSubmatch(content, -1) {
+		var sb strings.Builder
+		for _, t := range text.FindAllStringSubmatch(si[1], -1) {
+			sb.WriteString(html.UnescapeString(t[1]))
+		}
+		items = append(items, sb.String())
+	}
+	return items
+}
+
+// SharedStringCells returns the cells of a worksheet that show a shared string, in document order.
+func SharedStringCells(content string) []CellText {
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	cell := elementRegex(x, `<%c\s([^>]*?\bt="s"[^>]*)>\s*<%v>\s*(\d+)\s*</%v>`)
+
+	var cells []CellText
+	for _, m := range cell.FindAllStringSubmatch(content, -1) {
+		ref := refAttrRegex.FindStringSubmatch(m[1])
+		index, err := strconv.Atoi(m[2])
+		if ref == nil || err != nil {
+			continue
+		}
+		style := 0
+		if s := styleAttrRegex.FindStringSubmatch(m[1]); s != nil {
+			style, _ = strconv.Atoi(s[1])
+		}
+		cells = append(cells, CellText{Ref: ref[1], Index: index, Style: style})
+	}
+	return cells
+}
+
+// uniqueCountAttrRegex matches the uniqueCount attribute of a shared strings table, capturing the count.
+var uniqueCountAttrRegex = regexp.MustCompile(`(\suniqueCount=")(\d+)"`)
+
+// CopySharedStrings appends copies of the items of a shared strings part at the given indices,
+// in order, so that cells can be pointed at a copy (see SetSharedStringIndices) and translated
+// apart from the other cells showing the same string. The copies get the indices following the
+// existing items. The uniqueCount of the table grows by the number of copies; its count, the
+// number of cells referring to the table, does not change as the cells are only repointed.
+// Indices beyond the last item are ignored.
+func CopySharedStrings(content string, indices []int) string {
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	items := elementRegex(x, `(?s)<%si\b[^>]*?(?:/>|>.*?</%si>)`).FindAllString(content, -1)
+	end := elementRegex(x, `</%sst>`).FindStringIndex(content)
+	if end == nil {
+		return content
+	}
+
+	var copies strings.Builder
+	added := 0
+	for _, index := range indices {
+		if index < 0 || index >= len(items) {
+			continue
+		}
+		copies.WriteString(items[index])
+		added++
+	}
+	if added == 0 {
+		return content
+	}
+	content = content[:end[0]] + copies.String() + content[end[0]:]
+
+	start := elementRegex(x, `<%sst\b[^>]*>`).FindStringIndex(content)
+	if start == nil {
+		return content
+	}
+	tag := uniqueCountAttrRegex.ReplaceAllStringFunc(content[start[0]:start[1]], func(attr string) string {
+		m := uniqueCountAttrRegex.FindStringSubmatch(attr)
+		count, _ := strconv.Atoi(m[2])
+		return m[1] + strconv.Itoa(count+added) + `"`
+	})
+	return content[:start[0]] + tag + content[start[1]:]
+}
+
+// SetSharedStringIndices points the cells of a worksheet that show a shared string at other
+// items of the shared strings part, given by cell reference. Other cells are left unchanged.
+func SetSharedStringIndices(content string, indices map[string]int) string {
+	if len(indices) == 0 {
+		return content
+	}
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	cell := elementRegex(x, `<%c\s([^>]*?\bt="s"[^>]*)>\s*<%v>\s*(\d+)\s*</%v>`)
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range cell.FindAllStringSubmatchIndex(content, -1) {
+		ref := refAttrRegex.FindStringSubmatch(content[m[2]:m[3]])
+		if ref == nil {
+			continue
+		}
+		index, ok := indices[ref[1]]
+		if !ok {
+			continue
+		}
+		sb.WriteString(content[last:m[4]])
+		sb.WriteString(strconv.Itoa(index))
+		last = m[5]
+	}
+	sb.WriteString(content[last:])
+	return sb.String()
+}
+
+// CellPosition returns the zero-based column and row of a cell reference such as "B3".
+// It reports false if ref is not a cell reference.
+func CellPosition(ref string) (col, row int, ok bool) {
+	i := 0
+	for i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z' {
+		col = col*26 + int(ref[i]-'A'+1)
+		i++
+	}
+	n, err := strconv.Atoi(ref[i:])
+	if i == 0 || err != nil || n < 1 {
+		return 0, 0, false
+	}
+	return col - 1, n - 1, true
+}
+
+// relationshipsNamespace is the namespace of relationship ids (r:id) in document parts.
+const relationshipsNamespace = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+
+// LegacyDrawingID returns the relationship id of the VML drawing of a worksheet, which holds the
+// shapes of its comments, or "" if it has none.
+func LegacyDrawingID(content string) string {
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	r := namespacePrefix(content, "r:", relationshipsNamespace)
+	m := elementRegex(x, `<%legacyDrawing\b[^>]*?\s`+regexp.QuoteMeta(r)+`id="([^"]*)"`).FindStringSubmatch(content)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// AddLegacyDrawing adds a reference to the VML drawing with relationship id rID to a worksheet.
+// The element goes before the elements that follow it in the schema.
+func AddLegacyDrawing(content, rID string) string {
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	element := fmt.Sprintf(`<%slegacyDrawing xmlns:r="%s" r:id="%s"/>`, x, relationshipsNamespace, html.EscapeString(rID))
+	next := elementRegex(x, `<%(?:legacyDrawingHF|drawingHF|picture|oleObjects|controls|webPublishItems|tableParts|extLst)\b|</%worksheet>`)
+	loc := next.FindStringIndex(content)
+	if loc == nil {
+		return content
+	}
+	return content[:loc[0]] + element + content[loc[0]:]
+}
@@ -0,0 +1,49 @@
+package textextractor
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSheetNamespace = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+
+func TestCopySharedStrings(t *testing.T) {
+	sst := `<sst ` + testSheetNamespace + ` count="5" uniqueCount="3"><si><t>a</t></si><si><r><rPr><b/></rPr><t>b</t></r></si><si/></sst>`
+	got := CopySharedStrings(sst, []int{1, 0, 7})
+	want := `<sst ` + testSheetNamespace + ` count="5" uniqueCount="5"><si><t>a</t></si><si><r><rPr><b/></rPr><t>b</t></r></si><si/>` +
+		`<si><r><rPr><b/></rPr><t>b</t></r></si><si><t>a</t></si></sst>`
+	if got != want {
+		t.Errorf("CopySharedStrings =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := CopySharedStrings(sst, nil); got != sst {
+		t.Errorf("CopySharedStrings without indices changed the part: %s", got)
+	}
+
+	prefixed := `<x:sst xmlns:x="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><x:si><x:t>a</x:t></x:si></x:sst>`
+	if got := CopySharedStrings(prefixed, []int{0}); strings.Count(got, "<x:si>") != 2 || !strings.HasSuffix(got, "</x:si></x:sst>") {
+		t.Errorf("CopySharedStrings with a prefix = %s", got)
+	}
+}
+
+func TestSetSharedStringIndices(t *testing.T) {
+	sheet := `<worksheet ` + testSheetNamespace + `><sheetData><row r="1">` +
+		`<c r="A1" s="1" t="s"><v>0</v></c><c r="B1" t="s"><v>0</v></c><c r="C1"><v>0</v></c><c r="D1" t="s"><v> 1 </v></c>` +
+		`</row></sheetData></worksheet>`
+	got := SetSharedStringIndices(sheet, map[string]int{"A1": 3, "C1": 4, "D1": 5})
+
+	want := map[string]int{"A1": 3, "B1": 0, "D1": 5}
+	cells := SharedStringCells(got)
+	if len(cells) != len(want) {
+		t.Fatalf("got cells %+v, want %v", cells, want)
+	}
+	for _, cell := range cells {
+		if cell.Index != want[cell.Ref] {
+			t.Errorf("got %s showing %d, want %d", cell.Ref, cell.Index, want[cell.Ref])
+		}
+	}
+	// C1 holds a number, not a shared string
+	if !strings.Contains(got, `<c r="C1"><v>0</v></c>`) {
+		t.Errorf("SetSharedStringIndices changed a number cell: %s", got)
+	}
+}
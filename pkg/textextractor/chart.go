@@ -0,0 +1,57 @@
+package textextractor
+
+import (
+	"sort"
+	"strings"
+)
+
+// Namespace URIs of charts (c:), in both Transitional and Strict conformance.
+const (
+	chartNamespace       = "http://schemas.openxmlformats.org/drawingml/2006/chart"
+	chartStrictNamespace = "http://purl.oclc.org/ooxml/drawingml/chart"
+)
+
+// isChartPart reports whether the internal file is a chart of a workbook.
+func isChartPart(name string) bool {
+	return strings.Contains(name, "xl/charts/chart") && strings.HasSuffix(name, ".xml")
+}
+
+// extractChartValues finds the plain text values of a chart: series names given as literals
+// (<c:tx><c:v>) and the cached text of series names and categories that refer to cells
+// (c:strCache, c:multiLvlStrCache) or are literals (c:strLit). Cached numbers (c:numCache,
+// c:numLit) and their format codes are not text and are left out; the rich text of titles and
+// labels is extracted like that of shapes.
+func (e *Extractor) extractChartValues(content string) []ExtractionItem {
+	c := namespacePrefix(content, "c:", chartNamespace, chartStrictNamespace)
+	var items []ExtractionItem
+	for _, m := range elementRegex(c, `(?s)<%tx>\s*<%v>([^<]*)</%v>`).FindAllStringSubmatchIndex(content, -1) {
+		if item, ok := e.newItem(content, [][]int{m}); ok {
+			items = append(items, item)
+		}
+	}
+
+	value := elementRegex(c, `<%v>([^<]*)</%v>`)
+	for _, cache := range elementRegex(c, `(?s)<%(?:strCache|multiLvlStrCache|strLit)>.*?</%(?:strCache|multiLvlStrCache|strLit)>`).FindAllStringIndex(content, -1) {
+		for _, m := range value.FindAllStringSubmatchIndex(content[cache[0]:cache[1]], -1) {
+			for i := range m {
+				m[i] += cache[0]
+			}
+			if item, ok := e.newItem(content, [][]int{m}); ok {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// withChartValues adds the plain text values of a chart to its rich text items, keeping them in
+// document order as Apply requires.
+func (e *Extractor) withChartValues(content string, items []ExtractionItem) []ExtractionItem {
+	values := e.extractChartValues(content)
+	if len(values) == 0 {
+		return items
+	}
+	items = append(items, values...)
+	sort.Slice(items, func(i, j int) bool { return items[i].MatchStart < items[j].MatchStart })
+	return items
+}
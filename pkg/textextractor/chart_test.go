@@ -0,0 +1,39 @@
+package textextractor
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testChart is a bar chart with a title, a series named by a cell, text categories, numeric
+// values and axis titles, as Excel writes it.
+const testChart = `<c:chartSpace xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><c:chart>` +
+	`<c:title><c:tx><c:rich><a:bodyPr/><a:p><a:r><a:rPr lang="zh-CN" b="1"/><a:t>月度</a:t></a:r><a:r><a:rPr lang="zh-CN"/><a:t>销售额</a:t></a:r></a:p></c:rich></c:tx><c:overlay val="0"/></c:title>` +
+	`<c:plotArea><c:barChart><c:ser><c:idx val="0"/>` +
+	`<c:tx><c:strRef><c:f>Sheet1!$B$1</c:f><c:strCache><c:ptCount val="1"/><c:pt idx="0"><c:v>华东区</c:v></c:pt></c:strCache></c:strRef></c:tx>` +
+	`<c:cat><c:strRef><c:f>Sheet1!$A$2:$A$3</c:f><c:strCache><c:ptCount val="2"/><c:pt idx="0"><c:v>一月</c:v></c:pt><c:pt idx="1"><c:v>二月</c:v></c:pt></c:strCache></c:strRef></c:cat>` +
+	`<c:val><c:numRef><c:f>Sheet1!$B$2:$B$3</c:f><c:numCache><c:formatCode>General</c:formatCode><c:ptCount val="2"/><c:pt idx="0"><c:v>120</c:v></c:pt><c:pt idx="1"><c:v>135</c:v></c:pt></c:numCache></c:numRef></c:val>` +
+	`</c:ser></c:barChart>` +
+	`<c:catAx><c:axId val="1"/><c:title><c:tx><c:rich><a:bodyPr/><a:p><a:r><a:t>月份</a:t></a:r></a:p></c:rich></c:tx></c:title></c:catAx>` +
+	`<c:valAx><c:axId val="2"/><c:title><c:tx><c:rich><a:bodyPr/><a:p><a:r><a:t>金额（万元）</a:t></a:r></a:p></c:rich></c:tx></c:title><c:numFmt formatCode="#,##0" sourceLinked="1"/></c:valAx>` +
+	`</c:plotArea></c:chart></c:chartSpace>`
+
+func TestTranslateChart(t *testing.T) {
+	translations := map[string]string{
+		"月度销售额": "Monthly sales", "华东区": "East", "一月": "Jan", "二月": "Feb", "月份": "Month", "金额（万元）": "Amount (10k)",
+	}
+	texts, got := translatePart(t, NewExtractor(ExtractorConfig{}), "xl/charts/chart1.xml", testChart, func(s string) string { return translations[s] })
+	if want := []string{"月度销售额", "华东区", "一月", "二月", "月份", "金额（万元）"}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	// The title goes to its first run; cell references, numbers and formats are kept
+	want := strings.NewReplacer(
+		`<a:t>月度</a:t>`, `<a:t>Monthly sales</a:t>`, `<a:t>销售额</a:t>`, `<a:t></a:t>`,
+		`<c:v>华东区</c:v>`, `<c:v>East</c:v>`, `<c:v>一月</c:v>`, `<c:v>Jan</c:v>`, `<c:v>二月</c:v>`, `<c:v>Feb</c:v>`,
+		`<a:t>月份</a:t>`, `<a:t>Month</a:t>`, `<a:t>金额（万元）</a:t>`, `<a:t>Amount (10k)</a:t>`,
+	).Replace(testChart)
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package textextractor
+
+import (
+	"encoding/xml"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testComments returns a comments part holding the given comments, written by 张三.
+func testComments(comments string) string {
+	return `<comments ` + testSheetNamespace + `><authors><author>张三</author></authors><commentList>` + comments + `</commentList></comments>`
+}
+
+func TestTranslateComment(t *testing.T) {
+	comment := `<comment ref="B2" authorId="0"><text>` +
+		`<r><rPr><b/><sz val="9"/></rPr><t>张三:</t></r>` +
+		`<r><rPr><sz val="9"/></rPr><t xml:space="preserve">
+请核对收入</t></r></text></comment>`
+	texts, got := translatePart(t, NewExtractor(ExtractorConfig{}), "xl/comments1.xml", testComments(comment), bracket)
+	if want := []string{"请核对收入"}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	// The author and the bold author run are kept; the line break after it stays before the body
+	want := `<comment ref="B2" authorId="0"><text>` +
+		`<r><rPr><b/><sz val="9"/></rPr><t>张三:</t></r>` +
+		`<r><rPr><sz val="9"/></rPr><t xml:space="preserve">
+[请核对收入]</t></r></text></comment>`
+	if got != testComments(want) {
+		t.Errorf("got\n%s\nwant\n%s", got, testComments(want))
+	}
+
+	// Without the author run, e.g. when it was deleted, the whole body is translated
+	texts, _ = translatePart(t, NewExtractor(ExtractorConfig{}), "xl/comments1.xml", testComments(`<comment ref="A1" authorId="0"><text><t>张三</t></text></comment>`), bracket)
+	if want := []string{"张三"}; !slices.Equal(texts, want) {
+		t.Errorf("plain comment: texts = %q, want %q", texts, want)
+	}
+
+	if NewExtractor(ExtractorConfig{SkipComments: true}).Supports("xl/comments1.xml") {
+		t.Error("comments are translated with SkipComments")
+	}
+}
+
+func TestCoalesceCommentRuns(t *testing.T) {
+	comments := `<comment ref="C3" authorId="0"><text>` +
+		`<r><rPr><b/><sz val="9"/></rPr><t>张三:</t></r>` +
+		`<r><rPr><sz val="9"/></rPr><t xml:space="preserve">
+本月</t></r>` +
+		`<r><rPr><b/><color rgb="FFFF0000"/><sz val="9"/></rPr><t>收入</t></r>` +
+		`<r><rPr><sz val="9"/></rPr><t>需要复核</t></r></text></comment>` +
+		`<comment ref="D4" authorId="0"><text><r><rPr><sz val="9"/></rPr><t>已</t></r><r><rPr><i/><sz val="9"/></rPr><t>确认</t></r></text></comment>`
+	texts, got := translatePart(t, NewExtractor(ExtractorConfig{}), "xl/comments1.xml", testComments(comments), bracket)
+	// Each comment body is one sentence; runs of different comments are not joined
+	if want := []string{"本月收入需要复核", "已确认"}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	want := `<comment ref="C3" authorId="0"><text>` +
+		`<r><rPr><b/><sz val="9"/></rPr><t>张三:</t></r>` +
+		`<r><rPr><sz val="9"/></rPr><t xml:space="preserve">
+[本月收入需要复核]</t></r>` +
+		`<r><rPr><b/><color rgb="FFFF0000"/><sz val="9"/></rPr><t></t></r>` +
+		`<r><rPr><sz val="9"/></rPr><t></t></r></text></comment>` +
+		`<comment ref="D4" authorId="0"><text><r><rPr><sz val="9"/></rPr><t>[已确认]</t></r><r><rPr><i/><sz val="9"/></rPr><t></t></r></text></comment>`
+	if got != testComments(want) {
+		t.Errorf("got\n%s\nwant\n%s", got, testComments(want))
+	}
+
+	// The text of each translated comment reads as its author followed by the translation
+	var parsed struct {
+		Comments []struct {
+			Runs []string `xml:"text>r>t"`
+		} `xml:"commentList>comment"`
+	}
+	if err := xml.Unmarshal([]byte(got), &parsed); err != nil {
+		t.Fatalf("translated part is not well-formed: %v", err)
+	}
+	var bodies []string
+	for _, c := range parsed.Comments {
+		bodies = append(bodies, strings.Join(c.Runs, ""))
+	}
+	if want := []string{"张三:\n[本月收入需要复核]", "[已确认]"}; !slices.Equal(bodies, want) {
+		t.Errorf("comment texts = %q, want %q", bodies, want)
+	}
+}
@@ -0,0 +1,150 @@
+package textextractor
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// utf8BOM is the byte order mark Excel writes at the start of UTF-8 CSV files.
+const utf8BOM = "\ufeff"
+
+// IsCSV reports whether the file name is a CSV or TSV table, which is processed
+// as a single file instead of a zip archive.
+func IsCSV(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".csv" || ext == ".tsv"
+}
+
+// IsTextFile reports whether the file name is a document that is a single text file (RTF, CSV or TSV)
+// rather than a zip archive.
+func IsTextFile(name string) bool {
+	return IsRTF(name) || IsCSV(name)
+}
+
+// csvDelimiter returns the field delimiter of a CSV or TSV file.
+func csvDelimiter(name string) byte {
+	if strings.EqualFold(filepath.Ext(name), ".tsv") {
+		return '\t'
+	}
+	return ','
+}
+
+// csvField is the position of one field of a CSV table.
+type csvField struct {
+	row, col   int
+	start, end int    // Span of the field in the content, including quotes
+	value      string // Unquoted value
+}
+
+// parseCSV splits CSV content into fields. Quoted fields may contain delimiters, quotes ("")
+// and line breaks. Malformed quotes are accepted as in encoding/csv's LazyQuotes mode.
+func parseCSV(content string, delim byte) []csvField {
+	var fields []csvField
+	row, col := 0, 0
+	i := 0
+	if strings.HasPrefix(content, utf8BOM) {
+		i = len(utf8BOM)
+	}
+	for i < len(content) {
+		start := i
+		var value string
+		if content[i] == '"' {
+			var sb strings.Builder
+			j := i + 1
+			for j < len(content) {
+				if content[j] != '"' {
+					sb.WriteByte(content[j])
+					j++
+					continue
+				}
+				if j+1 < len(content) && content[j+1] == '"' {
+					sb.WriteByte('"')
+					j += 2
+					continue
+				}
+				j++
+				break
+			}
+			// Text after the closing quote belongs to the field
+			for j < len(content) && content[j] != delim && content[j] != '\n' && content[j] != '\r' {
+				sb.WriteByte(content[j])
+				j++
+			}
+			value, i = sb.String(), j
+		} else {
+			j := i
+			for j < len(content) && content[j] != delim && content[j] != '\n' && content[j] != '\r' {
+				j++
+			}
+			value, i = content[i:j], j
+		}
+		fields = append(fields, csvField{row: row, col: col, start: start, end: i, value: value})
+
+		switch {
+		case i >= len(content):
+		case content[i] == delim:
+			col++
+			i++
+		default:
+			// End of the record; a CRLF counts as one line break
+			if content[i] == '\r' && i+1 < len(content) && content[i+1] == '\n' {
+				i++
+			}
+			i++
+			row, col = row+1, 0
+		}
+	}
+	return fields
+}
+
+// extractCSV finds the fields of a CSV or TSV table to translate. If columns are configured,
+// only the fields of columns whose header (first row) or 1-based number is listed are translated.
+func (e *Extractor) extractCSV(content string, delim byte) []ExtractionItem {
+	fields := parseCSV(content, delim)
+
+	var selected map[int]bool
+	if len(e.config.CSVColumns) > 0 {
+		selected = make(map[int]bool)
+		for _, f := range fields {
+			if f.row != 0 {
+				break
+			}
+			for _, column := range e.config.CSVColumns {
+				if strings.TrimSpace(f.value) == column || strconv.Itoa(f.col+1) == column {
+					selected[f.col] = true
+				}
+			}
+		}
+	}
+
+	var items []ExtractionItem
+	for _, f := range fields {
+		if selected != nil && !selected[f.col] {
+			continue
+		}
+		if !IsValidTextContent(f.value) {
+			continue
+		}
+		if e.skipLanguage(f.value) {
+			continue
+		}
+		items = append(items, splitEdgeSpace(ExtractionItem{
+			Text:       f.value,
+			MatchStart: f.start,
+			MatchEnd:   f.end,
+			TextStart:  f.start,
+			TextEnd:    f.end,
+		}))
+	}
+	return items
+}
+
+// encodeCSVField encodes a field value, quoting it if it was quoted before or contains
+// characters that need quotes.
+func encodeCSVField(value string, quoted bool, delim byte) string {
+	if quoted || strings.ContainsAny(value, string(delim)+"\"\r\n") {
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	}
+	return value
+}
@@ -0,0 +1,76 @@
+package textextractor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		delim   byte
+		want    []string // row:col=value of every field
+	}{
+		{"plain", "a,b\nc,d", ',', []string{"0:0=a", "0:1=b", "1:0=c", "1:1=d"}},
+		{"trailing line break", "a,b\n", ',', []string{"0:0=a", "0:1=b"}},
+		{"crlf", "a,b\r\nc", ',', []string{"0:0=a", "0:1=b", "1:0=c"}},
+		{"bom", "\ufeff收入,成本", ',', []string{"0:0=收入", "0:1=成本"}},
+		{"empty fields", ",a,\nb", ',', []string{"0:0=", "0:1=a", "0:2=", "1:0=b"}},
+		{"quoted delimiter", `"a,b",c`, ',', []string{"0:0=a,b", "0:1=c"}},
+		{"escaped quote", `"say ""hi""",c`, ',', []string{`0:0=say "hi"`, "0:1=c"}},
+		{"quoted line break", "\"a\nb\",c\nd", ',', []string{"0:0=a\nb", "0:1=c", "1:0=d"}},
+		{"text after quote", `"a"b,c`, ',', []string{"0:0=ab", "0:1=c"}},
+		{"unterminated quote", `"a,b`, ',', []string{"0:0=a,b"}},
+		{"quote inside field", `a"b,c`, ',', []string{`0:0=a"b`, "0:1=c"}},
+		{"tab", "a\tb,c\nd", '\t', []string{"0:0=a", "0:1=b,c", "1:0=d"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, f := range parseCSV(tt.content, tt.delim) {
+			got = append(got, fmt.Sprintf("%d:%d=%s", f.row, f.col, f.value))
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: parseCSV = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseCSVSpans(t *testing.T) {
+	content := "a,\"b,c\"\r\nd"
+	want := []string{"a", `"b,c"`, "d"}
+	fields := parseCSV(content, ',')
+	if len(fields) != len(want) {
+		t.Fatalf("got %d fields, want %d", len(fields), len(want))
+	}
+	for i, f := range fields {
+		if got := content[f.start:f.end]; got != want[i] {
+			t.Errorf("field %d spans %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestEncodeCSVField(t *testing.T) {
+	tests := []struct {
+		value  string
+		quoted bool
+		delim  byte
+		want   string
+	}{
+		{"Revenue", false, ',', "Revenue"},
+		{"Revenue", true, ',', `"Revenue"`},
+		{"a, b", false, ',', `"a, b"`},
+		{"a, b", false, '\t', "a, b"},
+		{"a\tb", false, '\t', "\"a\tb\""},
+		{`say "hi"`, false, ',', `"say ""hi"""`},
+		{"two\nlines", false, ',', "\"two\nlines\""},
+		{"cr\r", false, ',', "\"cr\r\""},
+		{"", false, ',', ""},
+	}
+	for _, tt := range tests {
+		if got := encodeCSVField(tt.value, tt.quoted, tt.delim); got != tt.want {
+			t.Errorf("encodeCSVField(%q, %v, %q) = %q, want %q", tt.value, tt.quoted, tt.delim, got, tt.want)
+		}
+	}
+}
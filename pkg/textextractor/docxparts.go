@@ -0,0 +1,75 @@
+package textextractor
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// Parts of a docx document that can be selected for translation.
+const (
+	DocxPartDocument  = "document"  // Main document body
+	DocxPartHeaders   = "headers"   // Page headers
+	DocxPartFooters   = "footers"   // Page footers
+	DocxPartFootnotes = "footnotes" // Footnotes
+	DocxPartEndnotes  = "endnotes"  // Endnotes
+	DocxPartComments  = "comments"  // Review comments
+	DocxPartTextboxes = "textboxes" // Text boxes and shapes, within any of the parts above
+)
+
+// DefaultDocxParts are the docx parts translated when none are configured.
+var DefaultDocxParts = []string{DocxPartDocument, DocxPartHeaders, DocxPartFooters, DocxPartTextboxes}
+
+// docxPart returns the kind of docx part stored in the internal file, or "" if it has no translatable text.
+func docxPart(name string) string {
+	if !strings.HasPrefix(name, "word/") || path.Dir(name) != "word" {
+		return ""
+	}
+	base := path.Base(name)
+	switch {
+	case base == "document.xml":
+		return DocxPartDocument
+	case strings.HasPrefix(base, "header") && strings.HasSuffix(base, ".xml"):
+		return DocxPartHeaders
+	case strings.HasPrefix(base, "footer") && strings.HasSuffix(base, ".xml"):
+		return DocxPartFooters
+	case base == "footnotes.xml":
+		return DocxPartFootnotes
+	case base == "endnotes.xml":
+		return DocxPartEndnotes
+	case base == "comments.xml":
+		return DocxPartComments
+	}
+	return ""
+}
+
+// docxPartEnabled reports whether the configured docx parts include part.
+func (e *Extractor) docxPartEnabled(part string) bool {
+	parts := e.config.DocxParts
+	if len(parts) == 0 {
+		parts = DefaultDocxParts
+	}
+	return slices.Contains(parts, part)
+}
+
+// filterTextboxes keeps the items inside text boxes only if text boxes are enabled,
+// and the items outside them only if the part itself is enabled.
+func (e *Extractor) filterTextboxes(content, w, part string, items []ExtractionItem) []ExtractionItem {
+	partEnabled := e.docxPartEnabled(part)
+	textboxesEnabled := e.docxPartEnabled(DocxPartTextboxes)
+	if partEnabled && textboxesEnabled {
+		return items
+	}
+
+	boxes := elementRegex(w, `(?s)<%txbxContent\b.*?</%txbxContent>`).FindAllStringIndex(content, -1)
+	kept := items[:0]
+	for _, item := range items {
+		inBox := slices.ContainsFunc(boxes, func(box []int) bool {
+			return item.MatchStart >= box[0] && item.MatchEnd <= box[1]
+		})
+		if (inBox && textboxesEnabled) || (!inBox && partEnabled) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,83 @@
+package textextractor
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDocxPart(t *testing.T) {
+	for name, want := range map[string]string{
+		"word/document.xml":           DocxPartDocument,
+		"word/header2.xml":            DocxPartHeaders,
+		"word/footer1.xml":            DocxPartFooters,
+		"word/footnotes.xml":          DocxPartFootnotes,
+		"word/endnotes.xml":           DocxPartEndnotes,
+		"word/comments.xml":           DocxPartComments,
+		"word/styles.xml":             "",
+		"word/glossary/document.xml":  "",
+		"word/_rels/header2.xml.rels": "",
+		"xl/sharedStrings.xml":        "",
+	} {
+		if got := docxPart(name); got != want {
+			t.Errorf("docxPart(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// docxPartFiles are the parts of a docx document by their kind, in document order.
+var docxPartFiles = []struct{ name, kind string }{
+	{"word/document.xml", DocxPartDocument},
+	{"word/header1.xml", DocxPartHeaders},
+	{"word/footer1.xml", DocxPartFooters},
+	{"word/footnotes.xml", DocxPartFootnotes},
+	{"word/endnotes.xml", DocxPartEndnotes},
+	{"word/comments.xml", DocxPartComments},
+}
+
+// testDocxPart returns a part holding a paragraph with the text kind and a text box with the
+// text "kind box".
+func testDocxPart(kind string) string {
+	return `<w:root ` + testWordNamespaces + `><w:p><w:r><w:t>` + kind + `</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:pict><v:shape><v:textbox><w:txbxContent><w:p><w:r><w:t>` + kind + ` box</w:t></w:r></w:p></w:txbxContent></v:textbox></v:shape></w:pict></w:r></w:p></w:root>`
+}
+
+func TestDocxParts(t *testing.T) {
+	tests := []struct {
+		parts []string
+		want  []string
+	}{
+		// The default parts include the text boxes of the parts that are not translated
+		{nil, []string{"document", "document box", "headers", "headers box", "footers", "footers box", "footnotes box", "endnotes box", "comments box"}},
+		{[]string{DocxPartDocument}, []string{"document"}},
+		{[]string{DocxPartHeaders}, []string{"headers"}},
+		{[]string{DocxPartFooters}, []string{"footers"}},
+		{[]string{DocxPartFootnotes}, []string{"footnotes"}},
+		{[]string{DocxPartEndnotes}, []string{"endnotes"}},
+		{[]string{DocxPartComments}, []string{"comments"}},
+		// Text boxes are translated within every part, headers included
+		{[]string{DocxPartTextboxes}, []string{"document box", "headers box", "footers box", "footnotes box", "endnotes box", "comments box"}},
+		{[]string{DocxPartHeaders, DocxPartTextboxes}, []string{"document box", "headers", "headers box", "footers box", "footnotes box", "endnotes box", "comments box"}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.parts, ","), func(t *testing.T) {
+			e := NewExtractor(ExtractorConfig{DocxParts: tt.parts})
+			var texts []string
+			for _, f := range docxPartFiles {
+				if !e.Supports(f.name) {
+					continue
+				}
+				_, items, err := e.Extract(testDocxPart(f.kind), f.name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, item := range items {
+					texts = append(texts, item.Text)
+				}
+			}
+			if !slices.Equal(texts, tt.want) {
+				t.Errorf("got texts %q, want %q", texts, tt.want)
+			}
+		})
+	}
+}
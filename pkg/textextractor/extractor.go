@@ -4,13 +4,18 @@ import (
 	"fmt"
 	"html"
 	"regexp"
+	"slices"
 	"strings"
 	"unicode"
 )
 
 var (
-	phoneticRunRegex      = regexp.MustCompile(`(?s)<rPh\b[^>]*?>.*?</rPh>`)
-	phoneticPropertyRegex = regexp.MustCompile(`(?s)<phoneticPr\b[^>]*?/?>`)
+	phoneticRunRegex      = regexp.MustCompile(`(?s)<(\w+:)?rPh\b[^>]*?>.*?</(\w+:)?rPh>`)
+	phoneticPropertyRegex = regexp.MustCompile(`(?s)<(\w+:)?phoneticPr\b[^>]*?/?>`)
+
+	// Bracketed tokens at the start or end of a sheet name, e.g. "[A] 概要" or "概要 (v2)"
+	sheetNamePrefixRegex = regexp.MustCompile(`^\s*(?:\[[^\]]*\]|【[^】]*】|\([^)]*\)|（[^）]*）)\s*`)
+	sheetNameSuffixRegex = regexp.MustCompile(`\s*(?:\[[^\]]*\]|【[^】]*】|\([^)]*\)|（[^）]*）)\s*$`)
 )
 
 // FileType represents the type of file being processed
@@ -19,11 +24,45 @@ type FileType string
 const (
 	FileTypeDocx FileType = "docx"
 	FileTypeXlsx FileType = "xlsx"
+	FileTypeRtf  FileType = "rtf"
+	FileTypePptx FileType = "pptx"
+	FileTypeCsv  FileType = "csv"
 )
 
 // ExtractorConfig holds configuration for the extraction process
 type ExtractorConfig struct {
-	CJKOnly bool // If true, only translate text containing CJK characters
+	CJKOnly           bool // If true, only translate text containing CJK characters
+	SkipSameLanguage  bool // If true, skip text already written in the script of TargetLang, see DetectScript
+	PreserveSheetTag  bool // If true, keep a leading/trailing bracketed token of sheet names untranslated
+	RTL               bool // If true, the target language is right-to-left: add bidi marks and RTL run properties
+	KeepRuns          bool // If true, translate each formatted run of a cell, comment, shape or paragraph separately
+	DefinedNames      bool // If true, translate defined names whose value is a string constant, e.g. ="标题"
+	AutoFilters       bool // If true, translate the text criteria of autofilters in worksheets and tables
+	HyperlinkTooltips bool // If true, translate the tooltips of hyperlinks in worksheets
+	FormulaResults    bool // If true, translate the cached string results of formulas and recalculate on open (best effort)
+	AltText           bool // If true, translate the alt text and titles of images and shapes in Word documents
+	FormControls      bool // If true, translate the captions of form controls (buttons, check boxes) and ActiveX controls in worksheets
+
+	// CellStyle limits the translated cells of workbooks to those whose cell style matches; see StyleFilter
+	CellStyle StyleFilter
+
+	// Ranges limits the translated cells of workbooks to those in the given ranges, sheets,
+	// columns or rows; see ParseRanges. Empty translates all cells
+	Ranges []string
+
+	// Filters of workbook texts, usually set through ApplyScope
+	SkipSheetNames bool // If true, keep sheet names untranslated
+	SkipShapes     bool // If true, keep shapes and text boxes of worksheets (xl/drawings) untranslated
+	SkipComments   bool // If true, keep comments and threaded comments untranslated
+
+	// TargetLang is the language translated into, as an English name or a language code; used by SkipSameLanguage
+	TargetLang string
+
+	// DocxParts selects the docx parts to translate (see DocxPartDocument etc.); empty uses DefaultDocxParts
+	DocxParts []string
+
+	// CSVColumns selects the CSV/TSV columns to translate by header or 1-based number; empty translates all
+	CSVColumns []string
 }
 
 // Extractor handles text extraction and replacement
@@ -70,50 +109,210 @@ func IsValidTextContent(s string) bool {
 	return isMeaningful
 }
 
+// TextRange represents the position of a text content span within the XML.
+type TextRange struct {
+	Start int
+	End   int
+}
+
 // ExtractionItem represents a text segment to be translated
 type ExtractionItem struct {
-	Text       string // The content to be translated
-	MatchStart int    // Start index of the full XML match
-	MatchEnd   int    // End index of the full XML match
-	TextStart  int    // Start index of the text content within the match
-	TextEnd    int    // End index of the text content within the match
+	Text       string      // The content to be translated
+	MatchStart int         // Start index of the full XML match
+	MatchEnd   int         // End index of the full XML match
+	TextStart  int         // Start index of the text content within the match
+	TextEnd    int         // End index of the text content within the match
+	Merged     []TextRange // Text of further runs coalesced into this item; emptied on Apply
+	Prefix     string      // Untranslated text reattached before the translation
+	Suffix     string      // Untranslated text reattached after the translation
+
+	constant  bool // String constant of a defined name: quotes are doubled in the translation
+	wildcards bool // Custom filter value: wildcards in the translation are escaped with "~"
+}
+
+// Supports reports whether the internal file of a docx/xlsx/pptx document may contain text
+// to translate with the current configuration.
+func (e *Extractor) Supports(name string) bool {
+	if isFormControlPart(name) {
+		return e.config.FormControls && !e.config.SkipShapes
+	}
+	if !strings.HasSuffix(name, ".xml") {
+		return false
+	}
+	if part := docxPart(name); part != "" {
+		return e.docxPartEnabled(part) || e.docxPartEnabled(DocxPartTextboxes)
+	}
+	if isSlidePart(name) {
+		return true
+	}
+	if e.config.AutoFilters && isAutoFilterPart(name) ||
+		(e.config.HyperlinkTooltips || e.config.FormulaResults) && isWorksheetPart(name) {
+		return true
+	}
+	switch {
+	case strings.Contains(name, "xl/drawings/drawing") || isChartPart(name):
+		return !e.config.SkipShapes
+	case strings.Contains(name, "xl/comments") || strings.Contains(name, "xl/threadedComments/"):
+		return !e.config.SkipComments
+	case strings.Contains(name, "xl/workbook.xml"):
+		return !e.config.SkipSheetNames || e.config.DefinedNames || e.config.FormulaResults
+	}
+	return strings.Contains(name, "xl/sharedStrings.xml")
+}
+
+// isSlidePart reports whether the internal file is a slide, notes page or slide layout of a pptx.
+func isSlidePart(name string) bool {
+	if !strings.HasSuffix(name, ".xml") {
+		return false
+	}
+	return strings.HasPrefix(name, "ppt/slides/slide") ||
+		strings.HasPrefix(name, "ppt/notesSlides/notesSlide") ||
+		strings.HasPrefix(name, "ppt/slideLayouts/slideLayout")
+}
+
+// Phases group the internal files of a document by the kind of text they hold.
+const (
+	PhaseCell  = "cell"  // Cell text, comments and worksheet texts such as autofilters (xl/sharedStrings.xml, xl/comments*.xml, xl/threadedComments, worksheets) and CSV fields
+	PhaseSheet = "sheet" // Sheet names (xl/workbook.xml)
+	PhaseShape = "shape" // Shapes and text boxes (xl/drawings/drawing*.xml), charts, form controls and PowerPoint slides
+	PhaseDocx  = "docx"  // Word and RTF documents
+)
+
+// Phase returns the phase of an internal file (or of an RTF file), or "" if it has no text to translate.
+func Phase(name string) string {
+	switch {
+	case IsRTF(name) || docxPart(name) != "":
+		return PhaseDocx
+	case IsCSV(name) || strings.Contains(name, "xl/sharedStrings.xml") || strings.Contains(name, "xl/comments") ||
+		strings.Contains(name, "xl/threadedComments/") || isAutoFilterPart(name):
+		return PhaseCell
+	case strings.Contains(name, "xl/workbook.xml"):
+		return PhaseSheet
+	case strings.Contains(name, "xl/drawings/drawing") || isChartPart(name) || isFormControlPart(name) || isSlidePart(name):
+		return PhaseShape
+	}
+	return ""
 }
 
 // Extract finds text nodes in the content that need translation.
 // It returns the (potentially modified) content and a list of ExtractionItems.
 func (e *Extractor) Extract(content string, xmlType string) (string, []ExtractionItem, error) {
 	var re *regexp.Regexp
+	var split *regexp.Regexp // Optional: boundaries between groups of runs that are coalesced
+	var skip map[string]bool // Optional: texts of runs that are left untouched
 
-	// DOCX - word/document.xml, word/header*.xml, word/footer*.xml
-	if strings.Contains(xmlType, "word/document.xml") || strings.Contains(xmlType, "word/header") || strings.Contains(xmlType, "word/footer") {
+	// Element prefixes differ between documents (e.g. Strict OOXML files written by some tools),
+	// so they are resolved from the namespace declarations. "%" in patterns stands for the prefix.
+
+	// RTF documents are not XML; see extractRTF
+	if IsRTF(xmlType) {
+		return content, e.extractRTF(content), nil
+	}
+	// Neither are CSV/TSV tables; see extractCSV
+	if IsCSV(xmlType) {
+		return content, e.extractCSV(content, csvDelimiter(xmlType)), nil
+	}
+	// Form controls keep their captions in VML, which is only loosely XML; see extractFormControls
+	if isFormControlPart(xmlType) {
+		if !e.config.FormControls || e.config.SkipShapes {
+			return content, nil, nil
+		}
+		return content, e.extractFormControls(content), nil
+	}
+
+	// DOCX - word/document.xml, word/header*.xml, word/footer*.xml, footnotes, endnotes and comments
+	var w string
+	docxKind := docxPart(xmlType)
+	if docxKind != "" {
+		w = namespacePrefix(content, "w:", wordNamespace, wordStrictNamespace)
 		//<w:t xml:space="preserve">Hello there! My name is McKenzie, and I studied abroad at United International College in Zhuhai in the fall semester of 2023. I</w:t>
-		re = regexp.MustCompile(`(?s)<w:t\b[^>]*?>(.*?)</w:t>`)
+		// Self-closing text elements (<w:t/>) hold no text and must not open a match
+		re = elementRegex(w, `(?s)<%t(?:\s[^>]*[^/>])?>(.*?)</%t>`)
+		// Word splits sentences into several runs (formatting, spell checking, revisions), so the
+		// runs of one paragraph are translated together. Tabs and line breaks separate runs too,
+		// as do the bounds of hyperlinks and fields, whose text must stay within them.
+		boundaries := `<%p\b[^>]*?>|</%p>|<%(?:tab|br|cr)\b[^>]*?/?>|<%(?:hyperlink|fldSimple)\b[^>]*>|</%(?:hyperlink|fldSimple)>|<%fldChar\b[^>]*?/?>`
+		// Drawings separate runs too when their alt text is translated, so that the text of the
+		// runs around an image does not span its properties
+		if e.config.AltText {
+			boundaries += `|<%drawing\b[^>]*>`
+		}
+		split = elementRegex(w, boundaries)
 	} else if strings.Contains(xmlType, "xl/sharedStrings.xml") {
-		// Clean up phonetic annotations (furigana/ruby) which should not be translated
-		content = removePhoneticAnnotations(content)
-		// XLSX Shared Strings
-		re = regexp.MustCompile(`(?s)<t>(.*?)</t>`)
-	} else if strings.Contains(xmlType, "xl/drawings/drawing") {
-		// XLSX Drawings (Shapes)
-		re = regexp.MustCompile(`(?s)<a:t>(.*?)</a:t>`)
+		x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+		// XLSX Shared Strings: text elements may carry attributes such as xml:space="preserve";
+		// self-closing ones hold no text. Formatted runs of one string item are translated together.
+		// Phonetic annotations (furigana/ruby) are matched without a text so that they are not
+		// translated; Apply drops them from the strings it changes
+		re = elementRegex(x, `(?s)<%rPh\b[^>]*>.*?</%rPh>|<%t(?:\s[^>]*[^/>])?>(.*?)</%t>`)
+		split = elementRegex(x, `<%si\b[^>]*?>|</%si>`)
+	} else if strings.Contains(xmlType, "xl/drawings/drawing") || isSlidePart(xmlType) {
+		a := namespacePrefix(content, "a:", drawingNamespace, drawingStrictNamespace)
+		// XLSX Drawings (Shapes) and PPTX slides, notes and layouts: runs within one paragraph are translated together.
+		// Paragraph boundaries and line breaks separate runs that belong to different sentences,
+		// and fields such as slide numbers keep their text.
+		re = elementRegex(a, `(?s)<%t>(.*?)</%t>`)
+		split = elementRegex(a, `<%p\b[^>]*?>|</%p>|<%br\b[^>]*?>|<%fld\b[^>]*>|</%fld>`)
+	} else if isChartPart(xmlType) {
+		a := namespacePrefix(content, "a:", drawingNamespace, drawingStrictNamespace)
+		// XLSX Charts: titles, axis titles and data labels are rich text like shapes; the plain
+		// text of series names and categories is added by withChartValues
+		re = elementRegex(a, `(?s)<%t>(.*?)</%t>`)
+		split = elementRegex(a, `<%p\b[^>]*?>|</%p>|<%br\b[^>]*?>`)
 	} else if strings.Contains(xmlType, "xl/comments") {
-		re = regexp.MustCompile(`(?s)<t>(.*?)</t>`)
+		x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+		// XLSX Comments: formatted runs of one comment body are translated together; self-closing
+		// text elements are skipped as in shared strings
+		re = elementRegex(x, `(?s)<%t(?:\s[^>]*[^/>])?>(.*?)</%t>`)
+		split = elementRegex(x, `<%text\b[^>]*?>|</%text>`)
+		// Excel starts each comment with a bold "Author:" run, which is kept as is
+		skip = commentAuthorRuns(content, x)
+	} else if strings.Contains(xmlType, "xl/threadedComments/") {
+		tc := namespacePrefix(content, "", threadedNamespace)
+		// XLSX Threaded comments: the plain text body of each comment and reply.
+		// Authors are kept in xl/persons and are not touched.
+		re = elementRegex(tc, `(?s)<%text>(.*?)</%text>`)
 	} else if strings.Contains(xmlType, "xl/workbook.xml") {
 		// XLSX Workbook - sheet names
-		re = regexp.MustCompile(`<sheet name="([^"]+?)"[^>]*?>`)
+		re = sheetNameRegex(content)
+	} else if isAutoFilterPart(xmlType) && (e.config.AutoFilters || e.config.HyperlinkTooltips || e.config.FormulaResults) {
+		// XLSX Worksheets and tables: autofilter criteria, hyperlink tooltips and formula results; see extractWorksheet
+		return content, e.extractWorksheet(content, xmlType), nil
 	} else {
 		return content, nil, nil // No translation needed
 	}
 
+	// Translating runs separately keeps each translation in its own formatting, at the cost
+	// of sentences that are split across runs being translated in pieces
+	if e.config.KeepRuns {
+		split = nil
+	}
+
 	// Find all matches
 	matches := re.FindAllStringSubmatchIndex(content, -1)
-	if len(matches) == 0 {
+	if len(matches) == 0 && (docxKind == "" || !e.config.AltText) && !isChartPart(xmlType) {
 		return content, nil, nil
 	}
 
 	var items []ExtractionItem
+	var group [][]int
+	var splits [][]int
+	if split != nil {
+		splits = split.FindAllStringIndex(content, -1)
+	}
+
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+		if item, ok := e.newItem(content, group); ok {
+			items = append(items, item)
+		}
+		group = nil
+	}
 
 	// Extract
+	s := 0
 	for _, match := range matches {
 		// match[0], match[1]: indices of the full match (e.g. <w:t>text</w:t>)
 		// match[2], match[3]: indices of the capture group (e.g. text)
@@ -121,35 +320,180 @@ func (e *Extractor) Extract(content string, xmlType string) (string, []Extractio
 		if len(match) < 4 {
 			continue
 		}
+		// Empty text elements are left as they are, so that a translation is not moved into them
+		if match[2] == match[3] {
+			continue
+		}
 
-		originalText := content[match[2]:match[3]]
+		if skip[html.UnescapeString(content[match[2]:match[3]])] {
+			continue
+		}
+		if split == nil {
+			flush()
+		}
+		// Any boundary between the previous run and this one starts a new group
+		for s < len(splits) && splits[s][0] < match[0] {
+			flush()
+			s++
+		}
+		group = append(group, match)
+	}
+	flush()
 
-		// Unescape XML entities before processing
-		unescaped := html.UnescapeString(originalText)
+	if e.config.SkipSheetNames && strings.Contains(xmlType, "xl/workbook.xml") {
+		items = nil // The workbook is only read for defined names
+	}
+	if e.config.PreserveSheetTag && strings.Contains(xmlType, "xl/workbook.xml") {
+		items = splitSheetNameTags(items)
+	}
+	// Defined names follow the sheets in the workbook, so the items stay in document order
+	if e.config.DefinedNames && strings.Contains(xmlType, "xl/workbook.xml") {
+		items = append(items, e.extractDefinedNameConstants(content)...)
+	}
+	if docxKind != "" && e.config.AltText {
+		items = e.withAltText(content, items)
+	}
+	if isChartPart(xmlType) {
+		items = e.withChartValues(content, items)
+	}
+	if docxKind != "" {
+		items = e.filterTextboxes(content, w, docxKind, items)
+	}
+	if isSpaceSensitive(xmlType) {
+		// Edge whitespace (e.g. the line break after a comment's author run) would be lost in
+		// translation; it is kept out of the text and reattached
+		for i := range items {
+			items[i] = splitEdgeSpace(items[i])
+		}
+	}
+	if strings.Contains(xmlType, "xl/threadedComments/") {
+		items = skipMentions(content, namespacePrefix(content, "", threadedNamespace), items)
+	}
 
-		// 1. Filter: Check if text is meaningful (not just numbers/symbols)
-		if !IsValidTextContent(unescaped) {
-			continue
+	return content, items, nil
+}
+
+// commentAuthorRuns returns the "Author:" texts that Excel puts before the body of comments.
+func commentAuthorRuns(content, x string) map[string]bool {
+	runs := make(map[string]bool)
+	for _, m := range elementRegex(x, `(?s)<%author>(.*?)</%author>`).FindAllStringSubmatch(content, -1) {
+		runs[html.UnescapeString(m[1])+":"] = true
+	}
+	return runs
+}
+
+// splitEdgeSpace moves leading and trailing whitespace of the item's text into its Prefix and Suffix.
+func splitEdgeSpace(item ExtractionItem) ExtractionItem {
+	trimmed := strings.TrimLeftFunc(item.Text, unicode.IsSpace)
+	body := strings.TrimRightFunc(trimmed, unicode.IsSpace)
+	item.Prefix += item.Text[:len(item.Text)-len(trimmed)]
+	item.Suffix = trimmed[len(body):] + item.Suffix
+	item.Text = body
+	return item
+}
+
+// skipMentions drops the text of threaded comments that @mention a person. Mentions refer
+// to the text by character offsets, which the translation would invalidate.
+func skipMentions(content, tc string, items []ExtractionItem) []ExtractionItem {
+	comments := elementRegex(tc, `(?s)<%threadedComment\b[^>]*>.*?</%threadedComment>`).FindAllStringIndex(content, -1)
+	mention := elementRegex(tc, `<%mention\b`)
+	kept := items[:0]
+	for _, item := range items {
+		mentioned := slices.ContainsFunc(comments, func(c []int) bool {
+			return item.MatchStart >= c[0] && item.MatchEnd <= c[1] && mention.MatchString(content[c[0]:c[1]])
+		})
+		if !mentioned {
+			kept = append(kept, item)
 		}
+	}
+	return kept
+}
 
-		// 2. Filter: CJK Only check
-		if e.config.CJKOnly && !ContainsCJK(unescaped) {
+// definedNameConstantRegex matches defined names whose whole value is one string constant.
+// The quotes may be written as entities; quotes within the constant are doubled.
+var definedNameConstantRegex = regexp.MustCompile(`<(?:\w+:)?definedName\b[^>]*>\s*=?\s*(?:"|&quot;|&#34;)((?:[^"&<]|&(?:amp|lt|gt|apos|#39);|(?:""|&quot;&quot;|&#34;&#34;))*)(?:"|&quot;|&#34;)\s*</(?:\w+:)?definedName>`)
+
+// extractDefinedNameConstants finds the string constants of defined names in a workbook.
+// Names referring to cells, ranges or formulas are not matched.
+func (e *Extractor) extractDefinedNameConstants(content string) []ExtractionItem {
+	var items []ExtractionItem
+	for _, m := range definedNameConstantRegex.FindAllStringSubmatchIndex(content, -1) {
+		text := strings.ReplaceAll(html.UnescapeString(content[m[2]:m[3]]), `""`, `"`)
+		if !IsValidTextContent(text) || e.skipLanguage(text) {
 			continue
 		}
-
 		items = append(items, ExtractionItem{
-			Text:       unescaped,
-			MatchStart: match[0],
-			MatchEnd:   match[1],
-			TextStart:  match[2],
-			TextEnd:    match[3],
+			Text:       text,
+			MatchStart: m[0],
+			MatchEnd:   m[1],
+			TextStart:  m[2],
+			TextEnd:    m[3],
+			constant:   true,
 		})
 	}
+	return items
+}
 
-	return content, items, nil
+// splitSheetNameTags moves bracketed tokens such as "[A]" at either end of sheet names
+// into the item's Prefix/Suffix so that only the remainder is translated.
+func splitSheetNameTags(items []ExtractionItem) []ExtractionItem {
+	kept := items[:0]
+	for _, item := range items {
+		name := item.Text
+		if loc := sheetNamePrefixRegex.FindStringIndex(name); loc != nil {
+			item.Prefix = name[:loc[1]]
+			name = name[loc[1]:]
+		}
+		if loc := sheetNameSuffixRegex.FindStringIndex(name); loc != nil {
+			item.Suffix = name[loc[0]:]
+			name = name[:loc[0]]
+		}
+		// Nothing left to translate besides the tags
+		if !IsValidTextContent(name) {
+			continue
+		}
+		item.Text = name
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+// newItem builds an ExtractionItem from a group of consecutive run matches.
+// The text of all runs is concatenated; it returns false if the result should not be translated.
+func (e *Extractor) newItem(content string, group [][]int) (ExtractionItem, bool) {
+	var sb strings.Builder
+	for _, match := range group {
+		// Unescape XML entities before processing
+		sb.WriteString(html.UnescapeString(content[match[2]:match[3]]))
+	}
+	unescaped := sb.String()
+
+	// 1. Filter: Check if text is meaningful (not just numbers/symbols)
+	if !IsValidTextContent(unescaped) {
+		return ExtractionItem{}, false
+	}
+
+	// 2. Filter: CJK Only check and text already in the target language
+	if e.skipLanguage(unescaped) {
+		return ExtractionItem{}, false
+	}
+
+	first, last := group[0], group[len(group)-1]
+	item := ExtractionItem{
+		Text:       unescaped,
+		MatchStart: first[0],
+		MatchEnd:   last[1],
+		TextStart:  first[2],
+		TextEnd:    first[3],
+	}
+	for _, match := range group[1:] {
+		item.Merged = append(item.Merged, TextRange{Start: match[2], End: match[3]})
+	}
+	return item, true
 }
 
 // Apply replaces the extracted items with their translations in the content.
+// Items whose translation equals their text are left untouched, byte for byte.
 func (e *Extractor) Apply(content string, xmlType string, items []ExtractionItem, translations []string) (string, error) {
 	if len(items) != len(translations) {
 		return "", fmt.Errorf("items count (%d) and translations count (%d) do not match", len(items), len(translations))
@@ -164,30 +508,126 @@ func (e *Extractor) Apply(content string, xmlType string, items []ExtractionItem
 
 	lastIndex := 0
 
+	// Word runs receiving RTL text are marked as right-to-left
+	isWord := strings.HasPrefix(xmlType, "word/")
+	w := namespacePrefix(content, "w:", wordNamespace, wordStrictNamespace)
+
+	spaceSensitive := isSpaceSensitive(xmlType)
+
+	// Changed shared strings lose their phonetic annotations
+	var phonetic [][]int
+	if strings.Contains(xmlType, "xl/sharedStrings.xml") {
+		phonetic = phoneticAnnotations(content, items, translations)
+	}
+
+	// Translated sheet names must stay valid and unique
+	var sheets *sheetNameFixer
+	if strings.Contains(xmlType, "xl/workbook.xml") {
+		sheets = newSheetNameFixer(content, items, translations)
+	}
+
 	for i, item := range items {
 		translated := translations[i]
+		if translated == item.Text {
+			continue
+		}
+
+		if item.constant {
+			// Quotes within a string constant are doubled
+			translated = strings.ReplaceAll(item.Prefix+translated+item.Suffix, `"`, `""`)
+		} else if item.wildcards {
+			translated = item.Prefix + wildcardEscaper.Replace(translated) + item.Suffix
+		} else if sheets != nil {
+			// For sheet names, Excel has a 31-character limit.
+			// Shorten the translation first so that preserved tags survive the limit
+			tags := len([]rune(item.Prefix + item.Suffix))
+			translated = truncateRunes(translated, maxSheetNameRunes-tags)
+			translated = sheets.fix(item.Prefix + translated + item.Suffix)
+		} else {
+			translated = item.Prefix + translated + item.Suffix
+		}
 
-		// For sheet names, Excel has a 31-character limit
-		if strings.Contains(xmlType, "xl/workbook.xml") {
-			translated = truncateSheetName(translated)
+		before := withoutRanges(content, lastIndex, item.MatchStart, phonetic)
+		if e.config.RTL {
+			translated = addBidiMarks(translated)
+			if isWord && containsRTL(translated) {
+				before = markRunRTL(before, w)
+			}
 		}
 
-		// Escape XML entities after translation
-		escapedTranslated := html.EscapeString(translated)
+		// Escape XML entities (or RTF control characters, CSV quotes) after translation
+		var escapedTranslated string
+		if IsRTF(xmlType) {
+			escapedTranslated = encodeRTF(translated)
+		} else if IsCSV(xmlType) {
+			escapedTranslated = encodeCSVField(translated, content[item.MatchStart] == '"', csvDelimiter(xmlType))
+		} else {
+			escapedTranslated = html.EscapeString(translated)
+		}
+
+		openTag := content[item.MatchStart:item.TextStart]
+		if spaceSensitive && needsPreserveSpace(content, item, translated) {
+			openTag = preserveSpace(openTag)
+		}
 
-		sb.WriteString(content[lastIndex:item.MatchStart])
-		sb.WriteString(content[item.MatchStart:item.TextStart])
+		sb.WriteString(before)
+		sb.WriteString(openTag)
 		sb.WriteString(escapedTranslated)
-		sb.WriteString(content[item.TextEnd:item.MatchEnd])
+		// The translation of coalesced runs lives in the first run; the others are emptied
+		pos := item.TextEnd
+		for _, r := range item.Merged {
+			sb.WriteString(content[pos:r.Start])
+			pos = r.End
+		}
+		sb.WriteString(content[pos:item.MatchEnd])
 		lastIndex = item.MatchEnd
 	}
 
 	// Append remaining content
-	sb.WriteString(content[lastIndex:])
+	sb.WriteString(withoutRanges(content, lastIndex, len(content), phonetic))
 
 	return sb.String(), nil
 }
 
+// isSpaceSensitive reports whether the text elements of a part drop edge whitespace
+// unless xml:space="preserve" is set, as in Word and SpreadsheetML.
+func isSpaceSensitive(xmlType string) bool {
+	return docxPart(xmlType) != "" || strings.Contains(xmlType, "xl/sharedStrings.xml") ||
+		strings.Contains(xmlType, "xl/comments")
+}
+
+var (
+	xmlSpaceAttrRegex = regexp.MustCompile(`\sxml:space="[^"]*"`)
+	xmlSpacePreserve  = `xml:space="preserve"`
+)
+
+// needsPreserveSpace reports whether the text element receiving the translation of item needs
+// xml:space="preserve": the translation has edge or repeated whitespace, or one of the coalesced
+// runs preserved its whitespace.
+func needsPreserveSpace(content string, item ExtractionItem, translated string) bool {
+	trimmed := strings.TrimFunc(translated, unicode.IsSpace)
+	if trimmed != translated || strings.Contains(translated, "  ") || strings.ContainsAny(translated, "\t\n") {
+		return true
+	}
+	for _, r := range item.Merged {
+		// Opening tag of the coalesced run
+		if start := strings.LastIndexByte(content[:r.Start], '<'); start >= 0 &&
+			strings.Contains(content[start:r.Start], xmlSpacePreserve) {
+			return true
+		}
+	}
+	return false
+}
+
+// preserveSpace sets xml:space="preserve" on an opening tag. Self-closing tags are returned unchanged.
+func preserveSpace(tag string) string {
+	if strings.Contains(tag, xmlSpacePreserve) || !strings.HasSuffix(tag, ">") || strings.HasSuffix(tag, "/>") {
+		return tag
+	}
+	tag = xmlSpaceAttrRegex.ReplaceAllString(tag, "")
+	return strings.TrimSuffix(tag, ">") + " " + xmlSpacePreserve + ">"
+}
+
 // removePhoneticAnnotations strips Excel phonetic (ruby) markup that should not be preserved.
 func removePhoneticAnnotations(content string) string {
 	content = phoneticRunRegex.ReplaceAllString(content, "")
@@ -195,12 +635,64 @@ func removePhoneticAnnotations(content string) string {
 	return content
 }
 
+// phoneticAnnotations returns the ranges of the phonetic (ruby) markup of the shared string
+// items holding a changed item. The annotations refer to the characters of the original text
+// and would not match its translation; the other strings keep theirs.
+func phoneticAnnotations(content string, items []ExtractionItem, translations []string) [][]int {
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	var cuts [][]int
+	i := 0
+	for _, si := range elementRegex(x, `(?s)<%si\b[^>]*>.*?</%si>`).FindAllStringIndex(content, -1) {
+		changed := false
+		for ; i < len(items) && items[i].MatchStart < si[1]; i++ {
+			changed = changed || items[i].MatchStart >= si[0] && translations[i] != items[i].Text
+		}
+		if !changed {
+			continue
+		}
+		// The phonetic runs precede the phonetic properties, so the ranges stay sorted
+		ranges := phoneticRunRegex.FindAllStringIndex(content[si[0]:si[1]], -1)
+		ranges = append(ranges, phoneticPropertyRegex.FindAllStringIndex(content[si[0]:si[1]], -1)...)
+		for _, r := range ranges {
+			cuts = append(cuts, []int{si[0] + r[0], si[0] + r[1]})
+		}
+	}
+	return cuts
+}
+
+// withoutRanges returns content[start:end] without the parts covered by the sorted cuts.
+func withoutRanges(content string, start, end int, cuts [][]int) string {
+	if len(cuts) == 0 {
+		return content[start:end]
+	}
+	var sb strings.Builder
+	for _, c := range cuts {
+		if c[1] <= start || c[0] >= end {
+			continue
+		}
+		sb.WriteString(content[start:c[0]])
+		start = c[1]
+	}
+	sb.WriteString(content[start:end])
+	return sb.String()
+}
+
+// maxSheetNameRunes is Excel's sheet name length limit.
+const maxSheetNameRunes = 31
+
 // truncateSheetName enforces Excel's 31-character sheet name limit using rune count.
 func truncateSheetName(name string) string {
-	const maxRunes = 31
-	runes := []rune(name)
-	if len(runes) <= maxRunes {
-		return name
+	return truncateRunes(name, maxSheetNameRunes)
+}
+
+// truncateRunes shortens s to at most max runes.
+func truncateRunes(s string, max int) string {
+	if max < 0 {
+		max = 0
+	}
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
 	}
-	return string(runes[:maxRunes])
+	return string(runes[:max])
 }
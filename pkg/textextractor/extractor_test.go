@@ -0,0 +1,264 @@
+package textextractor
+
+import (
+	"slices"
+	"testing"
+)
+
+const (
+	testWordNamespaces    = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	testDrawingNamespaces = `xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"`
+)
+
+// bracket is a translation that marks the translated text.
+func bracket(text string) string { return "[" + text + "]" }
+
+// testDocument returns a Word document whose body holds the given paragraphs.
+func testDocument(body string) string {
+	return `<w:document ` + testWordNamespaces + `><w:body>` + body + `</w:body></w:document>`
+}
+
+func TestCoalesceRuns(t *testing.T) {
+	tests := []struct {
+		name     string
+		keepRuns bool
+		body     string
+		texts    []string
+		want     string
+	}{
+		{
+			name:  "split runs",
+			body:  `<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>销售</w:t></w:r><w:proofErr w:type="spellStart"/><w:r><w:t xml:space="preserve">报告 </w:t></w:r><w:r><w:t>汇总</w:t></w:r></w:p>`,
+			texts: []string{"销售报告 汇总"},
+			want:  `<w:p><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">[销售报告 汇总]</w:t></w:r><w:proofErr w:type="spellStart"/><w:r><w:t xml:space="preserve"></w:t></w:r><w:r><w:t></w:t></w:r></w:p>`,
+		},
+		{
+			name:     "keep runs",
+			keepRuns: true,
+			body:     `<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>销售</w:t></w:r><w:r><w:t>报告</w:t></w:r></w:p>`,
+			texts:    []string{"销售", "报告"},
+			want:     `<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>[销售]</w:t></w:r><w:r><w:t>[报告]</w:t></w:r></w:p>`,
+		},
+		{
+			name:  "paragraphs, tabs and breaks",
+			body:  `<w:p><w:r><w:t>标题</w:t></w:r></w:p><w:p><w:r><w:t>名称</w:t><w:tab/><w:t>数量</w:t><w:br/><w:t>合计</w:t></w:r></w:p>`,
+			texts: []string{"标题", "名称", "数量", "合计"},
+			want:  `<w:p><w:r><w:t>[标题]</w:t></w:r></w:p><w:p><w:r><w:t>[名称]</w:t><w:tab/><w:t>[数量]</w:t><w:br/><w:t>[合计]</w:t></w:r></w:p>`,
+		},
+		{
+			name: "complex field",
+			body: `<w:p><w:r><w:t>见第</w:t></w:r><w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText xml:space="preserve"> PAGE </w:instrText></w:r>` +
+				`<w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>3</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r><w:r><w:t>页</w:t></w:r></w:p>`,
+			texts: []string{"见第", "页"},
+			want: `<w:p><w:r><w:t>[见第]</w:t></w:r><w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText xml:space="preserve"> PAGE </w:instrText></w:r>` +
+				`<w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>3</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r><w:r><w:t>[页]</w:t></w:r></w:p>`,
+		},
+		{
+			name:  "simple field",
+			body:  `<w:p><w:r><w:t>作者：</w:t></w:r><w:fldSimple w:instr=" AUTHOR "><w:r><w:t>张三</w:t></w:r></w:fldSimple></w:p>`,
+			texts: []string{"作者：", "张三"},
+			want:  `<w:p><w:r><w:t>[作者：]</w:t></w:r><w:fldSimple w:instr=" AUTHOR "><w:r><w:t>[张三]</w:t></w:r></w:fldSimple></w:p>`,
+		},
+		{
+			name:  "hyperlink",
+			body:  `<w:p><w:r><w:t>请访问</w:t></w:r><w:hyperlink r:id="rId5"><w:r><w:t>官方</w:t></w:r><w:r><w:t>网站</w:t></w:r></w:hyperlink><w:r><w:t>了解详情</w:t></w:r></w:p>`,
+			texts: []string{"请访问", "官方网站", "了解详情"},
+			want:  `<w:p><w:r><w:t>[请访问]</w:t></w:r><w:hyperlink r:id="rId5"><w:r><w:t>[官方网站]</w:t></w:r><w:r><w:t></w:t></w:r></w:hyperlink><w:r><w:t>[了解详情]</w:t></w:r></w:p>`,
+		},
+		{
+			name: "text box paragraphs",
+			body: `<w:p><w:r><w:t>正文</w:t></w:r><w:r><w:drawing><wps:txbx><w:txbxContent><w:p><w:r><w:t>文本</w:t></w:r><w:r><w:t>框</w:t></w:r></w:p>` +
+				`<w:p><w:r><w:t>第二段</w:t></w:r></w:p></w:txbxContent></wps:txbx></w:drawing></w:r><w:r><w:t>继续</w:t></w:r></w:p>`,
+			texts: []string{"正文", "文本框", "第二段", "继续"},
+			want: `<w:p><w:r><w:t>[正文]</w:t></w:r><w:r><w:drawing><wps:txbx><w:txbxContent><w:p><w:r><w:t>[文本框]</w:t></w:r><w:r><w:t></w:t></w:r></w:p>` +
+				`<w:p><w:r><w:t>[第二段]</w:t></w:r></w:p></w:txbxContent></wps:txbx></w:drawing></w:r><w:r><w:t>[继续]</w:t></w:r></w:p>`,
+		},
+	}
+	for _, tt := range tests {
+		e := NewExtractor(ExtractorConfig{KeepRuns: tt.keepRuns})
+		texts, got := translatePart(t, e, "word/document.xml", testDocument(tt.body), bracket)
+		if !slices.Equal(texts, tt.texts) {
+			t.Errorf("%s: texts = %q, want %q", tt.name, texts, tt.texts)
+		}
+		if want := testDocument(tt.want); got != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, want)
+		}
+	}
+}
+
+func TestCoalesceDrawingRuns(t *testing.T) {
+	shape := func(body string) string {
+		return `<xdr:wsDr ` + testDrawingNamespaces + `><xdr:sp><xdr:txBody><a:bodyPr/>` + body + `</xdr:txBody></xdr:sp></xdr:wsDr>`
+	}
+	body := `<a:p><a:r><a:rPr b="1"/><a:t>季度</a:t></a:r><a:r><a:t>销售额</a:t></a:r><a:br/><a:r><a:t>同比增长</a:t></a:r></a:p>` +
+		`<a:p><a:r><a:t>第</a:t></a:r><a:fld id="{1}" type="slidenum"><a:t>2</a:t></a:fld><a:r><a:t>页</a:t></a:r></a:p>`
+	texts, got := translatePart(t, NewExtractor(ExtractorConfig{}), "xl/drawings/drawing1.xml", shape(body), bracket)
+	if want := []string{"季度销售额", "同比增长", "第", "页"}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	want := `<a:p><a:r><a:rPr b="1"/><a:t>[季度销售额]</a:t></a:r><a:r><a:t></a:t></a:r><a:br/><a:r><a:t>[同比增长]</a:t></a:r></a:p>` +
+		`<a:p><a:r><a:t>[第]</a:t></a:r><a:fld id="{1}" type="slidenum"><a:t>2</a:t></a:fld><a:r><a:t>[页]</a:t></a:r></a:p>`
+	if got != shape(want) {
+		t.Errorf("got\n%s\nwant\n%s", got, shape(want))
+	}
+
+	texts, _ = translatePart(t, NewExtractor(ExtractorConfig{KeepRuns: true}), "xl/drawings/drawing1.xml", shape(body), bracket)
+	if want := []string{"季度", "销售额", "同比增长", "第", "页"}; !slices.Equal(texts, want) {
+		t.Errorf("keep runs: texts = %q, want %q", texts, want)
+	}
+}
+
+// TestTranslateTextBox translates a text box as Excel writes it: a grouped shape whose
+// paragraphs mix differently formatted runs.
+func TestTranslateTextBox(t *testing.T) {
+	drawing := func(paragraphs string) string {
+		return `<xdr:wsDr ` + testDrawingNamespaces + `><xdr:twoCellAnchor editAs="oneCell">` +
+			`<xdr:from><xdr:col>1</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>2</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from>` +
+			`<xdr:to><xdr:col>5</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>8</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:to>` +
+			`<xdr:grpSp><xdr:nvGrpSpPr><xdr:cNvPr id="2" name="组合 1"/><xdr:cNvGrpSpPr/></xdr:nvGrpSpPr><xdr:grpSpPr/>` +
+			`<xdr:sp macro="" textlink=""><xdr:nvSpPr><xdr:cNvPr id="3" name="文本框 2"/><xdr:cNvSpPr txBox="1"/></xdr:nvSpPr><xdr:spPr/>` +
+			`<xdr:txBody><a:bodyPr vertOverflow="clip" wrap="square" rtlCol="0"/><a:lstStyle/>` + paragraphs + `</xdr:txBody></xdr:sp>` +
+			`</xdr:grpSp></xdr:twoCellAnchor></xdr:wsDr>`
+	}
+	paragraphs := `<a:p><a:pPr algn="l"/><a:r><a:rPr lang="zh-CN" altLang="en-US" sz="1100" b="1"/><a:t>注意：</a:t></a:r>` +
+		`<a:r><a:rPr lang="zh-CN" altLang="en-US" sz="1100"/><a:t>本表数据</a:t></a:r>` +
+		`<a:r><a:rPr lang="zh-CN" altLang="en-US" sz="1100"><a:solidFill><a:srgbClr val="FF0000"/></a:solidFill></a:rPr><a:t>仅供内部</a:t></a:r>` +
+		`<a:r><a:rPr lang="zh-CN" altLang="en-US" sz="1100"/><a:t>使用</a:t></a:r></a:p>` +
+		`<a:p><a:pPr algn="l"/><a:endParaRPr lang="zh-CN" altLang="en-US" sz="1100"/></a:p>` +
+		`<a:p><a:r><a:rPr lang="en-US" altLang="zh-CN" sz="1100"/><a:t>2024</a:t></a:r><a:r><a:rPr lang="zh-CN" altLang="en-US" sz="1100"/><a:t>年度</a:t></a:r>` +
+		`<a:endParaRPr lang="zh-CN" altLang="en-US" sz="1100"/></a:p>`
+
+	translations := map[string]string{"注意：本表数据仅供内部使用": "Note: internal use only", "2024年度": "Fiscal 2024"}
+	texts, got := translatePart(t, NewExtractor(ExtractorConfig{}), "xl/drawings/drawing1.xml", drawing(paragraphs), func(s string) string { return translations[s] })
+	if want := []string{"注意：本表数据仅供内部使用", "2024年度"}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	// Each paragraph is translated as one sentence into its first run; the other runs are
+	// emptied but keep their formatting, and shape names are not translated
+	want := `<a:p><a:pPr algn="l"/><a:r><a:rPr lang="zh-CN" altLang="en-US" sz="1100" b="1"/><a:t>Note: internal use only</a:t></a:r>` +
+		`<a:r><a:rPr lang="zh-CN" altLang="en-US" sz="1100"/><a:t></a:t></a:r>` +
+		`<a:r><a:rPr lang="zh-CN" altLang="en-US" sz="1100"><a:solidFill><a:srgbClr val="FF0000"/></a:solidFill></a:rPr><a:t></a:t></a:r>` +
+		`<a:r><a:rPr lang="zh-CN" altLang="en-US" sz="1100"/><a:t></a:t></a:r></a:p>` +
+		`<a:p><a:pPr algn="l"/><a:endParaRPr lang="zh-CN" altLang="en-US" sz="1100"/></a:p>` +
+		`<a:p><a:r><a:rPr lang="en-US" altLang="zh-CN" sz="1100"/><a:t>Fiscal 2024</a:t></a:r><a:r><a:rPr lang="zh-CN" altLang="en-US" sz="1100"/><a:t></a:t></a:r>` +
+		`<a:endParaRPr lang="zh-CN" altLang="en-US" sz="1100"/></a:p>`
+	if got != drawing(want) {
+		t.Errorf("got\n%s\nwant\n%s", got, drawing(want))
+	}
+}
+
+// TestCoalescedRunsUntranslated checks that runs whose translation equals their text are left as they are.
+func TestCoalescedRunsUntranslated(t *testing.T) {
+	doc := testDocument(`<w:p><w:r><w:t>销售</w:t></w:r><w:r><w:t>报告</w:t></w:r></w:p>`)
+	_, got := translatePart(t, NewExtractor(ExtractorConfig{}), "word/document.xml", doc, func(s string) string { return s })
+	if got != doc {
+		t.Errorf("got\n%s\nwant the document unchanged", got)
+	}
+}
+
+func TestMergeSharedStringRuns(t *testing.T) {
+	sst := func(items string) string { return `<sst ` + testSheetNamespace + `>` + items + `</sst>` }
+	items := `<si><r><rPr><b/></rPr><t xml:space="preserve">销售 </t></r><r><t>报告</t></r></si>` +
+		`<si><t xml:space="preserve">  合计  </t></si>` +
+		`<si><r><t>利润</t></r><r><t xml:space="preserve">  率</t></r><phoneticPr fontId="1"/></si>`
+	translations := map[string]string{"销售 报告": "Sales report", "合计": "Total", "利润  率": "Profit margin"}
+	texts, got := translatePart(t, NewExtractor(ExtractorConfig{}), "xl/sharedStrings.xml", sst(items), func(s string) string { return translations[s] })
+	if want := []string{"销售 报告", "合计", "利润  率"}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	// The translation of a string goes to its first run, which keeps its space when any run did;
+	// the edge spaces of a string are kept around the translation
+	want := `<si><r><rPr><b/></rPr><t xml:space="preserve">Sales report</t></r><r><t></t></r></si>` +
+		`<si><t xml:space="preserve">  Total  </t></si>` +
+		`<si><r><t xml:space="preserve">Profit margin</t></r><r><t xml:space="preserve"></t></r></si>`
+	if got != sst(want) {
+		t.Errorf("got\n%s\nwant\n%s", got, sst(want))
+	}
+}
+
+func TestPhoneticAnnotations(t *testing.T) {
+	sst := func(items string) string { return `<sst ` + testSheetNamespace + `>` + items + `</sst>` }
+	items := `<si><t>東京</t><rPh sb="0" eb="2"><t>トウキョウ</t></rPh><phoneticPr fontId="1"/></si>` +
+		`<si><t>大阪</t><rPh sb="0" eb="2"><t>オオサカ</t></rPh><phoneticPr fontId="1"/></si>`
+	texts, got := translatePart(t, NewExtractor(ExtractorConfig{}), "xl/sharedStrings.xml", sst(items), func(s string) string {
+		if s == "東京" {
+			return "Tokyo"
+		}
+		return s
+	})
+	// The readings are not translated; they are dropped from the translated string only
+	if want := []string{"東京", "大阪"}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	want := `<si><t>Tokyo</t></si>` +
+		`<si><t>大阪</t><rPh sb="0" eb="2"><t>オオサカ</t></rPh><phoneticPr fontId="1"/></si>`
+	if got != sst(want) {
+		t.Errorf("got\n%s\nwant\n%s", got, sst(want))
+	}
+}
+
+func TestPreserveEdgeSpace(t *testing.T) {
+	tests := []struct {
+		name        string
+		run         string
+		translation string
+		text        string
+		want        string
+	}{
+		{"edge spaces", `<w:t xml:space="preserve"> hello </w:t>`, "你好", "hello", `<w:t xml:space="preserve"> 你好 </w:t>`},
+		{"no edge spaces", `<w:t>hello</w:t>`, "你好", "hello", `<w:t>你好</w:t>`},
+		{"translation with edge space", `<w:t>hello</w:t>`, "你好 ", "hello", `<w:t xml:space="preserve">你好 </w:t>`},
+		{"translation with double space", `<w:t>hello</w:t>`, "你  好", "hello", `<w:t xml:space="preserve">你  好</w:t>`},
+		{"other space attribute", `<w:t xml:space="default">hello</w:t>`, "a\tb", "hello", `<w:t xml:space="preserve">a	b</w:t>`},
+	}
+	for _, tt := range tests {
+		doc := testDocument(`<w:p><w:r>` + tt.run + `</w:r></w:p>`)
+		texts, got := translatePart(t, NewExtractor(ExtractorConfig{}), "word/document.xml", doc, func(string) string { return tt.translation })
+		if len(texts) != 1 || texts[0] != tt.text {
+			t.Errorf("%s: texts = %q, want %q", tt.name, texts, tt.text)
+		}
+		if want := testDocument(`<w:p><w:r>` + tt.want + `</w:r></w:p>`); got != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, want)
+		}
+	}
+}
+
+func TestSplitEdgeSpace(t *testing.T) {
+	item := splitEdgeSpace(ExtractionItem{Text: " \n hello world\t", Prefix: "(", Suffix: ")"})
+	if item.Text != "hello world" || item.Prefix != "( \n " || item.Suffix != "\t)" {
+		t.Errorf("got text %q, prefix %q, suffix %q", item.Text, item.Prefix, item.Suffix)
+	}
+}
+
+// TestEmptyTextElements checks that empty and self-closing text elements are passed through
+// unchanged and receive no translation.
+func TestEmptyTextElements(t *testing.T) {
+	tests := []struct {
+		body  string
+		texts []string
+		want  string
+	}{
+		{`<w:p><w:r><w:t/></w:r></w:p>`, nil, `<w:p><w:r><w:t/></w:r></w:p>`},
+		{`<w:p><w:r><w:t></w:t></w:r></w:p>`, nil, `<w:p><w:r><w:t></w:t></w:r></w:p>`},
+		{`<w:p><w:r><w:t xml:space="preserve"/></w:r></w:p>`, nil, `<w:p><w:r><w:t xml:space="preserve"/></w:r></w:p>`},
+		{
+			`<w:p><w:r><w:t/></w:r><w:r><w:t>标题</w:t></w:r><w:r><w:t></w:t></w:r><w:r><w:t>内容</w:t></w:r><w:r><w:t xml:space="preserve"/></w:r></w:p>`,
+			[]string{"标题内容"},
+			`<w:p><w:r><w:t/></w:r><w:r><w:t>[标题内容]</w:t></w:r><w:r><w:t></w:t></w:r><w:r><w:t></w:t></w:r><w:r><w:t xml:space="preserve"/></w:r></w:p>`,
+		},
+		{
+			`<w:p><w:r><w:t>标题</w:t><w:t/></w:r></w:p><w:p><w:r><w:t/></w:r></w:p>`,
+			[]string{"标题"},
+			`<w:p><w:r><w:t>[标题]</w:t><w:t/></w:r></w:p><w:p><w:r><w:t/></w:r></w:p>`,
+		},
+	}
+	for _, tt := range tests {
+		texts, got := translatePart(t, NewExtractor(ExtractorConfig{}), "word/document.xml", testDocument(tt.body), bracket)
+		if !slices.Equal(texts, tt.texts) {
+			t.Errorf("%s: texts = %q, want %q", tt.body, texts, tt.texts)
+		}
+		if want := testDocument(tt.want); got != want {
+			t.Errorf("got\n%s\nwant\n%s", got, want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package textextractor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Namespaces of legacy VML drawings, which hold the form controls (and note shapes) of worksheets,
+// and of the property bags of ActiveX controls.
+const (
+	vmlNamespace      = "urn:schemas-microsoft-com:vml"
+	vmlExcelNamespace = "urn:schemas-microsoft-com:office:excel"
+	activeXNamespace  = "http://schemas.microsoft.com/office/2006/activeX"
+)
+
+// formControlTypes are the ObjectType values of form controls whose caption is shown on the sheet.
+// Notes are left out, their text lives in the comments part; list and drop-down boxes have no caption.
+var formControlTypes = map[string]bool{
+	"Button":   true,
+	"Checkbox": true,
+	"Radio":    true,
+	"Label":    true,
+	"GBox":     true, // Group box
+}
+
+var (
+	// vmlTextRegex matches the text between two tags of a VML text box, capturing it.
+	vmlTextRegex = regexp.MustCompile(`>([^<]+)<`)
+	// vmlLineRegex matches the boundaries between lines of a VML text box.
+	vmlLineRegex = regexp.MustCompile(`(?i)<div\b[^>]*>|</div>|<br\b[^>]*>`)
+	// objectTypeRegex captures the ObjectType attribute of a VML ClientData element.
+	objectTypeRegex = regexp.MustCompile(`\sObjectType="([^"]*)"`)
+	// activeXCaptionRegex matches the Caption property of an ActiveX control saved as a property
+	// bag, capturing its value. Controls saved in binary form (activeX*.bin) are not translated.
+	activeXCaptionRegex = regexp.MustCompile(`<(?:\w+:)?ocxPr\s[^>]*?\b(?:\w+:)?name="Caption"[^>]*?\s(?:\w+:)?value="([^"]*)"`)
+)
+
+// isFormControlPart reports whether the internal file may hold the captions of form controls:
+// a legacy VML drawing or the properties of an ActiveX control.
+func isFormControlPart(name string) bool {
+	return strings.Contains(name, "xl/drawings/vmlDrawing") && strings.HasSuffix(name, ".vml") ||
+		strings.Contains(name, "xl/activeX/activeX") && strings.HasSuffix(name, ".xml")
+}
+
+// extractFormControls finds the captions of the buttons, check boxes, option buttons, labels
+// and group boxes of a VML drawing, or the caption of an ActiveX control. Each line of a caption
+// is one item; the rest of the shape, including the macro it runs (x:FmlaMacro) and the cell it
+// is linked to, is left as it is.
+func (e *Extractor) extractFormControls(content string) []ExtractionItem {
+	if strings.Contains(content, activeXNamespace) {
+		var items []ExtractionItem
+		for _, m := range activeXCaptionRegex.FindAllStringSubmatchIndex(content, -1) {
+			if item, ok := e.newItem(content, [][]int{m}); ok {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+
+	v := namespacePrefix(content, "v:", vmlNamespace)
+	x := namespacePrefix(content, "x:", vmlExcelNamespace)
+	shapeRegex := elementRegex(v, `(?s)<%shape\b[^>]*>.*?</%shape>`)
+	textboxRegex := elementRegex(v, `(?s)<%textbox\b[^>]*>(.*?)</%textbox>`)
+	clientDataRegex := elementRegex(x, `<%ClientData\b[^>]*>`)
+
+	var items []ExtractionItem
+	for _, shape := range shapeRegex.FindAllStringIndex(content, -1) {
+		body := content[shape[0]:shape[1]]
+		clientData := clientDataRegex.FindString(body)
+		objectType := objectTypeRegex.FindStringSubmatch(clientData)
+		if objectType == nil || !formControlTypes[objectType[1]] {
+			continue
+		}
+		textbox := textboxRegex.FindStringSubmatchIndex(body)
+		if textbox == nil {
+			continue
+		}
+		start := shape[0] + textbox[2]
+		items = append(items, e.extractVMLText(content, start, shape[0]+textbox[3])...)
+	}
+	return items
+}
+
+// extractVMLText builds items from the text of a VML text box between start and end, one per
+// line unless the runs are kept separate.
+func (e *Extractor) extractVMLText(content string, start, end int) []ExtractionItem {
+	text := content[start:end]
+	var lines [][]int
+	if !e.config.KeepRuns {
+		lines = vmlLineRegex.FindAllStringIndex(text, -1)
+	}
+
+	var items []ExtractionItem
+	var group [][]int
+	flush := func() {
+		if len(group) > 0 {
+			if item, ok := e.newItem(content, group); ok {
+				items = append(items, item)
+			}
+		}
+		group = nil
+	}
+	l := 0
+	// The text box content is preceded by ">" and followed by "<", so the first and last text are found too
+	for _, m := range vmlTextRegex.FindAllStringSubmatchIndex(content[start-1:end+1], -1) {
+		m[2], m[3] = m[2]+start-1, m[3]+start-1
+		// Indentation between the tags is not text, while spaces between runs of a line are
+		raw := content[m[2]:m[3]]
+		if strings.ContainsAny(raw, "\r\n") {
+			trimmed := strings.TrimSpace(raw)
+			if trimmed == "" {
+				continue
+			}
+			m[2] += strings.Index(raw, trimmed)
+			m[3] = m[2] + len(trimmed)
+		}
+		if e.config.KeepRuns {
+			flush()
+		}
+		for l < len(lines) && lines[l][0]+start < m[2] {
+			flush()
+			l++
+		}
+		group = append(group, []int{m[2], m[3], m[2], m[3]})
+	}
+	flush()
+	return items
+}
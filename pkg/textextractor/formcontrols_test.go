@@ -0,0 +1,72 @@
+package textextractor
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testVML is a legacy drawing with a button running a macro, a check box linked to a cell and
+// a note, as Excel writes them.
+const testVML = `<xml xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office" xmlns:x="urn:schemas-microsoft-com:office:excel">
+ <v:shape id="_x0000_s1025" type="#_x0000_t201" style="position:absolute" o:button="t" fillcolor="buttonFace [67]">
+  <v:textbox style="mso-direction-alt:auto" o:singleclick="f">
+   <div style="text-align:center"><font face="等线" size="220" color="#000000">生成报表</font></div>
+  </v:textbox>
+  <x:ClientData ObjectType="Button">
+   <x:Anchor>1, 0, 1, 0, 3, 0, 3, 0</x:Anchor>
+   <x:PrintObject>False</x:PrintObject>
+   <x:AutoFill>False</x:AutoFill>
+   <x:FmlaMacro>[0]!生成报表</x:FmlaMacro>
+   <x:TextHAlign>Center</x:TextHAlign>
+  </x:ClientData>
+ </v:shape>
+ <v:shape id="_x0000_s1026" type="#_x0000_t201" style="position:absolute">
+  <v:textbox style="mso-direction-alt:auto" o:singleclick="f">
+   <div style="text-align:left"><font face="等线" size="220" color="#000000">已审核</font></div>
+  </v:textbox>
+  <x:ClientData ObjectType="Checkbox">
+   <x:Anchor>1, 0, 5, 0, 3, 0, 6, 0</x:Anchor>
+   <x:FmlaLink>$D$6</x:FmlaLink>
+  </x:ClientData>
+ </v:shape>
+ <v:shape id="_x0000_s1027" type="#_x0000_t202" style="position:absolute;visibility:hidden">
+  <v:textbox style="mso-direction-alt:auto"><div style="text-align:left">批注</div></v:textbox>
+  <x:ClientData ObjectType="Note"><x:Row>0</x:Row><x:Column>0</x:Column></x:ClientData>
+ </v:shape>
+</xml>`
+
+func TestTranslateFormControls(t *testing.T) {
+	translations := map[string]string{"生成报表": "Build report", "已审核": "Reviewed"}
+	texts, got := translatePart(t, NewExtractor(ExtractorConfig{FormControls: true}), "xl/drawings/vmlDrawing1.vml", testVML, func(s string) string { return translations[s] })
+	if want := []string{"生成报表", "已审核"}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	// Only the captions change: the macro the button runs, the linked cell and the note are kept
+	want := strings.NewReplacer(
+		`color="#000000">生成报表</font>`, `color="#000000">Build report</font>`,
+		`color="#000000">已审核</font>`, `color="#000000">Reviewed</font>`,
+	).Replace(testVML)
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if !strings.Contains(got, "<x:FmlaMacro>[0]!生成报表</x:FmlaMacro>") {
+		t.Error("the macro binding of the button changed")
+	}
+
+	if NewExtractor(ExtractorConfig{}).Supports("xl/drawings/vmlDrawing1.vml") {
+		t.Error("form controls are translated without FormControls")
+	}
+}
+
+func TestTranslateActiveXCaption(t *testing.T) {
+	control := `<ax:ocx ax:classid="{D7053240-CE69-11CD-A777-00DD01143C57}" ax:persistence="persistPropertyBag" xmlns:ax="http://schemas.microsoft.com/office/2006/activeX" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<ax:ocxPr ax:name="Caption" ax:value="提交"/><ax:ocxPr ax:name="Size" ax:value="2540;846"/></ax:ocx>`
+	texts, got := translatePart(t, NewExtractor(ExtractorConfig{FormControls: true}), "xl/activeX/activeX1.xml", control, bracket)
+	if want := []string{"提交"}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	if want := strings.Replace(control, `ax:value="提交"`, `ax:value="[提交]"`, 1); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
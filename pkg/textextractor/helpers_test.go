@@ -0,0 +1,24 @@
+package textextractor
+
+import "testing"
+
+// translatePart extracts the texts of a part, translates each with translate and applies the
+// translations. It returns the extracted texts and the translated part.
+func translatePart(t *testing.T, e *Extractor, name, content string, translate func(string) string) ([]string, string) {
+	t.Helper()
+	content, items, err := e.Extract(content, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := make([]string, len(items))
+	translations := make([]string, len(items))
+	for i, item := range items {
+		texts[i] = item.Text
+		translations[i] = translate(item.Text)
+	}
+	output, err := e.Apply(content, name, items, translations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return texts, output
+}
@@ -0,0 +1,11 @@
+package textextractor
+
+import "regexp"
+
+// hyperlinkTooltipRegex returns the regex matching the hyperlinks of a worksheet that have a
+// tooltip, capturing the tooltip. The target (r:id relationship or location) and the display
+// attribute are other attributes and are left as they are; the text shown in the cell is a
+// shared string and is translated with the other cells.
+func hyperlinkTooltipRegex(x string) *regexp.Regexp {
+	return elementRegex(x, `<%hyperlink\b[^>]*?\stooltip="([^"]*)"[^>]*>`)
+}
@@ -0,0 +1,129 @@
+package textextractor
+
+import "unicode"
+
+// Scripts told apart by DetectScript. Japanese and Korean texts may contain Han characters too.
+const (
+	ScriptChinese  = "chinese"  // Han characters only
+	ScriptJapanese = "japanese" // Kana, with or without Han characters
+	ScriptKorean   = "korean"   // Hangul, with or without Han characters
+	ScriptLatin    = "latin"
+	ScriptCyrillic = "cyrillic"
+	ScriptGreek    = "greek"
+	ScriptArabic   = "arabic"
+	ScriptHebrew   = "hebrew"
+	ScriptThai     = "thai"
+)
+
+// cjkWeight is the weight of a CJK character against a letter of an alphabet when the letters
+// of a text are counted, as it carries about as much as a short word.
+const cjkWeight = 3
+
+// dominantShare is the share of the letters of a text that must belong to one script for
+// DetectScript to report it. Mixed texts below it are not recognized.
+const dominantShare = 0.8
+
+// scriptLanguages are names and codes of the languages written in each script, see matchLanguage.
+// "uk" is left out as it also names a region, e.g. "English (UK)".
+var scriptLanguages = []struct {
+	script    string
+	languages []string
+}{
+	{ScriptChinese, []string{"chinese", "zh", "mandarin", "cantonese"}},
+	{ScriptJapanese, []string{"japanese", "ja"}},
+	{ScriptKorean, []string{"korean", "ko"}},
+	{ScriptLatin, []string{"english", "en", "french", "fr", "german", "de", "spanish", "es", "italian", "it",
+		"portuguese", "pt", "dutch", "nl", "polish", "pl", "czech", "cs", "swedish", "sv", "danish", "da",
+		"norwegian", "no", "nb", "finnish", "fi", "turkish", "tr", "indonesian", "id", "malay", "ms",
+		"vietnamese", "vi", "romanian", "ro", "hungarian", "hu"}},
+	{ScriptCyrillic, []string{"russian", "ru", "ukrainian", "bulgarian", "bg", "serbian", "sr", "kazakh", "kk"}},
+	{ScriptGreek, []string{"greek", "el"}},
+	{ScriptArabic, []string{"arabic", "ar", "persian", "farsi", "fa", "urdu", "ur"}},
+	{ScriptHebrew, []string{"hebrew", "he", "iw"}},
+	{ScriptThai, []string{"thai", "th"}},
+}
+
+// LanguageScript returns the script of a language given as an English name or a language code
+// (e.g. "Simplified Chinese", "en-US"), or "" if it is not known.
+func LanguageScript(lang string) string {
+	for _, s := range scriptLanguages {
+		if matchLanguage(lang, s.languages) {
+			return s.script
+		}
+	}
+	return ""
+}
+
+// DetectScript guesses the script a text is written in from its letters, or returns "" if it
+// has no letters or mixes scripts, e.g. a Japanese sentence around an English name. Kana mark a
+// text as Japanese and Hangul as Korean; Han characters alone are taken as Chinese.
+func DetectScript(text string) string {
+	counts := make(map[string]int)
+	han, total := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		script := ""
+		weight := 1
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han += cjkWeight
+			total += cjkWeight
+			continue
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			script, weight = ScriptJapanese, cjkWeight
+		case unicode.Is(unicode.Hangul, r):
+			script, weight = ScriptKorean, cjkWeight
+		case unicode.Is(unicode.Latin, r):
+			script = ScriptLatin
+		case unicode.Is(unicode.Cyrillic, r):
+			script = ScriptCyrillic
+		case unicode.Is(unicode.Greek, r):
+			script = ScriptGreek
+		case unicode.Is(unicode.Arabic, r):
+			script = ScriptArabic
+		case unicode.Is(unicode.Hebrew, r):
+			script = ScriptHebrew
+		case unicode.Is(unicode.Thai, r):
+			script = ScriptThai
+		}
+		counts[script] += weight
+		total += weight
+	}
+	if total == 0 {
+		return ""
+	}
+
+	// Han characters belong to the Japanese or Korean text they appear in
+	switch {
+	case counts[ScriptJapanese] > 0 && counts[ScriptJapanese] >= counts[ScriptKorean]:
+		counts[ScriptJapanese] += han
+	case counts[ScriptKorean] > 0:
+		counts[ScriptKorean] += han
+	default:
+		counts[ScriptChinese] = han
+	}
+	for script, n := range counts {
+		if script != "" && float64(n) >= dominantShare*float64(total) {
+			return script
+		}
+	}
+	return ""
+}
+
+// sameLanguage reports whether the text is already written in the script of the target language,
+// see ExtractorConfig.SkipSameLanguage.
+func (e *Extractor) sameLanguage(text string) bool {
+	if !e.config.SkipSameLanguage {
+		return false
+	}
+	target := LanguageScript(e.config.TargetLang)
+	return target != "" && DetectScript(text) == target
+}
+
+// skipLanguage reports whether the text is left untranslated for its language: text without CJK
+// characters when only CJK text is translated, or text already in the target language.
+func (e *Extractor) skipLanguage(text string) bool {
+	return e.config.CJKOnly && !ContainsCJK(text) || e.sameLanguage(text)
+}
@@ -0,0 +1,87 @@
+package textextractor
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// mirrorDroppedElements are the worksheet elements a mirror sheet leaves out, as they refer to
+// parts of the sheet through relationships or would be shared with the mirrored sheet.
+var mirrorDroppedElements = []string{
+	"hyperlinks", "customProperties", "drawing", "legacyDrawing", "legacyDrawingHF", "drawingHF",
+	"picture", "oleObjects", "controls", "tableParts", "extLst",
+}
+
+// codeNameAttrRegex matches the code name of a sheet, which must be unique in a workbook.
+var codeNameAttrRegex = regexp.MustCompile(`\scodeName="[^"]*"`)
+
+// MirrorWorksheet returns a copy of a worksheet to add to the same workbook, e.g. to show the
+// translation of its cells next to the source. The copy has no relationships: hyperlinks,
+// drawings, comments, tables, controls and extensions are left out, as is the printer setup.
+// Its tab is not selected and it has no code name.
+func MirrorWorksheet(content string) string {
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	r := namespacePrefix(content, "r:", relationshipsNamespace)
+	for _, name := range mirrorDroppedElements {
+		content = elementRegex(x, `(?s)<%`+name+`\b(?:[^>]*/>|[^>]*>.*?</%`+name+`>)`).ReplaceAllString(content, "")
+	}
+	content = regexp.MustCompile(`\s`+regexp.QuoteMeta(r)+`id="[^"]*"`).ReplaceAllString(content, "")
+	if loc := elementRegex(x, `<%sheetPr\b[^>]*>`).FindStringIndex(content); loc != nil {
+		content = content[:loc[0]] + codeNameAttrRegex.ReplaceAllString(content[loc[0]:loc[1]], "") + content[loc[1]:]
+	}
+	return elementRegex(x, `<%sheetView\b[^>]*>`).ReplaceAllStringFunc(content, func(view string) string {
+		return strings.Replace(view, ` tabSelected="1"`, "", 1)
+	})
+}
+
+// NewSheet is a sheet added to a workbook, see AddSheets.
+type NewSheet struct {
+	Name  string // Wanted name, made valid and unique
+	RelID string // Relationship id of its worksheet part in the workbook's relationships
+	State string // Visibility: "" for visible, "hidden" or "veryHidden"
+}
+
+// sheetIDAttrRegex matches the id of a sheet in a workbook, capturing it.
+var sheetIDAttrRegex = regexp.MustCompile(`\ssheetId="(\d+)"`)
+
+// AddSheets adds sheets after the last sheet of a workbook (xl/workbook.xml), so that sheets
+// referred to by their position, e.g. by the print areas of defined names, stay in place. The
+// names are made valid and unique like translated sheet names; the names given are returned.
+func AddSheets(content string, sheets []NewSheet) (string, []string) {
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	r := namespacePrefix(content, "r:", relationshipsNamespace)
+	end := elementRegex(x, `</%sheets>`).FindStringIndex(content)
+	if end == nil || len(sheets) == 0 {
+		return content, nil
+	}
+
+	used := make(map[string]bool)
+	for name := range reservedSheetNames {
+		used[name] = true
+	}
+	id := 0
+	for _, m := range sheetNameRegex(content).FindAllStringSubmatch(content, -1) {
+		used[strings.ToLower(html.UnescapeString(m[1]))] = true
+		if v := sheetIDAttrRegex.FindStringSubmatch(m[0]); v != nil {
+			n, _ := strconv.Atoi(v[1])
+			id = max(id, n)
+		}
+	}
+	fixer := &sheetNameFixer{used: used}
+
+	var sb strings.Builder
+	names := make([]string, len(sheets))
+	for i, sheet := range sheets {
+		names[i] = fixer.fix(sheet.Name)
+		id++
+		state := ""
+		if sheet.State != "" {
+			state = fmt.Sprintf(` state="%s"`, html.EscapeString(sheet.State))
+		}
+		fmt.Fprintf(&sb, `<%ssheet name="%s" sheetId="%d"%s %sid="%s"/>`, x, html.EscapeString(names[i]), id, state, r, html.EscapeString(sheet.RelID))
+	}
+	return content[:end[0]] + sb.String() + content[end[0]:], names
+}
@@ -0,0 +1,43 @@
+package textextractor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMirrorWorksheet(t *testing.T) {
+	sheet := `<x:worksheet xmlns:x="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:rel="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<x:sheetPr codeName="Sheet1"><x:tabColor rgb="FFFF0000"/></x:sheetPr><x:sheetViews><x:sheetView tabSelected="1" workbookViewId="0"/></x:sheetViews>` +
+		`<x:sheetData><x:row r="1"><x:c r="A1"><x:v>1</x:v></x:c></x:row></x:sheetData>` +
+		`<x:hyperlinks><x:hyperlink ref="A1" rel:id="rId1"/></x:hyperlinks><x:pageSetup orientation="landscape" rel:id="rId2"/>` +
+		`<x:drawing rel:id="rId3"/><x:legacyDrawing rel:id="rId4"/><x:tableParts count="1"><x:tablePart rel:id="rId5"/></x:tableParts>` +
+		`<x:extLst><x:ext uri="{x}"><x14:slicerList xmlns:x14="x14"><x14:slicer rel:id="rId6"/></x14:slicerList></x:ext></x:extLst></x:worksheet>`
+	want := `<x:worksheet xmlns:x="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:rel="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<x:sheetPr><x:tabColor rgb="FFFF0000"/></x:sheetPr><x:sheetViews><x:sheetView workbookViewId="0"/></x:sheetViews>` +
+		`<x:sheetData><x:row r="1"><x:c r="A1"><x:v>1</x:v></x:c></x:row></x:sheetData>` +
+		`<x:pageSetup orientation="landscape"/></x:worksheet>`
+	if got := MirrorWorksheet(sheet); got != want {
+		t.Errorf("MirrorWorksheet =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAddSheets(t *testing.T) {
+	workbook := `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Data" sheetId="2" r:id="rId1"/><sheet name="Notes" sheetId="7" r:id="rId2"/></sheets></workbook>`
+	got, names := AddSheets(workbook, []NewSheet{
+		{Name: "Data", RelID: "rId3"},
+		{Name: "Q1/Q2 & more", RelID: "rId4", State: "hidden"},
+		{Name: "History", RelID: "rId5"}, // Reserved by Excel
+	})
+	wantNames := []string{"Data (2)", "Q1_Q2 & more", "History (2)"}
+	if strings.Join(names, "|") != strings.Join(wantNames, "|") {
+		t.Errorf("got names %q, want %q", names, wantNames)
+	}
+	want := `<sheet name="Notes" sheetId="7" r:id="rId2"/>` +
+		`<sheet name="Data (2)" sheetId="8" r:id="rId3"/>` +
+		`<sheet name="Q1_Q2 &amp; more" sheetId="9" state="hidden" r:id="rId4"/>` +
+		`<sheet name="History (2)" sheetId="10" r:id="rId5"/></sheets>`
+	if !strings.Contains(got, want) {
+		t.Errorf("AddSheets =\n%s\nwant it to contain\n%s", got, want)
+	}
+}
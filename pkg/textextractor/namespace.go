@@ -0,0 +1,42 @@
+package textextractor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Namespace URIs of the OOXML vocabularies, in both Transitional and Strict conformance.
+const (
+	wordNamespace          = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	wordStrictNamespace    = "http://purl.oclc.org/ooxml/wordprocessingml/main"
+	sheetNamespace         = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	sheetStrictNamespace   = "http://purl.oclc.org/ooxml/spreadsheetml/main"
+	drawingNamespace       = "http://schemas.openxmlformats.org/drawingml/2006/main"
+	drawingStrictNamespace = "http://purl.oclc.org/ooxml/drawingml/main"
+	threadedNamespace      = "http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments"
+)
+
+var namespaceDeclRegex = regexp.MustCompile(`\bxmlns(?::([A-Za-z_][\w.-]*))?\s*=\s*["']([^"']*)["']`)
+
+// namespacePrefix returns the element prefix (e.g. "w:") bound to one of the given namespace URIs
+// in the content. A default namespace declaration yields an empty prefix. If none of the URIs is
+// declared, fallback is returned, which keeps the conventional prefixes working for fragments.
+func namespacePrefix(content string, fallback string, uris ...string) string {
+	for _, decl := range namespaceDeclRegex.FindAllStringSubmatch(content, -1) {
+		for _, uri := range uris {
+			if decl[2] != uri {
+				continue
+			}
+			if decl[1] == "" {
+				return ""
+			}
+			return decl[1] + ":"
+		}
+	}
+	return fallback
+}
+
+// elementRegex compiles a pattern in which every "%" is replaced with the quoted namespace prefix.
+func elementRegex(prefix string, pattern string) *regexp.Regexp {
+	return regexp.MustCompile(strings.ReplaceAll(pattern, "%", regexp.QuoteMeta(prefix)))
+}
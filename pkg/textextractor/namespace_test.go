@@ -0,0 +1,83 @@
+package textextractor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNamespacePrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"conventional", `<w:document xmlns:w="` + wordNamespace + `">`, "w:"},
+		{"strict", `<w:document xmlns:w="` + wordStrictNamespace + `">`, "w:"},
+		{"other prefix", `<ns0:document xmlns:ns0="` + wordStrictNamespace + `">`, "ns0:"},
+		{"default namespace", `<document xmlns="` + wordNamespace + `">`, ""},
+		{"single quotes", `<x:document xmlns:x='` + wordNamespace + `'>`, "x:"},
+		{"other namespaces first", `<w:document xmlns:r="http://example.com/r" xmlns:w14="http://example.com/w14" xmlns:doc="` + wordNamespace + `">`, "doc:"},
+		{"fragment", `<w:p><w:r><w:t>text</w:t></w:r></w:p>`, "fallback:"},
+	}
+	for _, tt := range tests {
+		if got := namespacePrefix(tt.content, "fallback:", wordNamespace, wordStrictNamespace); got != tt.want {
+			t.Errorf("%s: namespacePrefix = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestStrictDocuments checks that parts saved in Strict Open XML or with unusual prefixes are translated.
+func TestStrictDocuments(t *testing.T) {
+	tests := []struct {
+		name    string
+		part    string
+		content string
+		want    string
+	}{
+		{
+			"strict document",
+			"word/document.xml",
+			`<w:document xmlns:w="` + wordStrictNamespace + `"><w:body><w:p><w:r><w:t>销售</w:t></w:r><w:r><w:t>报告</w:t></w:r></w:p></w:body></w:document>`,
+			`<w:document xmlns:w="` + wordStrictNamespace + `"><w:body><w:p><w:r><w:t>[销售报告]</w:t></w:r><w:r><w:t></w:t></w:r></w:p></w:body></w:document>`,
+		},
+		{
+			"document with another prefix",
+			"word/document.xml",
+			`<ns0:document xmlns:ns0="` + wordNamespace + `"><ns0:body><ns0:p><ns0:r><ns0:t>销售</ns0:t></ns0:r><ns0:r><ns0:t>报告</ns0:t></ns0:r></ns0:p></ns0:body></ns0:document>`,
+			`<ns0:document xmlns:ns0="` + wordNamespace + `"><ns0:body><ns0:p><ns0:r><ns0:t>[销售报告]</ns0:t></ns0:r><ns0:r><ns0:t></ns0:t></ns0:r></ns0:p></ns0:body></ns0:document>`,
+		},
+		{
+			"strict shared strings",
+			"xl/sharedStrings.xml",
+			`<sst xmlns="` + sheetStrictNamespace + `"><si><t>收入</t></si></sst>`,
+			`<sst xmlns="` + sheetStrictNamespace + `"><si><t>[收入]</t></si></sst>`,
+		},
+		{
+			"prefixed shared strings",
+			"xl/sharedStrings.xml",
+			`<x:sst xmlns:x="` + sheetStrictNamespace + `"><x:si><x:r><x:t>收</x:t></x:r><x:r><x:t>入</x:t></x:r></x:si></x:sst>`,
+			`<x:sst xmlns:x="` + sheetStrictNamespace + `"><x:si><x:r><x:t>[收入]</x:t></x:r><x:r><x:t></x:t></x:r></x:si></x:sst>`,
+		},
+		{
+			"strict drawing",
+			"xl/drawings/drawing1.xml",
+			`<xdr:wsDr xmlns:xdr="http://purl.oclc.org/ooxml/drawingml/spreadsheetDrawing" xmlns:dml="` + drawingStrictNamespace + `"><xdr:sp><xdr:txBody><dml:p><dml:r><dml:t>图表</dml:t></dml:r></dml:p></xdr:txBody></xdr:sp></xdr:wsDr>`,
+			`<xdr:wsDr xmlns:xdr="http://purl.oclc.org/ooxml/drawingml/spreadsheetDrawing" xmlns:dml="` + drawingStrictNamespace + `"><xdr:sp><xdr:txBody><dml:p><dml:r><dml:t>[图表]</dml:t></dml:r></dml:p></xdr:txBody></xdr:sp></xdr:wsDr>`,
+		},
+	}
+	for _, tt := range tests {
+		texts, got := translatePart(t, NewExtractor(ExtractorConfig{}), tt.part, tt.content, bracket)
+		if len(texts) != 1 {
+			t.Errorf("%s: texts = %q, want one", tt.name, texts)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+
+	// Elements of another namespace using the conventional prefix are not text
+	other := `<w:document xmlns:w="http://example.com/other" xmlns:doc="` + wordNamespace + `"><w:t>不是文本</w:t><doc:t>文本</doc:t></w:document>`
+	if texts, _ := translatePart(t, NewExtractor(ExtractorConfig{}), "word/document.xml", other, bracket); !slices.Equal(texts, []string{"文本"}) {
+		t.Errorf("texts = %q, want only the text of the Word namespace", texts)
+	}
+}
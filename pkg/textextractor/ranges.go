@@ -0,0 +1,192 @@
+package textextractor
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Size of a worksheet, the bounds of whole columns and rows.
+const (
+	maxColumns = 16384   // Columns A to XFD
+	maxRows    = 1048576 // Rows 1 to 1048576
+)
+
+// CellRange is a rectangle of worksheet cells selected for translation, see ParseRanges.
+// Columns and rows are zero-based and inclusive.
+type CellRange struct {
+	Sheet            string // Sheet name, "" for every sheet
+	FromCol, FromRow int
+	ToCol, ToRow     int
+}
+
+// Ranges returns the ranges of workbook cells to translate as configured, see ParseRanges;
+// empty translates all cells. Like CellStyle, Extract does not apply them.
+func (e *Extractor) Ranges() []string {
+	return e.config.Ranges
+}
+
+// Contains reports whether the cell at the reference (e.g. "B3") of the sheet is in the range.
+func (r CellRange) Contains(sheet, ref string) bool {
+	if r.Sheet != "" && !strings.EqualFold(r.Sheet, sheet) {
+		return false
+	}
+	col, row, ok := CellPosition(ref)
+	return ok && col >= r.FromCol && col <= r.ToCol && row >= r.FromRow && row <= r.ToRow
+}
+
+// ParseRanges parses the ranges of cells to translate, given like in formulas: cells ("B2"),
+// areas ("B2:B999"), columns ("B", "B:D") or rows ("1", "1:3"), optionally preceded by a sheet
+// name ("Data!B:B", "'My sheet'!A1:C3"). A sheet name alone ("Data") selects the whole sheet and
+// is taken as a name rather than a column when the workbook has such a sheet; sheets lists the
+// sheet names of the workbook.
+func ParseRanges(specs []string, sheets []string) ([]CellRange, error) {
+	var ranges []CellRange
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		if !strings.Contains(spec, "!") && containsFold(sheets, spec) {
+			ranges = append(ranges, CellRange{Sheet: spec, ToCol: maxColumns - 1, ToRow: maxRows - 1})
+			continue
+		}
+
+		r := CellRange{ToCol: maxColumns - 1, ToRow: maxRows - 1}
+		area := spec
+		if i := strings.LastIndex(spec, "!"); i >= 0 {
+			r.Sheet, area = unquoteSheetName(spec[:i]), spec[i+1:]
+			if r.Sheet == "" {
+				return nil, fmt.Errorf("invalid cell range %q: empty sheet name", spec)
+			}
+			if !containsFold(sheets, r.Sheet) {
+				return nil, fmt.Errorf("invalid cell range %q: no sheet named %s", spec, r.Sheet)
+			}
+		}
+		if area != "" && !parseArea(area, &r) {
+			return nil, fmt.Errorf("invalid cell range %q: expected cells, columns or rows such as B2:B9, B:D or 1:3, or a sheet name", spec)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// parseArea sets the bounds of r from an area such as "B2:C9", "B:C" or "2:9", whose ends
+// must be of the same kind. Absolute references ("$B$2") are accepted.
+func parseArea(area string, r *CellRange) bool {
+	from, to, found := strings.Cut(strings.ToUpper(strings.ReplaceAll(area, "$", "")), ":")
+	if !found {
+		to = from
+	}
+	fromCol, fromRow, ok1 := parseAreaEnd(from)
+	toCol, toRow, ok2 := parseAreaEnd(to)
+	if !ok1 || !ok2 || (fromCol < 0) != (toCol < 0) || (fromRow < 0) != (toRow < 0) {
+		return false
+	}
+	if fromCol >= 0 {
+		r.FromCol, r.ToCol = min(fromCol, toCol), max(fromCol, toCol)
+	}
+	if fromRow >= 0 {
+		r.FromRow, r.ToRow = min(fromRow, toRow), max(fromRow, toRow)
+	}
+	return true
+}
+
+// parseAreaEnd parses one end of an area: a cell ("B2"), a column ("B") or a row ("2").
+// It returns -1 for the part that is not given.
+func parseAreaEnd(end string) (col, row int, ok bool) {
+	i := strings.IndexFunc(end, func(r rune) bool { return r < 'A' || r > 'Z' })
+	if i < 0 {
+		i = len(end)
+	}
+	letters, digits := end[:i], end[i:]
+	col, row = -1, -1
+	if letters != "" {
+		if col = columnIndex(letters); col < 0 {
+			return 0, 0, false
+		}
+	}
+	if digits != "" {
+		if row = rowIndex(digits); row < 0 {
+			return 0, 0, false
+		}
+	}
+	return col, row, letters != "" || digits != ""
+}
+
+// columnIndex returns the zero-based index of a column given by its letters, or -1 if it is
+// beyond the last column.
+func columnIndex(letters string) int {
+	col := 0
+	for _, c := range letters {
+		col = col*26 + int(c-'A'+1)
+		if col > maxColumns {
+			return -1
+		}
+	}
+	return col - 1
+}
+
+// rowIndex returns the zero-based index of a row given by its 1-based number, or -1 if it is
+// not a valid row number.
+func rowIndex(digits string) int {
+	row := 0
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return -1
+		}
+		row = row*10 + int(c-'0')
+		if row > maxRows {
+			return -1
+		}
+	}
+	return row - 1
+}
+
+// unquoteSheetName removes the quotes around a sheet name of a reference, e.g. 'My sheet'.
+func unquoteSheetName(name string) string {
+	if len(name) >= 2 && name[0] == '\'' && name[len(name)-1] == '\'' {
+		return strings.ReplaceAll(name[1:len(name)-1], "''", "'")
+	}
+	return name
+}
+
+// containsFold reports whether names contains name, ignoring case as Excel does for sheet names.
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// relIDAttrRegex matches the relationship id attribute (r:id) of an element, capturing the id.
+var relIDAttrRegex = regexp.MustCompile(`\s\w+:id="([^"]*)"`)
+
+// WorkbookSheet is a sheet listed in a workbook.
+type WorkbookSheet struct {
+	Name  string
+	RelID string // Relationship id of the worksheet part in the workbook's relationships
+	State string // Visibility: "" for visible, "hidden" or "veryHidden"
+}
+
+// stateAttrRegex matches the visibility of a sheet in a workbook, capturing it.
+var stateAttrRegex = regexp.MustCompile(`\sstate="([^"]*)"`)
+
+// WorkbookSheets returns the sheets of a workbook (xl/workbook.xml) in order.
+func WorkbookSheets(content string) []WorkbookSheet {
+	var sheets []WorkbookSheet
+	for _, m := range sheetNameRegex(content).FindAllStringSubmatch(content, -1) {
+		sheet := WorkbookSheet{Name: html.UnescapeString(m[1])}
+		if id := relIDAttrRegex.FindStringSubmatch(m[0]); id != nil {
+			sheet.RelID = html.UnescapeString(id[1])
+		}
+		if state := stateAttrRegex.FindStringSubmatch(m[0]); state != nil && state[1] != "visible" {
+			sheet.State = state[1]
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets
+}
@@ -0,0 +1,84 @@
+package textextractor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRanges(t *testing.T) {
+	sheets := []string{"Data", "My sheet", "B"}
+	tests := []struct {
+		spec string
+		want CellRange
+	}{
+		{"B2", CellRange{FromCol: 1, FromRow: 1, ToCol: 1, ToRow: 1}},
+		{"B2:C9", CellRange{FromCol: 1, FromRow: 1, ToCol: 2, ToRow: 8}},
+		{"C9:B2", CellRange{FromCol: 1, FromRow: 1, ToCol: 2, ToRow: 8}},
+		{"$B$2:$C$9", CellRange{FromCol: 1, FromRow: 1, ToCol: 2, ToRow: 8}},
+		{"b2:c9", CellRange{FromCol: 1, FromRow: 1, ToCol: 2, ToRow: 8}},
+		{"D", CellRange{FromCol: 3, ToCol: 3, ToRow: maxRows - 1}},
+		{"B:D", CellRange{FromCol: 1, ToCol: 3, ToRow: maxRows - 1}},
+		{"3", CellRange{FromRow: 2, ToCol: maxColumns - 1, ToRow: 2}},
+		{"1:3", CellRange{ToCol: maxColumns - 1, ToRow: 2}},
+		{"XFD1048576", CellRange{FromCol: maxColumns - 1, FromRow: maxRows - 1, ToCol: maxColumns - 1, ToRow: maxRows - 1}},
+		{"Data!B:B", CellRange{Sheet: "Data", FromCol: 1, ToCol: 1, ToRow: maxRows - 1}},
+		{"'My sheet'!A1", CellRange{Sheet: "My sheet", ToCol: 0, ToRow: 0}},
+		{"Data", CellRange{Sheet: "Data", ToCol: maxColumns - 1, ToRow: maxRows - 1}},
+		{"data", CellRange{Sheet: "data", ToCol: maxColumns - 1, ToRow: maxRows - 1}},
+		{"B", CellRange{Sheet: "B", ToCol: maxColumns - 1, ToRow: maxRows - 1}}, // The sheet, not the column
+		{"Data!", CellRange{Sheet: "Data", ToCol: maxColumns - 1, ToRow: maxRows - 1}},
+		{" A:A ", CellRange{ToCol: 0, ToRow: maxRows - 1}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRanges([]string{tt.spec}, sheets)
+		if err != nil {
+			t.Errorf("ParseRanges(%q): %v", tt.spec, err)
+			continue
+		}
+		if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+			t.Errorf("ParseRanges(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+
+	if got, err := ParseRanges([]string{"", "  "}, sheets); err != nil || len(got) != 0 {
+		t.Errorf("ParseRanges of empty specs = %+v, %v, want none", got, err)
+	}
+}
+
+func TestParseRangesErrors(t *testing.T) {
+	for _, spec := range []string{
+		"!A1",       // Empty sheet name
+		"Other!A1",  // Unknown sheet
+		"B2:C",      // Ends of different kinds
+		"B:3",       // Ends of different kinds
+		"XFE1",      // Beyond the last column
+		"A1048577",  // Beyond the last row
+		"A0",        // Rows start at 1
+		"A1:B2:C3",  // Three ends
+		"Revenue 1", // Not a sheet of the workbook
+	} {
+		if got, err := ParseRanges([]string{spec}, []string{"Data"}); err == nil {
+			t.Errorf("ParseRanges(%q) = %+v, want an error", spec, got)
+		}
+	}
+}
+
+func TestCellRangeContains(t *testing.T) {
+	r := CellRange{Sheet: "Data", FromCol: 1, FromRow: 1, ToCol: 2, ToRow: 8}
+	tests := []struct {
+		sheet, ref string
+		want       bool
+	}{
+		{"Data", "B2", true},
+		{"data", "C9", true},
+		{"Data", "A2", false},
+		{"Data", "B10", false},
+		{"Other", "B2", false},
+		{"Data", "not a ref", false},
+	}
+	for _, tt := range tests {
+		if got := r.Contains(tt.sheet, tt.ref); got != tt.want {
+			t.Errorf("Contains(%q, %q) = %v, want %v", tt.sheet, tt.ref, got, tt.want)
+		}
+	}
+}
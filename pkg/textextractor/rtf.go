@@ -0,0 +1,351 @@
+package textextractor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// IsRTF reports whether the file name is an RTF document, which is processed
+// as a single file instead of a zip archive.
+func IsRTF(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".rtf")
+}
+
+// rtfSkipDestinations are destination groups that do not contain document text.
+var rtfSkipDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true, "pict": true,
+	"object": true, "themedata": true, "colorschememapping": true, "datastore": true,
+	"latentstyles": true, "listtable": true, "listoverridetable": true, "rsidtbl": true,
+	"generator": true, "xmlnstbl": true, "mmathPr": true, "fldinst": true, "filetbl": true,
+	"revtbl": true, "pgdsctbl": true, "fonttable": true,
+}
+
+// rtfCharWords are control words that stand for a character of the text.
+var rtfCharWords = map[string]string{
+	"tab": "\t", "line": "\n", "emdash": "—", "endash": "–", "emspace": "\u2003", "enspace": "\u2002",
+	"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”", "bullet": "•",
+}
+
+// rtfControlSymbols are control symbols that stand for a character of the text.
+var rtfControlSymbols = map[string]string{
+	"~": "\u00a0", // Non-breaking space
+	"-": "",       // Optional hyphen
+	"_": "\u2011", // Non-breaking hyphen
+}
+
+// rtfSpan accumulates the decoded text of a run of text tokens.
+type rtfSpan struct {
+	start, end int
+	text       strings.Builder
+	raw        []byte // Pending literal and \'hh bytes in the document codepage
+	surrogate  rune   // Pending high surrogate of a \u pair
+}
+
+// extractRTF finds the runs of plain text in an RTF document. Any control word other than
+// the character words above (formatting changes, \par, \cell) ends a run, as do group boundaries.
+func (e *Extractor) extractRTF(content string) []ExtractionItem {
+	var items []ExtractionItem
+	decoder := rtfDecoder(1252)
+
+	ucStack := []int{1} // Number of fallback characters after \uN, scoped to groups
+	var span *rtfSpan
+
+	open := func(i int) {
+		if span == nil {
+			span = &rtfSpan{start: i}
+		}
+	}
+	flushRaw := func() {
+		if span == nil || len(span.raw) == 0 {
+			return
+		}
+		decoded, err := decoder.Bytes(span.raw)
+		if err != nil {
+			decoded = span.raw
+		}
+		span.text.Write(decoded)
+		span.raw = span.raw[:0]
+	}
+	flush := func() {
+		if span == nil {
+			return
+		}
+		flushRaw()
+		if item, ok := e.newRTFItem(span); ok {
+			items = append(items, item)
+		}
+		span = nil
+	}
+	appendText := func(s string) {
+		flushRaw()
+		span.text.WriteString(s)
+	}
+
+	for i := 0; i < len(content); {
+		switch c := content[i]; c {
+		case '{':
+			flush()
+			if end, ok := skipRTFDestination(content, i); ok {
+				i = end
+				continue
+			}
+			ucStack = append(ucStack, ucStack[len(ucStack)-1])
+			i++
+		case '}':
+			flush()
+			if len(ucStack) > 1 {
+				ucStack = ucStack[:len(ucStack)-1]
+			}
+			i++
+		case '\r', '\n':
+			// Line breaks in the source are not part of the text
+			i++
+		case '\\':
+			word, param, hasParam, next := readRTFControl(content, i)
+			switch {
+			case word == "'" && next-i == 4:
+				open(i)
+				if b, err := strconv.ParseUint(content[i+2:i+4], 16, 8); err == nil {
+					span.raw = append(span.raw, byte(b))
+				}
+				span.end = next
+			case word == "\\" || word == "{" || word == "}":
+				open(i)
+				span.raw = append(span.raw, word[0])
+				span.end = next
+			case word == "~" || word == "-" || word == "_":
+				open(i)
+				appendText(rtfControlSymbols[word])
+				span.end = next
+			case word == "u" && hasParam:
+				open(i)
+				r := rune(param)
+				if r < 0 {
+					r += 65536
+				}
+				flushRaw()
+				if utf16.IsSurrogate(r) && span.surrogate == 0 && r < 0xDC00 {
+					span.surrogate = r
+				} else {
+					if span.surrogate != 0 {
+						r = utf16.DecodeRune(span.surrogate, r)
+						span.surrogate = 0
+					}
+					span.text.WriteRune(r)
+				}
+				// Skip the fallback characters for readers without Unicode support
+				next = skipRTFFallback(content, next, ucStack[len(ucStack)-1])
+				span.end = next
+			case rtfCharWords[word] != "":
+				open(i)
+				appendText(rtfCharWords[word])
+				span.end = next
+			default:
+				flush()
+				switch word {
+				case "ansicpg":
+					decoder = rtfDecoder(param)
+				case "uc":
+					ucStack[len(ucStack)-1] = max(param, 0)
+				case "bin":
+					next = min(next+max(param, 0), len(content))
+				}
+			}
+			i = next
+		default:
+			open(i)
+			span.raw = append(span.raw, c)
+			i++
+			span.end = i
+		}
+	}
+	flush()
+	return items
+}
+
+// newRTFItem builds an ExtractionItem from a run of RTF text. Surrounding whitespace is kept
+// out of the translation and reattached afterwards.
+func (e *Extractor) newRTFItem(span *rtfSpan) (ExtractionItem, bool) {
+	text := span.text.String()
+	if !IsValidTextContent(text) {
+		return ExtractionItem{}, false
+	}
+	if e.skipLanguage(text) {
+		return ExtractionItem{}, false
+	}
+
+	return splitEdgeSpace(ExtractionItem{
+		Text:       text,
+		MatchStart: span.start,
+		MatchEnd:   span.end,
+		TextStart:  span.start,
+		TextEnd:    span.end,
+	}), true
+}
+
+// readRTFControl reads the control word or control symbol starting at the backslash at i.
+// It returns the word (or the symbol character), its numeric parameter and the index after it,
+// including the space delimiter of a control word.
+func readRTFControl(content string, i int) (word string, param int, hasParam bool, next int) {
+	j := i + 1
+	if j >= len(content) {
+		return "", 0, false, j
+	}
+	if !isASCIILetter(content[j]) {
+		// Control symbol; \'hh carries two hex digits
+		if content[j] == '\'' && j+3 <= len(content) {
+			return "'", 0, false, j + 3
+		}
+		return content[j : j+1], 0, false, j + 1
+	}
+
+	for j < len(content) && isASCIILetter(content[j]) {
+		j++
+	}
+	word = content[i+1 : j]
+
+	k := j
+	if k < len(content) && content[k] == '-' {
+		k++
+	}
+	for k < len(content) && content[k] >= '0' && content[k] <= '9' {
+		k++
+	}
+	if digits := content[j:k]; digits != "" && digits != "-" {
+		param, _ = strconv.Atoi(digits)
+		hasParam = true
+		j = k
+	}
+	if j < len(content) && content[j] == ' ' {
+		j++
+	}
+	return word, param, hasParam, j
+}
+
+// skipRTFFallback skips n fallback characters after a \uN control word.
+func skipRTFFallback(content string, i int, n int) int {
+	for ; n > 0 && i < len(content); n-- {
+		switch content[i] {
+		case '{', '}':
+			return i
+		case '\\':
+			_, _, _, i = readRTFControl(content, i)
+		default:
+			i++
+		}
+	}
+	return i
+}
+
+// skipRTFDestination returns the index after the group opened at i if the group is
+// an ignorable destination ({\*...}) or a destination without document text.
+func skipRTFDestination(content string, i int) (int, bool) {
+	j := i + 1
+	for j < len(content) && (content[j] == '\r' || content[j] == '\n') {
+		j++
+	}
+	if j >= len(content) || content[j] != '\\' {
+		return 0, false
+	}
+	word, _, _, _ := readRTFControl(content, j)
+	if word != "*" && !rtfSkipDestinations[word] {
+		return 0, false
+	}
+
+	depth := 0
+	for ; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++ // Skip the escaped character
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		}
+	}
+	return len(content), true
+}
+
+// encodeRTF encodes text for an RTF document: special characters are escaped and
+// characters outside ASCII are written as \uN? escapes (one fallback character, as with \uc1).
+func encodeRTF(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '{' || r == '}':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString(`\line `)
+		case r == '\t':
+			sb.WriteString(`\tab `)
+		case r >= 0x20 && r < 0x7f:
+			sb.WriteRune(r)
+		case r < 0x20:
+			// Other control characters cannot be represented
+		default:
+			units := []rune{r}
+			if r > 0xFFFF {
+				r1, r2 := utf16.EncodeRune(r)
+				units = []rune{r1, r2}
+			}
+			for _, u := range units {
+				fmt.Fprintf(&sb, `\u%d?`, int16(uint16(u)))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// rtfDecoder returns the decoder for an ANSI codepage given by \ansicpg.
+func rtfDecoder(codepage int) *encoding.Decoder {
+	var enc encoding.Encoding
+	switch codepage {
+	case 936:
+		enc = simplifiedchinese.GBK
+	case 950:
+		enc = traditionalchinese.Big5
+	case 932:
+		enc = japanese.ShiftJIS
+	case 949:
+		enc = korean.EUCKR
+	case 874:
+		enc = charmap.Windows874
+	case 1250:
+		enc = charmap.Windows1250
+	case 1251:
+		enc = charmap.Windows1251
+	case 1253:
+		enc = charmap.Windows1253
+	case 1254:
+		enc = charmap.Windows1254
+	case 1255:
+		enc = charmap.Windows1255
+	case 1256:
+		enc = charmap.Windows1256
+	case 1257:
+		enc = charmap.Windows1257
+	case 1258:
+		enc = charmap.Windows1258
+	default:
+		enc = charmap.Windows1252
+	}
+	return enc.NewDecoder()
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
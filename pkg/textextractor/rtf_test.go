@@ -0,0 +1,81 @@
+package textextractor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractRTF(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"plain runs", `{\rtf1\ansi Hello world\par Second line}`, []string{"Hello world", "Second line"}},
+		{"skipped destinations", `{\rtf1{\fonttbl{\f0 Arial;}}{\*\generator Writer;}{\info{\title Title}}Body}`, []string{"Body"}},
+		{"escaped characters", `{\rtf1 a \{b\} c\\d}`, []string{`a {b} c\d`}},
+		{"codepage", `{\rtf1\ansi\ansicpg936 \'ca\'d5\'c8\'eb}`, []string{"收入"}},
+		{"default codepage", `{\rtf1 caf\'e9}`, []string{"café"}},
+		{"unicode with fallback", `{\rtf1 \u25910?\u20837?}`, []string{"收入"}},
+		{"negative unicode", `{\rtf1 \u-3913?}`, []string{"\uf0b7"}},
+		{"uc2 fallback", `{\rtf1\uc2 \u25910\'ca\'d5\u20837\'c8\'eb}`, []string{"收入"}},
+		{"surrogate pair", `{\rtf1 ok \u-10179?\u-8704?}`, []string{"ok 😀"}},
+		{"character words", `{\rtf1 a\tab b\line c\emdash d\~e}`, []string{"a\tb\nc—d e"}},
+		{"source line breaks", "{\\rtf1 one\r\ntwo}", []string{"onetwo"}},
+		{"numbers only", `{\rtf1 2024\par Total}`, []string{"Total"}},
+	}
+	e := NewExtractor(ExtractorConfig{})
+	for _, tt := range tests {
+		_, items, err := e.Extract(tt.content, "doc.rtf")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, item := range items {
+			got = append(got, item.Text)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: texts = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeRTF(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Revenue", "Revenue"},
+		{`a {b} c\d`, `a \{b\} c\\d`},
+		{"a\tb\nc", `a\tab b\line c`},
+		{"收入", `\u25910?\u20837?`},
+		{"\uf0b7", `\u-3913?`},
+		{"😀", `\u-10179?\u-8704?`},
+		{"bell\a", "bell"},
+	}
+	for _, tt := range tests {
+		if got := encodeRTF(tt.text); got != tt.want {
+			t.Errorf("encodeRTF(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRTF(t *testing.T) {
+	content := `{\rtf1\ansi{\fonttbl{\f0 Arial;}}\f0 \u25910?\u20837?\par {\b  Cost }}`
+	e := NewExtractor(ExtractorConfig{})
+	content, items, err := e.Extract(content, "doc.rtf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	got, err := e.Apply(content, "doc.rtf", items, []string{"Income {net}", "成本"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{\rtf1\ansi{\fonttbl{\f0 Arial;}}\f0 Income \{net\}\par {\b  \u25104?\u26412? }}`
+	if got != want {
+		t.Errorf("Apply =\n%s\nwant\n%s", got, want)
+	}
+}
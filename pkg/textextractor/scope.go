@@ -0,0 +1,26 @@
+package textextractor
+
+// Scope presets limit which texts of a workbook are translated, see ApplyScope.
+const (
+	ScopeAll              = "all"                // Everything the configuration enables (default)
+	ScopeCellsOnly        = "cells_only"         // Cell text only
+	ScopeCellsAndComments = "cells_and_comments" // Cell text and comments
+	ScopeTextOnly         = "text_only"          // Cell text, comments, shapes and text boxes, but no names
+)
+
+// ApplyScope returns config with the filters of a scope preset set. Scopes only restrict what
+// config enables; an empty or unknown scope returns config unchanged.
+func ApplyScope(config ExtractorConfig, scope string) ExtractorConfig {
+	switch scope {
+	case ScopeCellsOnly:
+		config.SkipSheetNames, config.SkipShapes, config.SkipComments = true, true, true
+		config.DefinedNames = false
+	case ScopeCellsAndComments:
+		config.SkipSheetNames, config.SkipShapes = true, true
+		config.DefinedNames = false
+	case ScopeTextOnly:
+		config.SkipSheetNames = true
+		config.DefinedNames = false
+	}
+	return config
+}
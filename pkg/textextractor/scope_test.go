@@ -0,0 +1,56 @@
+package textextractor
+
+import (
+	"slices"
+	"testing"
+)
+
+// scopeParts are workbook parts of every phase.
+var scopeParts = []string{
+	"xl/workbook.xml",
+	"xl/sharedStrings.xml",
+	"xl/comments1.xml",
+	"xl/threadedComments/threadedComment1.xml",
+	"xl/drawings/drawing1.xml",
+	"xl/charts/chart1.xml",
+}
+
+func TestApplyScope(t *testing.T) {
+	all := scopeParts
+	tests := []struct {
+		scope  string
+		config ExtractorConfig
+		parts  []string
+		phases []string
+	}{
+		{ScopeAll, ExtractorConfig{DefinedNames: true}, all, []string{PhaseSheet, PhaseCell, PhaseShape}},
+		{"", ExtractorConfig{DefinedNames: true}, all, []string{PhaseSheet, PhaseCell, PhaseShape}},
+		{"unknown", ExtractorConfig{DefinedNames: true}, all, []string{PhaseSheet, PhaseCell, PhaseShape}},
+		{ScopeCellsOnly, ExtractorConfig{DefinedNames: true}, []string{"xl/sharedStrings.xml"}, []string{PhaseCell}},
+		{ScopeCellsAndComments, ExtractorConfig{DefinedNames: true},
+			[]string{"xl/sharedStrings.xml", "xl/comments1.xml", "xl/threadedComments/threadedComment1.xml"}, []string{PhaseCell}},
+		{ScopeTextOnly, ExtractorConfig{DefinedNames: true},
+			[]string{"xl/sharedStrings.xml", "xl/comments1.xml", "xl/threadedComments/threadedComment1.xml", "xl/drawings/drawing1.xml", "xl/charts/chart1.xml"},
+			[]string{PhaseCell, PhaseShape}},
+		// Scopes do not enable what the configuration skips
+		{ScopeCellsAndComments, ExtractorConfig{SkipComments: true}, []string{"xl/sharedStrings.xml"}, []string{PhaseCell}},
+		{ScopeTextOnly, ExtractorConfig{SkipShapes: true},
+			[]string{"xl/sharedStrings.xml", "xl/comments1.xml", "xl/threadedComments/threadedComment1.xml"}, []string{PhaseCell}},
+	}
+	for _, tt := range tests {
+		e := NewExtractor(ApplyScope(tt.config, tt.scope))
+		var parts, phases []string
+		for _, name := range scopeParts {
+			if !e.Supports(name) {
+				continue
+			}
+			parts = append(parts, name)
+			if phase := Phase(name); !slices.Contains(phases, phase) {
+				phases = append(phases, phase)
+			}
+		}
+		if !slices.Equal(parts, tt.parts) || !slices.Equal(phases, tt.phases) {
+			t.Errorf("scope %q with %+v: got parts %q in phases %q, want %q in %q", tt.scope, tt.config, parts, phases, tt.parts, tt.phases)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package textextractor
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// Elements holding formulas: cells, conditional formats, data validations, defined names,
+	// table columns and chart series
+	formulaElementRegex = regexp.MustCompile(`(?s)(<(?:\w+:)?(?:f|formula|formula1|formula2|definedName|calculatedColumnFormula|totalsRowFormula)(?:\s[^>]*[^/>])?>)([^<]*)(</)`)
+	// Internal hyperlinks and pivot cache sources refer to sheets in attributes
+	hyperlinkRegex       = regexp.MustCompile(`<(?:\w+:)?hyperlink\b[^>]*>`)
+	worksheetSourceRegex = regexp.MustCompile(`<(?:\w+:)?worksheetSource\b[^>]*>`)
+	locationAttrRegex    = regexp.MustCompile(`(\slocation=")([^"]*)(")`)
+	sheetAttrRegex       = regexp.MustCompile(`(\ssheet=")([^"]*)(")`)
+
+	// Sheet names that can be written in formulas without quotes; cell references are excluded below
+	plainSheetNameRegex = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_.]*$`)
+	cellLikeNameRegex   = regexp.MustCompile(`^(?i:[a-z]{1,3}\d+|r\d*c\d*|r|c|true|false)$`)
+)
+
+// formulaEscaper escapes rewritten formulas, keeping the apostrophes of quoted sheet names readable.
+var formulaEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// Characters Excel does not allow in sheet names.
+var invalidSheetNameChars = strings.NewReplacer(
+	"[", "_", "]", "_", ":", "_", "*", "_", "?", "_", "/", "_", "\\", "_",
+)
+
+// reservedSheetNames are names Excel refuses for sheets, lower-cased.
+var reservedSheetNames = map[string]bool{"history": true}
+
+// sheetNameRegex returns the regex matching the sheet elements of a workbook, capturing the name.
+func sheetNameRegex(content string) *regexp.Regexp {
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	return elementRegex(x, `<%sheet\b[^>]*?\sname="([^"]+?)"[^>]*?>`)
+}
+
+// sheetNames returns the names of the sheets of a workbook in order.
+func sheetNames(content string) []string {
+	var names []string
+	for _, m := range sheetNameRegex(content).FindAllStringSubmatch(content, -1) {
+		names = append(names, html.UnescapeString(m[1]))
+	}
+	return names
+}
+
+// SheetRenames compares a workbook before and after translation and returns the new name
+// of every renamed sheet, keyed by the lower-cased old name.
+func SheetRenames(before, after string) map[string]string {
+	renamed := RenamedSheets(before, after)
+	if renamed == nil {
+		return nil
+	}
+	renames := make(map[string]string, len(renamed))
+	for name, newName := range renamed {
+		renames[strings.ToLower(name)] = newName
+	}
+	return renames
+}
+
+// RenamedSheets is like SheetRenames but keys the new names by the old names as they are
+// written, e.g. for showing them. It returns nil if the workbooks have different sheets.
+func RenamedSheets(before, after string) map[string]string {
+	oldNames, newNames := sheetNames(before), sheetNames(after)
+	if len(oldNames) != len(newNames) {
+		return nil
+	}
+	renamed := make(map[string]string)
+	for i, name := range oldNames {
+		if newNames[i] != name {
+			renamed[name] = newNames[i]
+		}
+	}
+	return renamed
+}
+
+// HasSheetRefs reports whether an internal file of a workbook may refer to sheets by name.
+func HasSheetRefs(name string) bool {
+	return strings.Contains(name, "xl/workbook.xml") ||
+		strings.Contains(name, "xl/worksheets/") ||
+		strings.Contains(name, "xl/charts/") ||
+		strings.Contains(name, "xl/pivotCache/") ||
+		strings.Contains(name, "xl/tables/")
+}
+
+// RenameSheetRefs updates references to renamed sheets in formulas, defined names, internal
+// hyperlinks and pivot cache sources. renames is the result of SheetRenames.
+func RenameSheetRefs(content string, renames map[string]string) string {
+	if len(renames) == 0 {
+		return content
+	}
+
+	content = formulaElementRegex.ReplaceAllStringFunc(content, func(element string) string {
+		m := formulaElementRegex.FindStringSubmatch(element)
+		formula := html.UnescapeString(m[2])
+		renamed := renameFormulaRefs(formula, renames)
+		if renamed == formula {
+			return element
+		}
+		return m[1] + formulaEscaper.Replace(renamed) + element[len(m[1])+len(m[2]):]
+	})
+
+	replaceAttr := func(element *regexp.Regexp, attr *regexp.Regexp, rename func(string) string) {
+		content = element.ReplaceAllStringFunc(content, func(tag string) string {
+			return attr.ReplaceAllStringFunc(tag, func(a string) string {
+				m := attr.FindStringSubmatch(a)
+				value := html.UnescapeString(m[2])
+				renamed := rename(value)
+				if renamed == value {
+					return a
+				}
+				return m[1] + formulaEscaper.Replace(renamed) + m[3]
+			})
+		})
+	}
+	replaceAttr(hyperlinkRegex, locationAttrRegex, func(location string) string {
+		return renameFormulaRefs(location, renames)
+	})
+	replaceAttr(worksheetSourceRegex, sheetAttrRegex, func(sheet string) string {
+		if renamed, ok := renames[strings.ToLower(sheet)]; ok {
+			return renamed
+		}
+		return sheet
+	})
+	return content
+}
+
+// renameFormulaRefs rewrites the sheet references (Sheet1!A1, 'My sheet'!A1, Sheet1:Sheet3!A1)
+// of a formula. String literals and references to other workbooks are left alone.
+func renameFormulaRefs(formula string, renames map[string]string) string {
+	var sb strings.Builder
+	for i := 0; i < len(formula); {
+		c := formula[i]
+		switch {
+		case c == '"':
+			end := closingQuote(formula, i, '"')
+			sb.WriteString(formula[i:end])
+			i = end
+		case c == '\'':
+			end := closingQuote(formula, i, '\'')
+			if end < len(formula) && formula[end] == '!' {
+				names := strings.ReplaceAll(formula[i+1:end-1], "''", "'")
+				sb.WriteString(renameSheetRef(names, formula[i:end], renames))
+			} else {
+				sb.WriteString(formula[i:end])
+			}
+			i = end
+		case isSheetNameChar(formula[i:]) && !followsName(formula[:i]):
+			end := i
+			for end < len(formula) && isSheetNameChar(formula[end:]) {
+				_, size := utf8.DecodeRuneInString(formula[end:])
+				end += size
+			}
+			// Second sheet of a 3D reference
+			if end < len(formula) && formula[end] == ':' {
+				next := end + 1
+				for next < len(formula) && isSheetNameChar(formula[next:]) {
+					_, size := utf8.DecodeRuneInString(formula[next:])
+					next += size
+				}
+				if next > end+1 && next < len(formula) && formula[next] == '!' {
+					end = next
+				}
+			}
+			if end < len(formula) && formula[end] == '!' {
+				sb.WriteString(renameSheetRef(formula[i:end], formula[i:end], renames))
+			} else {
+				sb.WriteString(formula[i:end])
+			}
+			i = end
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// renameSheetRef returns the reference to the sheet (or sheet range of a 3D reference) names
+// after renaming, or original if no sheet in it was renamed.
+func renameSheetRef(names, original string, renames map[string]string) string {
+	// External references such as [1]Sheet1 or 'C:\dir\[Book.xlsx]Sheet1' name another workbook
+	if strings.ContainsAny(names, "[]") {
+		return original
+	}
+
+	parts := strings.Split(names, ":")
+	changed := false
+	for j, name := range parts {
+		if renamed, ok := renames[strings.ToLower(name)]; ok {
+			parts[j] = renamed
+			changed = true
+		}
+	}
+	if !changed {
+		return original
+	}
+
+	quote := false
+	for _, name := range parts {
+		if !plainSheetNameRegex.MatchString(name) || cellLikeNameRegex.MatchString(name) {
+			quote = true
+		}
+	}
+	ref := strings.Join(parts, ":")
+	if quote {
+		ref = "'" + strings.ReplaceAll(ref, "'", "''") + "'"
+	}
+	return ref
+}
+
+// closingQuote returns the index after the quoted section starting at i. A doubled quote
+// character is an escaped quote.
+func closingQuote(s string, i int, quote byte) int {
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != quote {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == quote {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(s)
+}
+
+// followsName reports whether the formula text before a position ends within a name or
+// an external workbook reference ("[1]"), so the position does not start a sheet name.
+func followsName(before string) bool {
+	r, _ := utf8.DecodeLastRuneInString(before)
+	return r == ']' || r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isSheetNameChar reports whether s starts with a character of an unquoted sheet name.
+func isSheetNameChar(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// sheetNameFixer makes translated sheet names valid and unique within a workbook.
+type sheetNameFixer struct {
+	used map[string]bool // Lower-cased names already taken
+}
+
+// newSheetNameFixer returns a fixer for a workbook whose sheet names are about to be replaced.
+// The names of the sheets that keep their name are taken from the start.
+func newSheetNameFixer(content string, items []ExtractionItem, translations []string) *sheetNameFixer {
+	renamed := make(map[int]bool)
+	for i, item := range items {
+		if translations[i] != item.Text && !item.constant {
+			renamed[item.MatchStart] = true
+		}
+	}
+	used := make(map[string]bool)
+	for name := range reservedSheetNames {
+		used[name] = true
+	}
+	re := sheetNameRegex(content)
+	for _, m := range re.FindAllStringSubmatchIndex(content, -1) {
+		if !renamed[m[0]] {
+			used[strings.ToLower(html.UnescapeString(content[m[2]:m[3]]))] = true
+		}
+	}
+	return &sheetNameFixer{used: used}
+}
+
+// fix replaces characters Excel does not allow, enforces the length limit and appends
+// a number if the name is taken. The returned name is marked as taken.
+func (f *sheetNameFixer) fix(name string) string {
+	name = invalidSheetNameChars.Replace(name)
+	name = strings.Trim(name, "'")
+	if strings.TrimSpace(name) == "" {
+		name = "Sheet"
+	}
+	name = truncateSheetName(name)
+
+	unique := name
+	for n := 2; f.used[strings.ToLower(unique)]; n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		unique = truncateRunes(name, maxSheetNameRunes-len(suffix)) + suffix
+	}
+	f.used[strings.ToLower(unique)] = true
+	return unique
+}
+
+// RepairSheetNames makes the sheet names of a workbook valid and unique without translating
+// them, e.g. to fix the output of a run that produced colliding or over-length names. It returns
+// the repaired workbook and the renamed sheets like SheetRenames. Of the sheets sharing a name,
+// the first keeps it; references to that name are ambiguous and are left to the first sheet.
+func RepairSheetNames(content string) (string, map[string]string) {
+	re := sheetNameRegex(content)
+	matches := re.FindAllStringSubmatchIndex(content, -1)
+	count := make(map[string]int)
+	for _, m := range matches {
+		count[strings.ToLower(html.UnescapeString(content[m[2]:m[3]]))]++
+	}
+
+	used := make(map[string]bool)
+	for name := range reservedSheetNames {
+		used[name] = true
+	}
+	fixer := &sheetNameFixer{used: used}
+	renames := make(map[string]string)
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		name := html.UnescapeString(content[m[2]:m[3]])
+		fixed := fixer.fix(name)
+		if fixed == name {
+			continue
+		}
+		if count[strings.ToLower(name)] == 1 {
+			renames[strings.ToLower(name)] = fixed
+		}
+		sb.WriteString(content[last:m[2]])
+		sb.WriteString(html.EscapeString(fixed))
+		last = m[3]
+	}
+	if last == 0 {
+		return content, renames
+	}
+	sb.WriteString(content[last:])
+	return sb.String(), renames
+}
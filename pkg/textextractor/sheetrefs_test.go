@@ -0,0 +1,41 @@
+package textextractor
+
+import "testing"
+
+func TestRenameFormulaRefs(t *testing.T) {
+	renames := map[string]string{
+		"sheet1":   "Data",
+		"sheet2":   "My data",
+		"my sheet": "Sales",
+		"数据":       "Figures",
+		"q1":       "A1",
+		"notes":    "O'Brien",
+	}
+	tests := []struct {
+		formula, want string
+	}{
+		{"Sheet1!A1+1", "Data!A1+1"},
+		{"SUM(Sheet1!A1:A3)", "SUM(Data!A1:A3)"},
+		{"SHEET1!$A$1", "Data!$A$1"},
+		{"'My sheet'!B2", "Sales!B2"},
+		{"Sheet2!A1", "'My data'!A1"},
+		{"数据!A1*2", "Figures!A1*2"},
+		{"Q1!B2", "'A1'!B2"},                 // A cell-like name is quoted
+		{"Notes!A1", "'O''Brien'!A1"},        // Apostrophes are doubled
+		{"Sheet1:Other!A1", "Data:Other!A1"}, // 3D reference
+		{"SUM(Sheet1:Sheet2!A1)", "SUM('Data:My data'!A1)"},
+		{`"Sheet1!A1"&Sheet1!B2`, `"Sheet1!A1"&Data!B2`}, // String literals are kept
+		{`"it''s"&'My sheet'!A1`, `"it''s"&Sales!A1`},
+		{"[1]Sheet1!A1", "[1]Sheet1!A1"}, // Other workbooks are kept
+		{`'C:\dir\[Book.xlsx]Sheet1'!A1`, `'C:\dir\[Book.xlsx]Sheet1'!A1`},
+		{"MySheet1!A1", "MySheet1!A1"}, // Another sheet
+		{"Other!A1", "Other!A1"},
+		{"Sheet1+1", "Sheet1+1"}, // Not a reference
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := renameFormulaRefs(tt.formula, renames); got != tt.want {
+			t.Errorf("renameFormulaRefs(%q) = %q, want %q", tt.formula, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package textextractor
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// StyleFilter selects workbook cells by the font of their cell style, e.g. to translate only the
+// red cells of an annotated template. Empty fields match any font; a zero filter translates all cells.
+type StyleFilter struct {
+	FontColor string // RGB color as hex, e.g. "FF0000"; a leading "#" and an alpha byte ("FFFF0000") are ignored
+	FontName  string // Font name, e.g. "Arial", compared ignoring case
+	Bold      bool   // Only bold fonts
+	Italic    bool   // Only italic fonts
+}
+
+// IsZero reports whether the filter has no criterion, in which case all cells are translated.
+func (f StyleFilter) IsZero() bool {
+	return f == StyleFilter{}
+}
+
+// CellStyle returns the filter selecting the workbook cells to translate by their style; a zero
+// filter translates all cells. Extract does not apply it, as the styles and cells of a workbook
+// are kept in other parts than the shared strings; see MatchingStyles and FilterSharedStrings.
+func (e *Extractor) CellStyle() StyleFilter {
+	return e.config.CellStyle
+}
+
+// indexedColors are the RGB values of the first entries of the legacy color palette, which
+// fonts refer to with <color indexed="N"/>. Entries 8 to 15 repeat 0 to 7.
+var indexedColors = []string{"000000", "FFFFFF", "FF0000", "00FF00", "0000FF", "FFFF00", "FF00FF", "00FFFF"}
+
+var (
+	colorAttrRegex = regexp.MustCompile(`\s(rgb|indexed)="([^"]*)"`)
+	valAttrRegex   = regexp.MustCompile(`\sval="([^"]*)"`)
+	fontIDRegex    = regexp.MustCompile(`\sfontId="(\d+)"`)
+	styleAttrRegex = regexp.MustCompile(`(?:^|\s)s="(\d+)"`)
+)
+
+// fontProps holds the font properties a StyleFilter can match.
+type fontProps struct {
+	color  string // Upper-case RGB hex without alpha, "" for theme or automatic colors
+	name   string
+	bold   bool
+	italic bool
+}
+
+// matches reports whether the font meets every criterion of the filter.
+func (f StyleFilter) matches(font fontProps) bool {
+	if f.FontColor != "" && normalizeColor(f.FontColor) != font.color {
+		return false
+	}
+	if f.FontName != "" && !strings.EqualFold(f.FontName, font.name) {
+		return false
+	}
+	return (!f.Bold || font.bold) && (!f.Italic || font.italic)
+}
+
+// normalizeColor returns an RGB hex color in upper case without "#" or alpha byte.
+func normalizeColor(color string) string {
+	color = strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(color), "#"))
+	if len(color) == 8 {
+		color = color[2:]
+	}
+	return color
+}
+
+// parseFonts returns the fonts of a styles part (xl/styles.xml) in index order.
+func parseFonts(content, x string) []fontProps {
+	fonts := elementRegex(x, `(?s)<%fonts\b[^>]*>(.*?)</%fonts>`).FindStringSubmatch(content)
+	if fonts == nil {
+		return nil
+	}
+	prop := func(font, name string) (string, bool) {
+		m := elementRegex(x, `<%`+name+`\b[^>]*?/?>`).FindString(font)
+		if m == "" {
+			return "", false
+		}
+		if v := valAttrRegex.FindStringSubmatch(m); v != nil {
+			return v[1], true
+		}
+		return "", true
+	}
+	flag := func(font, name string) bool {
+		v, ok := prop(font, name)
+		return ok && v != "0" && v != "false"
+	}
+
+	var props []fontProps
+	for _, m := range elementRegex(x, `(?s)<%font\b[^>]*?(?:/>|>(.*?)</%font>)`).FindAllStringSubmatch(fonts[1], -1) {
+		font := fontProps{bold: flag(m[1], "b"), italic: flag(m[1], "i")}
+		font.name, _ = prop(m[1], "name")
+		if color := elementRegex(x, `<%color\b[^>]*>`).FindString(m[1]); color != "" {
+			if c := colorAttrRegex.FindStringSubmatch(color); c != nil && c[1] == "rgb" {
+				font.color = normalizeColor(c[2])
+			} else if c != nil {
+				if i, err := strconv.Atoi(c[2]); err == nil && i < 16 {
+					font.color = indexedColors[i%8]
+				}
+			}
+		}
+		props = append(props, font)
+	}
+	return props
+}
+
+// MatchingStyles returns the indices of the cell formats (cellXfs) of a styles part whose font
+// matches the filter. Cells refer to them with their s attribute; cells without one use format 0.
+// Theme colors are not resolved, so they only match a filter without a font color.
+func MatchingStyles(content string, filter StyleFilter) map[int]bool {
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	fonts := parseFonts(content, x)
+	xfs := elementRegex(x, `(?s)<%cellXfs\b[^>]*>(.*?)</%cellXfs>`).FindStringSubmatch(content)
+	if xfs == nil {
+		return nil
+	}
+
+	styles := make(map[int]bool)
+	for i, xf := range elementRegex(x, `<%xf\b[^>]*>`).FindAllString(xfs[1], -1) {
+		fontID := 0
+		if m := fontIDRegex.FindStringSubmatch(xf); m != nil {
+			fontID, _ = strconv.Atoi(m[1])
+		}
+		if fontID < len(fonts) && filter.matches(fonts[fontID]) {
+			styles[i] = true
+		}
+	}
+	return styles
+}
+
+// FilterSharedStrings keeps the items of a shared strings part that belong to a string item
+// selected by keep, given its index. content is the part as returned by Extract.
+func FilterSharedStrings(content string, items []ExtractionItem, keep func(index int) bool) []ExtractionItem {
+	var kept []ExtractionItem
+	for i, index := range SharedStringIndices(content, items) {
+		if index >= 0 && keep(index) {
+			kept = append(kept, items[i])
+		}
+	}
+	return kept
+}
+
+// SharedStringIndices returns the index of the string item holding each item of a shared
+// strings part, or -1 for an item before the first one. content is the part as returned by Extract.
+func SharedStringIndices(content string, items []ExtractionItem) []int {
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	starts := elementRegex(x, `<%si\b`).FindAllStringIndex(content, -1)
+
+	indices := make([]int, len(items))
+	index := -1
+	for i, item := range items {
+		for index+1 < len(starts) && starts[index+1][0] < item.MatchStart {
+			index++
+		}
+		indices[i] = index
+	}
+	return indices
+}
@@ -0,0 +1,37 @@
+package textextractor
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTranslateDefinedNames(t *testing.T) {
+	workbook := `<workbook ` + testSheetNamespace + `><sheets><sheet name="数据" sheetId="1" r:id="rId1" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"/></sheets><definedNames>` +
+		`<definedName name="报表标题">"年度报表"</definedName>` +
+		`<definedName name="备注">="说明：""含税"""</definedName>` +
+		`<definedName name="区域">数据!$A$1:$C$10</definedName>` +
+		`<definedName name="_xlnm.Print_Titles" localSheetId="0">数据!$1:$1</definedName>` +
+		`<definedName name="合计">SUM(数据!$B$2:$B$10)</definedName>` +
+		`<definedName name="标签">IF(数据!$A$1="是","通过","未通过")</definedName>` +
+		`</definedNames></workbook>`
+	translations := map[string]string{"年度报表": "Annual report", `说明："含税"`: `Note: "incl. tax"`}
+	config := ExtractorConfig{DefinedNames: true, SkipSheetNames: true}
+	texts, got := translatePart(t, NewExtractor(config), "xl/workbook.xml", workbook, func(s string) string { return translations[s] })
+	// Only names whose value is a string constant are translated; the names themselves and the
+	// references, ranges and formulas of the others are left as they are
+	if want := []string{"年度报表", `说明："含税"`}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	want := strings.NewReplacer(
+		`"年度报表"`, `"Annual report"`,
+		`="说明：""含税"""`, `="Note: &#34;&#34;incl. tax&#34;&#34;"`, // Quotes are doubled and escaped
+	).Replace(workbook)
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if texts, _ := translatePart(t, NewExtractor(ExtractorConfig{SkipSheetNames: true}), "xl/workbook.xml", workbook, bracket); len(texts) != 0 {
+		t.Errorf("without DefinedNames: texts = %q", texts)
+	}
+}
@@ -0,0 +1,91 @@
+package textextractor
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	calcPrRegex         = regexp.MustCompile(`<(?:\w+:)?calcPr\b[^>]*?/?>`)
+	fullCalcOnLoadRegex = regexp.MustCompile(`\sfullCalcOnLoad="[^"]*"`)
+	// Closing tags of the workbook elements that precede calcPr
+	beforeCalcPrRegex = regexp.MustCompile(`</(?:\w+:)?(?:definedNames|externalReferences|functionGroups|sheets)>`)
+)
+
+// isWorksheetPart reports whether the internal file of a workbook is a worksheet.
+func isWorksheetPart(name string) bool {
+	return strings.HasSuffix(name, ".xml") && strings.Contains(name, "xl/worksheets/sheet")
+}
+
+// formulaResultRegex returns the regex matching formula cells with a string result, capturing
+// the cached result. The formula itself is left as it is.
+func formulaResultRegex(x string) *regexp.Regexp {
+	return elementRegex(x, `<%c\s[^>]*?\bt="str"[^>]*>\s*<%f\b(?:[^>]*/>|[^>]*>[^<]*</%f>)\s*<%v>([^<]*)</%v>`)
+}
+
+// extractWorksheet finds the texts of worksheets and tables outside of shared strings: the text
+// criteria of autofilters, the tooltips of hyperlinks and the cached string results of formulas,
+// as enabled by the configuration.
+func (e *Extractor) extractWorksheet(content, xmlType string) []ExtractionItem {
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+
+	var items []ExtractionItem
+	if e.config.AutoFilters {
+		// Each value is translated on its own
+		items = append(items, splitAutoFilterWildcards(content, e.matchItems(content, autoFilterRegex(x)))...)
+	}
+	if isWorksheetPart(xmlType) {
+		if e.config.HyperlinkTooltips {
+			items = append(items, e.matchItems(content, hyperlinkTooltipRegex(x))...)
+		}
+		if e.config.FormulaResults {
+			items = append(items, e.matchItems(content, formulaResultRegex(x))...)
+		}
+	}
+
+	// Apply replaces the items in document order
+	sort.Slice(items, func(i, j int) bool { return items[i].MatchStart < items[j].MatchStart })
+	return items
+}
+
+// matchItems returns an item for each match of re that holds text to translate.
+func (e *Extractor) matchItems(content string, re *regexp.Regexp) []ExtractionItem {
+	var items []ExtractionItem
+	for _, m := range re.FindAllStringSubmatchIndex(content, -1) {
+		if item, ok := e.newItem(content, [][]int{m}); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Recalculate reports whether translated workbooks should be recalculated when opened, see
+// SetFullCalcOnLoad. This is the case when cached formula results are translated: the cached
+// values are only shown until the formulas run again.
+func (e *Extractor) Recalculate() bool {
+	return e.config.FormulaResults
+}
+
+// SetFullCalcOnLoad marks a workbook (xl/workbook.xml) to be fully recalculated when it is
+// opened, by setting fullCalcOnLoad on its calculation properties or adding them.
+func SetFullCalcOnLoad(content string) string {
+	if loc := calcPrRegex.FindStringIndex(content); loc != nil {
+		tag := fullCalcOnLoadRegex.ReplaceAllString(content[loc[0]:loc[1]], "")
+		name := strings.IndexAny(tag[1:], " \t\r\n/>") + 1
+		tag = tag[:name] + ` fullCalcOnLoad="1"` + tag[name:]
+		return content[:loc[0]] + tag + content[loc[1]:]
+	}
+
+	x := namespacePrefix(content, "", sheetNamespace, sheetStrictNamespace)
+	calcPr := "<" + x + `calcPr fullCalcOnLoad="1"/>`
+	// calcPr follows the defined names, external references, function groups and sheets
+	var at []int
+	for _, loc := range beforeCalcPrRegex.FindAllStringIndex(content, -1) {
+		at = loc
+	}
+	if at == nil {
+		return content
+	}
+	return content[:at[1]] + calcPr + content[at[1]:]
+}
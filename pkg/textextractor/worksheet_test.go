@@ -0,0 +1,55 @@
+package textextractor
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTranslateFormulaResults(t *testing.T) {
+	sheet := `<worksheet ` + testSheetNamespace + `><sheetData><row r="1">` +
+		`<c r="A1" t="s"><v>0</v></c>` +
+		`<c r="B1" t="str"><f>IF(A2&gt;100,"达标","未达标")</f><v>达标</v></c>` +
+		`<c r="C1" t="str"><f t="shared" ref="C1:C2" si="0">CONCATENATE("合计",A2)</f><v>合计120</v></c>` +
+		`</row><row r="2">` +
+		`<c r="A2"><v>120</v></c>` +
+		`<c r="B2"><f>A2*2</f><v>240</v></c>` +
+		`<c r="C2" t="str"><f t="shared" si="0"/><v>合计240</v></c>` +
+		`<c r="D2" t="e"><f>1/0</f><v>#DIV/0!</v></c>` +
+		`</row></sheetData></worksheet>`
+	texts, got := translatePart(t, NewExtractor(ExtractorConfig{FormulaResults: true}), "xl/worksheets/sheet1.xml", sheet, bracket)
+	// Only the cached string results are translated; formulas, numbers and errors are kept
+	if want := []string{"达标", "合计120", "合计240"}; !slices.Equal(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	want := strings.NewReplacer(
+		`<v>达标</v>`, `<v>[达标]</v>`,
+		`<v>合计120</v>`, `<v>[合计120]</v>`,
+		`<v>合计240</v>`, `<v>[合计240]</v>`,
+	).Replace(sheet)
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if e := NewExtractor(ExtractorConfig{}); e.Supports("xl/worksheets/sheet1.xml") || e.Recalculate() {
+		t.Error("formula results are translated without FormulaResults")
+	}
+}
+
+func TestSetFullCalcOnLoad(t *testing.T) {
+	workbook := func(calc string) string {
+		return `<workbook ` + testSheetNamespace + `><sheets><sheet name="数据" sheetId="1"/></sheets>` + calc + `</workbook>`
+	}
+	tests := []struct {
+		calc, want string
+	}{
+		{``, `<calcPr fullCalcOnLoad="1"/>`},
+		{`<calcPr calcId="191029"/>`, `<calcPr fullCalcOnLoad="1" calcId="191029"/>`},
+		{`<calcPr calcId="191029" fullCalcOnLoad="0"/>`, `<calcPr fullCalcOnLoad="1" calcId="191029"/>`},
+	}
+	for _, tt := range tests {
+		if got := SetFullCalcOnLoad(workbook(tt.calc)); got != workbook(tt.want) {
+			t.Errorf("SetFullCalcOnLoad(%s) = %s, want %s", tt.calc, got, workbook(tt.want))
+		}
+	}
+}
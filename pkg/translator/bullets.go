@@ -0,0 +1,47 @@
+package translator
+
+import (
+	"regexp"
+	"strings"
+)
+
+// bulletRegex 匹配行首的列表标记，如 "• "、"- "、"1. "、"（2）"、"①"
+// 半角的 "1." 和 "1)" 之后须有空白或位于行尾，以免把 "3.14 m"、"1.5倍" 中的数字当作标记
+var bulletRegex = regexp.MustCompile(`^\s*(?:[•·●○■□◆◇▪‣⁃※]|[-*]\s|\d{1,3}(?:[.)](?:\s|$)|[．、])|[（(]\d{1,3}[)）]|[①-⑳])\s*`)
+
+// stripBullets 去除多行文本中每行开头的列表标记
+// 返回每行的标记和去除标记后的文本；文本不是多行列表时 ok 为 false
+func stripBullets(text string) (markers []string, body string, ok bool) {
+	if !strings.Contains(text, "\n") {
+		return nil, text, false
+	}
+
+	lines := strings.Split(text, "\n")
+	markers = make([]string, len(lines))
+	for i, line := range lines {
+		if loc := bulletRegex.FindStringIndex(line); loc != nil && loc[1] < len(line) {
+			markers[i] = line[:loc[1]]
+			lines[i] = line[loc[1]:]
+			ok = true
+		}
+	}
+	if !ok {
+		return nil, text, false
+	}
+	return markers, strings.Join(lines, "\n"), true
+}
+
+// restoreBullets 将列表标记重新添加到译文的对应行
+// 译文行数与原文不一致时无法对应，原样返回译文
+func restoreBullets(markers []string, translated string) string {
+	lines := strings.Split(translated, "\n")
+	if len(lines) != len(markers) {
+		return translated
+	}
+	for i, line := range lines {
+		if markers[i] != "" {
+			lines[i] = markers[i] + bulletRegex.ReplaceAllString(line, "")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
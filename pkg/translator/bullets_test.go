@@ -0,0 +1,44 @@
+package translator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStripBullets(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		markers []string
+		body    string
+		ok      bool
+	}{
+		{"single line", "1. 概要", nil, "1. 概要", false},
+		{"numbered", "1. 概要\n2. 详情", []string{"1. ", "2. "}, "概要\n详情", true},
+		{"parenthesis", "1) 概要\n2) 详情", []string{"1) ", "2) "}, "概要\n详情", true},
+		{"full-width", "1．概要\n2、详情", []string{"1．", "2、"}, "概要\n详情", true},
+		{"symbols", "• 概要\n- 详情\n①结论", []string{"• ", "- ", "①"}, "概要\n详情\n结论", true},
+		{"decimal number", "3.14 m\n长度", []string{"", ""}, "3.14 m\n长度", false},
+		{"decimal factor", "1.5倍\n2.0倍", []string{"", ""}, "1.5倍\n2.0倍", false},
+		{"marker and decimal", "1. 3.5 m\n2. 1.5倍", []string{"1. ", "2. "}, "3.5 m\n1.5倍", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markers, body, ok := stripBullets(tt.text)
+			if ok != tt.ok || body != tt.body || (ok && !reflect.DeepEqual(markers, tt.markers)) {
+				t.Errorf("stripBullets(%q) = %q, %q, %v; want %q, %q, %v", tt.text, markers, body, ok, tt.markers, tt.body, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRestoreBullets(t *testing.T) {
+	got := restoreBullets([]string{"1. ", "2. "}, "Overview\n2. Details")
+	if want := "1. Overview\n2. Details"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// Lines that no longer match keep the translation as it is
+	if got := restoreBullets([]string{"1. "}, "a\nb"); got != "a\nb" {
+		t.Errorf("got %q, want the translation unchanged", got)
+	}
+}
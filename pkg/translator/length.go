@@ -0,0 +1,28 @@
+package translator
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// LengthLimit 定义译文长度检查规则，超出限制的译文通过 OnFlagged 回调报告，不会被截断
+// 长度按字符（rune）计算，零值表示不检查
+type LengthLimit struct {
+	MaxRatio  float64 // 译文与原文字符数之比的上限
+	MaxLength int     // 译文字符数上限
+}
+
+// check 检查译文长度，超出限制时返回原因，否则返回空字符串
+func (l LengthLimit) check(original, translated string) string {
+	n := utf8.RuneCountInString(translated)
+	if l.MaxLength > 0 && n > l.MaxLength {
+		return fmt.Sprintf("translation has %d characters, exceeds limit of %d", n, l.MaxLength)
+	}
+	if l.MaxRatio > 0 {
+		src := utf8.RuneCountInString(original)
+		if src > 0 && float64(n)/float64(src) > l.MaxRatio {
+			return fmt.Sprintf("translation is %.1fx the source length, exceeds ratio of %.1f", float64(n)/float64(src), l.MaxRatio)
+		}
+	}
+	return ""
+}
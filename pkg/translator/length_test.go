@@ -0,0 +1,63 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLengthLimitCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      LengthLimit
+		translated string
+		flagged    bool
+	}{
+		{"no limit", LengthLimit{}, strings.Repeat("x", 100), false},
+		{"within max length", LengthLimit{MaxLength: 4}, "收入增长", false},
+		{"over max length", LengthLimit{MaxLength: 4}, "收入增长率", true},
+		{"within ratio", LengthLimit{MaxRatio: 2}, "abcdefgh", false},
+		{"over ratio", LengthLimit{MaxRatio: 2}, "abcdefghi", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The source has 4 characters
+			reason := tt.limit.check("营业收入", tt.translated)
+			if (reason != "") != tt.flagged {
+				t.Errorf("check(%q) = %q, want flagged %v", tt.translated, reason, tt.flagged)
+			}
+		})
+	}
+}
+
+func TestOnFlaggedKeepsLongTranslations(t *testing.T) {
+	var mu sync.Mutex
+	flagged := make(map[string]string)
+	lt := NewTranslator(context.Background(), &fakeEngine{}, TranslationCallbacks{
+		OnFlagged: func(original, translated, reason string) {
+			mu.Lock()
+			defer mu.Unlock()
+			flagged[original] = translated
+			if reason == "" {
+				t.Errorf("flagged %q without a reason", original)
+			}
+		},
+	})
+	lt.SetLengthLimit(LengthLimit{MaxRatio: 2})
+
+	// "T " makes a one-character text three times as long and a four-character one 1.5 times
+	texts := []string{"甲", "营业收入"}
+	results, err := lt.TranslateFileTexts(context.Background(), "document", texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, text := range texts {
+		if results[i] != "T "+text {
+			t.Errorf("result %d = %q, want %q", i, results[i], "T "+text)
+		}
+	}
+	if len(flagged) != 1 || flagged["甲"] != "T 甲" {
+		t.Errorf("flagged %q, want only 甲", flagged)
+	}
+}
@@ -0,0 +1,88 @@
+package translator
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 译文句末标点的处理方式
+const (
+	PunctKeep  = ""      // 保留翻译引擎返回的句末标点
+	PunctMatch = "match" // 原文以句末标点结尾时为译文补上句号，原文没有时去掉译文末尾的句号
+)
+
+// sentenceEnds 句末标点，原文以其中之一结尾即视为有句末标点
+const sentenceEnds = ".。．!！?？…;；"
+
+// periods 可以补上或去掉的句号；问号、感叹号等带有语气，不做改动
+const periods = ".。．"
+
+// closers 句末标点之后可能出现的右引号和右括号
+const closers = `"'”’」』)）]】》>`
+
+// abbreviations 以句号结尾的常见缩写，译文以其结尾时句号不是句末标点，不去掉
+var abbreviations = []string{"etc.", "Ltd.", "Inc.", "Co.", "Corp.", "No.", "e.g.", "i.e.", "vs.", "approx."}
+
+// trailingEnd 返回 s 去掉末尾空白和右引号、右括号后的长度，句末标点位于该位置之前
+func trailingEnd(s string) int {
+	return len(strings.TrimRightFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(closers, r)
+	}))
+}
+
+// matchTrailingPunct 使译文是否以句末标点结尾与原文一致
+// 原文有而译文没有时补上句号，译文以中日文字结尾时用"。"，否则用"."；
+// 原文没有而译文以句号结尾时去掉句号。标点插入或去掉的位置在末尾的引号、括号和空白之前
+func matchTrailingPunct(original, translated string) string {
+	srcEnd := trailingEnd(original)
+	if srcEnd == 0 {
+		return translated
+	}
+	dstEnd := trailingEnd(translated)
+	if dstEnd == 0 {
+		return translated
+	}
+	srcLast, _ := utf8.DecodeLastRuneInString(original[:srcEnd])
+	dstLast, size := utf8.DecodeLastRuneInString(translated[:dstEnd])
+	srcHas := strings.ContainsRune(sentenceEnds, srcLast)
+	dstHas := strings.ContainsRune(sentenceEnds, dstLast)
+
+	switch {
+	case srcHas && !dstHas:
+		period := "."
+		if isCJKWide(dstLast) {
+			period = "。"
+		}
+		return translated[:dstEnd] + period + translated[dstEnd:]
+	case !srcHas && strings.ContainsRune(periods, dstLast):
+		body := translated[:dstEnd]
+		// 省略号和缩写末尾的句号不是句末标点，只有句号的译文也保持不变
+		if dstEnd == size || strings.HasSuffix(body[:dstEnd-size], ".") || endsWithAbbreviation(body) {
+			return translated
+		}
+		return body[:dstEnd-size] + translated[dstEnd:]
+	}
+	return translated
+}
+
+// isCJKWide 判断字符是否为使用全角句号的中日文字或全角符号
+func isCJKWide(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF01 && r <= 0xFF60)
+}
+
+// endsWithAbbreviation 判断文本是否以常见缩写结尾
+func endsWithAbbreviation(s string) bool {
+	for _, abbr := range abbreviations {
+		if !strings.HasSuffix(s, abbr) {
+			continue
+		}
+		// 缩写须是完整的单词，如 "Co." 不匹配 "Taco."
+		r, _ := utf8.DecodeLastRuneInString(s[:len(s)-len(abbr)])
+		if r == utf8.RuneError || !unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
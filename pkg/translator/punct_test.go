@@ -0,0 +1,37 @@
+package translator
+
+import "testing"
+
+func TestMatchTrailingPunct(t *testing.T) {
+	tests := []struct {
+		name, original, translated, want string
+	}{
+		// Source with a sentence-final mark
+		{"add period", "收入增加了。", "Revenue increased", "Revenue increased."},
+		{"add full stop", "Revenue increased.", "收入增加了", "收入增加了。"},
+		{"add before quote", "他说“好。”", `He said "OK"`, `He said "OK."`},
+		{"add before space", "完成。", "Done  ", "Done.  "},
+		{"keep period", "完成。", "Done.", "Done."},
+		{"keep question", "完成了吗？", "Done?", "Done?"},
+		{"keep exclamation", "Done!", "完成！", "完成！"},
+
+		// Source without one
+		{"drop period", "收入", "Revenue.", "Revenue"},
+		{"drop full stop", "Revenue", "收入。", "收入"},
+		{"drop before bracket", "（备注）", "(Note.)", "(Note)"},
+		{"keep question mark", "收入", "Revenue?", "Revenue?"},
+		{"keep ellipsis", "等等", "and so on...", "and so on..."},
+		{"keep abbreviation", "某公司", "Acme Inc.", "Acme Inc."},
+		{"keep lone period", "。", ".", "."},
+		{"unchanged", "收入", "Revenue", "Revenue"},
+
+		// Nothing to compare
+		{"empty source", "", "Revenue.", "Revenue."},
+		{"empty translation", "完成。", "", ""},
+	}
+	for _, tt := range tests {
+		if got := matchTrailingPunct(tt.original, tt.translated); got != tt.want {
+			t.Errorf("%s: matchTrailingPunct(%q, %q) = %q, want %q", tt.name, tt.original, tt.translated, got, tt.want)
+		}
+	}
+}
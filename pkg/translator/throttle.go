@@ -0,0 +1,59 @@
+package translator
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// ProgressThrottle 控制 OnProgress 回调的粒度，零值表示每个文本项都回调
+type ProgressThrottle struct {
+	MinInterval time.Duration // 两次回调之间的最小时间间隔
+	MinStep     float64       // 两次回调之间完成比例的最小增量，如 0.01 表示每完成 1% 回调一次
+}
+
+// Wrap 返回按 p 合并回调的 onProgress，各阶段分别节流，每个阶段的最后一项总是回调，可并发调用。
+// p 为零值时返回 onProgress 本身
+func (p ProgressThrottle) Wrap(onProgress func(phase string, done, total int)) func(phase string, done, total int) {
+	if p == (ProgressThrottle{}) || onProgress == nil {
+		return onProgress
+	}
+	var mu sync.Mutex
+	throttlers := make(map[string]*progressThrottler)
+	return func(phase string, done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		throttler, ok := throttlers[phase]
+		if !ok {
+			throttler = &progressThrottler{throttle: p}
+			throttlers[phase] = throttler
+		}
+		if throttler.allow(done, total) {
+			onProgress(phase, done, total)
+		}
+	}
+}
+
+// progressThrottler 根据 ProgressThrottle 合并进度回调
+type progressThrottler struct {
+	throttle ProgressThrottle
+	lastDone int
+	lastTime time.Time
+}
+
+// allow 判断本次进度是否需要回调，最后一项总是回调
+func (p *progressThrottler) allow(done, total int) bool {
+	now := time.Now()
+	if done < total {
+		step := int(math.Ceil(p.throttle.MinStep * float64(total)))
+		if step > 0 && done-p.lastDone < step {
+			return false
+		}
+		if p.throttle.MinInterval > 0 && now.Sub(p.lastTime) < p.throttle.MinInterval {
+			return false
+		}
+	}
+	p.lastDone = done
+	p.lastTime = now
+	return true
+}
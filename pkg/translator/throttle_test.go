@@ -0,0 +1,47 @@
+package translator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressThrottleMinStep(t *testing.T) {
+	var calls []int
+	onProgress := ProgressThrottle{MinStep: 0.1}.Wrap(func(_ string, done, _ int) { calls = append(calls, done) })
+	for done := 1; done <= 95; done++ {
+		onProgress("document", done, 95)
+	}
+	// Every 10% of 95 items is a step of 10 items (rounded up); the last item is always reported
+	want := []int{10, 20, 30, 40, 50, 60, 70, 80, 90, 95}
+	if len(calls) != len(want) {
+		t.Fatalf("got calls %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("got calls %v, want %v", calls, want)
+		}
+	}
+}
+
+func TestProgressThrottleMinInterval(t *testing.T) {
+	calls := 0
+	onProgress := ProgressThrottle{MinInterval: time.Hour}.Wrap(func(string, int, int) { calls++ })
+	for done := 1; done <= 100; done++ {
+		onProgress("document", done, 100)
+	}
+	// The first item and the last one
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+}
+
+func TestProgressThrottleZero(t *testing.T) {
+	calls := 0
+	onProgress := ProgressThrottle{}.Wrap(func(string, int, int) { calls++ })
+	for done := 1; done <= 10; done++ {
+		onProgress("document", done, 10)
+	}
+	if calls != 10 {
+		t.Errorf("got %d calls, want 10", calls)
+	}
+}
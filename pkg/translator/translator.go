@@ -2,7 +2,10 @@ package translator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 )
 
 // TranslationEngine 定义翻译引擎接口，用于将原文转换成翻译结果
@@ -14,10 +17,44 @@ type TranslationEngine interface {
 	Translate(ctx context.Context, text string) (string, error)
 }
 
+// BatchTranslationEngine 是支持在一次调用中翻译多个文本的翻译引擎
+type BatchTranslationEngine interface {
+	TranslationEngine
+	// TranslateBatch 翻译多个文本，返回与输入一一对应的译文
+	TranslateBatch(ctx context.Context, texts []string) ([]string, error)
+}
+
+// ErrBudgetExceeded 表示翻译引擎的用量预算已用完，不再发送请求。
+// 引擎返回包装了该错误的错误时，TranslateFileTexts 保留相应文本的原文并继续处理，而不是中止翻译
+var ErrBudgetExceeded = errors.New("translation budget exceeded")
+
 // Translator 定义翻译器接口，供 FileProcessor 使用
 type Translator interface {
-	// TranslateFileTexts 批量翻译文本数组
-	TranslateFileTexts(fileName string, texts []string) ([]string, error)
+	// TranslateFileTexts 批量翻译文本数组，ctx 取消时停止翻译并返回其错误
+	TranslateFileTexts(ctx context.Context, fileName string, texts []string) ([]string, error)
+}
+
+// CachingEngine 是可以查询译文是否已缓存的翻译引擎
+type CachingEngine interface {
+	// Cached 判断文本的译文是否已缓存，翻译时不会发起请求
+	Cached(text string) bool
+}
+
+// SegmentResult 描述单个文本项的翻译结果
+type SegmentResult struct {
+	File       string // 文本所在的文件部件，单独调用 Translate 时为空
+	Original   string
+	Translated string
+	Cached     bool  // 译文来自翻译引擎的缓存
+	Err        error // 翻译失败时的错误
+}
+
+// SegmentHooks 定义每个文本片段翻译前后的处理钩子，用于在不修改翻译器的情况下扩展处理逻辑
+type SegmentHooks struct {
+	// Pre 在翻译前处理原文（如去除空白、统一标点），返回值将发送给翻译引擎
+	Pre func(src string) string
+	// Post 在翻译后处理译文（如统一术语大小写），src 为 Pre 处理后的原文
+	Post func(src, dst string) string
 }
 
 // TranslationCallbacks 定义翻译流程中的回调
@@ -26,69 +63,467 @@ type TranslationCallbacks struct {
 	OnProgress   func(phase string, done, total int)
 	OnError      func(stage string, err error)
 	OnComplete   func(err error)
+
+	// OnFlagged 在译文超出长度限制时调用，reason 说明超出的规则，用于人工检查
+	OnFlagged func(original, translated, reason string)
+
+	// ProgressThrottle 合并 OnProgress 回调，默认不节流；MinStep 按每次翻译的部件的文本项数计算
+	ProgressThrottle ProgressThrottle
+
+	// Hooks 在每个文本片段翻译前后调用
+	Hooks SegmentHooks
+
+	// OnSegment 在每个文本项翻译完成或失败后调用，用于记录翻译日志
+	OnSegment func(result SegmentResult)
+
+	// Ordered 为 true 时，并发翻译的 OnTranslated 和 OnProgress 按原文顺序回调；
+	// 先完成的文本项会等待之前的文本项完成后再回调
+	Ordered bool
 }
 
 // LocalTranslator 封装翻译引擎和上下文，负责执行翻译操作
 type LocalTranslator struct {
-	ctx       context.Context
-	engine    TranslationEngine
-	callbacks TranslationCallbacks
+	ctx            context.Context
+	engine         TranslationEngine
+	callbacks      TranslationCallbacks
+	concurrency    int                       // 最大并发翻译数
+	concurrencyFor func(fileName string) int // 按文件部件覆盖最大并发数，返回 0 时使用 concurrency
+	batchSize      int                       // 每次引擎调用翻译的文本数
+	lengthLimit    LengthLimit               // 译文长度检查规则
+	widthMode      string                    // 全角字母数字的处理方式，见 WidthNormalize
+	punctMode      string                    // 译文句末标点的处理方式，见 PunctMatch
+
+	// 预览模式：只翻译前 previewLimit 个不重复的文本，其余保留原文
+	previewMu      sync.Mutex
+	previewLimit   int
+	previewed      map[string]bool // 已选入预览的文本
+	previewSkipped map[string]bool // 因超出预览数量而保留原文的文本
+
+	budgetMu      sync.Mutex
+	budgetSkipped map[string]bool // 因预算用完而保留原文的文本
 }
 
 // NewTranslator 创建一个新的 LocalTranslator 实例
 func NewTranslator(ctx context.Context, engine TranslationEngine, callbacks TranslationCallbacks) *LocalTranslator {
 	return &LocalTranslator{
-		ctx:       ctx,
-		engine:    engine,
-		callbacks: callbacks,
+		ctx:         ctx,
+		engine:      engine,
+		callbacks:   callbacks,
+		concurrency: 1,
+		batchSize:   1,
+	}
+}
+
+// SetConcurrency 设置批量翻译时的最大并发数，小于 1 时按 1 处理
+func (t *LocalTranslator) SetConcurrency(n int) {
+	t.concurrency = max(n, 1)
+}
+
+// SetConcurrencyFunc 按文件部件设置批量翻译的最大并发数，f 返回 0 时使用 SetConcurrency 设置的值
+func (t *LocalTranslator) SetConcurrencyFunc(f func(fileName string) int) {
+	t.concurrencyFor = f
+}
+
+// concurrencyOf 返回翻译 fileName 中的文本时的最大并发数
+func (t *LocalTranslator) concurrencyOf(fileName string) int {
+	if t.concurrencyFor != nil {
+		if n := t.concurrencyFor(fileName); n > 0 {
+			return n
+		}
+	}
+	return t.concurrency
+}
+
+// SetBatchSize 设置批量翻译时每次引擎调用包含的文本数，小于 1 时按 1 处理
+// 仅当引擎实现 BatchTranslationEngine 时生效
+func (t *LocalTranslator) SetBatchSize(n int) {
+	t.batchSize = max(n, 1)
+}
+
+// SetWidthMode 设置原文中全角字母和数字的处理方式（WidthKeep、WidthNormalize 或 WidthRestore）
+func (t *LocalTranslator) SetWidthMode(mode string) {
+	t.widthMode = mode
+}
+
+// SetTrailingPunct 设置译文句末标点的处理方式（PunctKeep 或 PunctMatch）
+func (t *LocalTranslator) SetTrailingPunct(mode string) {
+	t.punctMode = mode
+}
+
+// SetLengthLimit 设置译文长度检查规则
+func (t *LocalTranslator) SetLengthLimit(limit LengthLimit) {
+	t.lengthLimit = limit
+}
+
+// SetPreviewLimit 设置预览模式，整个文件只翻译前 n 个不重复的文本，其余保留原文；0 表示翻译全部文本
+// 仅对 TranslateFileTexts 生效
+func (t *LocalTranslator) SetPreviewLimit(n int) {
+	t.previewMu.Lock()
+	defer t.previewMu.Unlock()
+	t.previewLimit = max(n, 0)
+	t.previewed = make(map[string]bool)
+	t.previewSkipped = make(map[string]bool)
+}
+
+// PreviewSkipped 返回预览模式下因超出数量而未翻译的不重复文本数
+func (t *LocalTranslator) PreviewSkipped() int {
+	t.previewMu.Lock()
+	defer t.previewMu.Unlock()
+	return len(t.previewSkipped)
+}
+
+// limitPreview 按预览数量划分不重复的文本，返回需要翻译的文本和保留原文的文本
+func (t *LocalTranslator) limitPreview(unique []string) (selected, skipped []string) {
+	t.previewMu.Lock()
+	defer t.previewMu.Unlock()
+	if t.previewLimit == 0 {
+		return unique, nil
 	}
+	for _, text := range unique {
+		if t.previewed[text] || len(t.previewed) < t.previewLimit {
+			t.previewed[text] = true
+			selected = append(selected, text)
+		} else {
+			t.previewSkipped[text] = true
+			skipped = append(skipped, text)
+		}
+	}
+	return selected, skipped
 }
 
 // Translate 执行翻译操作，内部调用翻译引擎
 func (t *LocalTranslator) Translate(text string) (string, error) {
+	translated, cached, err := t.translate(t.ctx, text)
+	if err != nil {
+		t.notifySegment("", text, "", false, err)
+		return "", err
+	}
+	t.notifyTranslated(text, translated)
+	t.notifySegment("", text, translated, cached, nil)
+	return translated, nil
+}
+
+// BudgetSkipped 返回因翻译引擎预算用完而未翻译的不重复文本数
+func (t *LocalTranslator) BudgetSkipped() int {
+	t.budgetMu.Lock()
+	defer t.budgetMu.Unlock()
+	return len(t.budgetSkipped)
+}
+
+// skipOverBudget 记录因预算用完而保留原文的文本
+func (t *LocalTranslator) skipOverBudget(texts []string) {
+	t.budgetMu.Lock()
+	defer t.budgetMu.Unlock()
+	if t.budgetSkipped == nil {
+		t.budgetSkipped = make(map[string]bool)
+	}
+	for _, text := range texts {
+		t.budgetSkipped[text] = true
+	}
+}
+
+// notifyTranslated 在实际翻译发生时触发 OnTranslated 回调
+func (t *LocalTranslator) notifyTranslated(original, translated string) {
+	if translated != original && t.callbacks.OnTranslated != nil {
+		t.callbacks.OnTranslated(original, translated)
+	}
+}
+
+// notifySegment 触发 OnSegment 回调
+func (t *LocalTranslator) notifySegment(fileName, original, translated string, cached bool, err error) {
+	if t.callbacks.OnSegment != nil {
+		t.callbacks.OnSegment(SegmentResult{
+			File:       fileName,
+			Original:   original,
+			Translated: translated,
+			Cached:     cached,
+			Err:        err,
+		})
+	}
+}
+
+// isCached 判断引擎是否已缓存文本的译文，引擎不支持查询时返回 false
+func (t *LocalTranslator) isCached(text string) bool {
+	engine, ok := t.engine.(CachingEngine)
+	return ok && engine.Cached(text)
+}
+
+// segment 保存单个文本在发送给翻译引擎前的处理状态
+type segment struct {
+	text    string   // 原文
+	source  string   // Pre 处理后的原文
+	markers []string // 多行列表的列表标记
+	body    string   // 发送给翻译引擎的文本
+	isList  bool
+}
+
+// prepare 执行翻译前处理
+func (t *LocalTranslator) prepare(text string) segment {
+	seg := segment{text: text, source: text}
+	if t.widthMode == WidthNormalize || t.widthMode == WidthRestore {
+		seg.source = normalizeWidth(seg.source)
+	}
+	if t.callbacks.Hooks.Pre != nil {
+		seg.source = t.callbacks.Hooks.Pre(seg.source)
+	}
+
+	// 多行列表只翻译内容，列表标记在翻译后重新添加
+	seg.markers, seg.body, seg.isList = stripBullets(seg.source)
+	return seg
+}
+
+// finish 执行翻译后处理并检查译文长度
+func (t *LocalTranslator) finish(seg segment, translatedText string) string {
+	if seg.isList {
+		translatedText = restoreBullets(seg.markers, translatedText)
+	}
+
+	// 翻译后处理
+	if t.callbacks.Hooks.Post != nil {
+		translatedText = t.callbacks.Hooks.Post(seg.source, translatedText)
+	}
+	if t.widthMode == WidthRestore {
+		translatedText = restoreWidth(seg.text, translatedText)
+	}
+	if t.punctMode == PunctMatch {
+		translatedText = matchTrailingPunct(seg.text, translatedText)
+	}
+
+	// 标记过长的译文
+	if reason := t.lengthLimit.check(seg.text, translatedText); reason != "" && t.callbacks.OnFlagged != nil {
+		t.callbacks.OnFlagged(seg.text, translatedText, reason)
+	}
+	return translatedText
+}
+
+// reportError 报告翻译引擎错误，因其他文本项失败或用户停止而取消时不重复报告；
+// 预算用完不是单个文本项的错误，不在此报告
+func (t *LocalTranslator) reportError(ctx context.Context, text string, err error) {
+	if ctx.Err() == nil && t.callbacks.OnError != nil && !errors.Is(err, ErrBudgetExceeded) {
+		t.callbacks.OnError("translation_engine", fmt.Errorf("translation failed for text '%s': %w", text, err))
+	}
+}
+
+// translate 使用给定的上下文翻译单个文本，同时返回译文是否来自引擎缓存
+func (t *LocalTranslator) translate(ctx context.Context, text string) (string, bool, error) {
 	// 检查上下文是否已取消
 	select {
-	case <-t.ctx.Done():
-		return "", t.ctx.Err()
+	case <-ctx.Done():
+		return "", false, ctx.Err()
 	default:
 		// 继续执行
 	}
 
+	seg := t.prepare(text)
+	cached := t.isCached(seg.body)
+
 	// 调用翻译引擎
-	translatedText, err := t.engine.Translate(t.ctx, text)
+	translatedText, err := t.engine.Translate(ctx, seg.body)
 	if err != nil {
-		if t.callbacks.OnError != nil {
-			t.callbacks.OnError("translation_engine", fmt.Errorf("translation failed for text '%s': %w", text, err))
+		t.reportError(ctx, text, err)
+		return "", false, err
+	}
+	return t.finish(seg, translatedText), cached, nil
+}
+
+// translateBatch 通过一次引擎调用翻译多个文本，引擎不支持批量翻译时逐个翻译
+// 返回译文及每个译文是否来自引擎缓存
+func (t *LocalTranslator) translateBatch(ctx context.Context, texts []string) ([]string, []bool, error) {
+	results := make([]string, len(texts))
+	cached := make([]bool, len(texts))
+
+	batchEngine, ok := t.engine.(BatchTranslationEngine)
+	if !ok || len(texts) == 1 {
+		for i, text := range texts {
+			translated, hit, err := t.translate(ctx, text)
+			if err != nil {
+				return nil, nil, err
+			}
+			results[i], cached[i] = translated, hit
 		}
-		return "", err
+		return results, cached, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
 	}
 
-	// 只有在实际翻译发生时才触发回调
-	if translatedText != text && t.callbacks.OnTranslated != nil {
-		t.callbacks.OnTranslated(text, translatedText)
+	segs := make([]segment, len(texts))
+	bodies := make([]string, len(texts))
+	for i, text := range texts {
+		segs[i] = t.prepare(text)
+		bodies[i] = segs[i].body
+		cached[i] = t.isCached(bodies[i])
+	}
+
+	translated, err := batchEngine.TranslateBatch(ctx, bodies)
+	if err != nil {
+		t.reportError(ctx, texts[0], err)
+		return nil, nil, err
+	}
+	if len(translated) != len(texts) {
+		return nil, nil, fmt.Errorf("batch translation returned %d texts for %d inputs", len(translated), len(texts))
 	}
 
-	return translatedText, nil
+	for i, seg := range segs {
+		results[i] = t.finish(seg, translated[i])
+	}
+	return results, cached, nil
 }
 
 // TranslateFileTexts 批量翻译文本数组
-func (t *LocalTranslator) TranslateFileTexts(fileName string, texts []string) ([]string, error) {
-	translations := make([]string, 0, len(texts))
+// 重复的文本只翻译一次，译文回填到所有出现的位置并按原顺序返回；
+// 每 batchSize 个文本合并为一次引擎调用，最多同时进行 concurrency（或该文件部件的并发数）次调用，
+// 任一调用失败时取消其余翻译
+func (t *LocalTranslator) TranslateFileTexts(parent context.Context, fileName string, texts []string) ([]string, error) {
+	translations := make([]string, len(texts))
 	totalItems := len(texts)
 
+	// 按首次出现的顺序收集不重复的文本及其所有位置
+	var unique []string
+	positions := make(map[string][]int)
 	for i, text := range texts {
-		// 翻译单个文本项
-		translated, err := t.Translate(text)
-		if err != nil {
-			return nil, fmt.Errorf("translation failed for item %d in %s: %w", i, fileName, err)
+		if _, ok := positions[text]; !ok {
+			unique = append(unique, text)
 		}
-		translations = append(translations, translated)
+		positions[text] = append(positions[text], i)
+	}
+	throttler := &progressThrottler{throttle: t.callbacks.ProgressThrottle}
+	unique, skipped := t.limitPreview(unique)
+
+	// 调用方的 ctx 和创建翻译器时的 ctx 任一取消都会停止翻译
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	stop := context.AfterFunc(t.ctx, cancel)
+	defer stop()
 
-		// 报告进度
-		if t.callbacks.OnProgress != nil {
-			t.callbacks.OnProgress(fileName, i+1, totalItems)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex // 保护 firstErr 和进度回调
+		firstErr error
+		done     atomic.Int64
+
+		// 按顺序回调时的重排缓冲：已完成的位置及下一个待回调的位置
+		ready     = make([]bool, totalItems)
+		fromCache = make([]bool, totalItems)
+		next      int
+
+		// 预览模式下或因预算用完而保留原文的位置，不触发翻译回调
+		passed = make([]bool, totalItems)
+	)
+	for _, text := range skipped {
+		for _, i := range positions[text] {
+			translations[i] = text
+			ready[i] = true
+			passed[i] = true
 		}
+		done.Add(int64(len(positions[text])))
+	}
+	if len(unique) == 0 && len(skipped) > 0 && t.callbacks.OnProgress != nil {
+		t.callbacks.OnProgress(fileName, totalItems, totalItems)
 	}
+	sem := make(chan struct{}, t.concurrencyOf(fileName))
 
+	for start := 0; start < len(unique); start += t.batchSize {
+		batch := unique[start:min(start+t.batchSize, len(unique))]
+
+		// 获取信号量，已取消时停止派发
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(batch []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// 翻译一组文本项
+			results, cached, err := t.translateBatch(ctx, batch)
+			// 预算用完时保留原文，已翻译的部分照常写入
+			overBudget := err != nil && errors.Is(err, ErrBudgetExceeded)
+			if overBudget {
+				t.skipOverBudget(batch)
+				results, cached, err = batch, make([]bool, len(batch)), nil
+			}
+			if err != nil {
+				// 记录失败的文本项，因其他文本项失败或用户停止而取消的除外
+				if ctx.Err() == nil {
+					for _, text := range batch {
+						t.notifySegment(fileName, text, "", false, err)
+					}
+				}
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("translation failed for item %d in %s: %w", positions[batch[0]][0], fileName, err)
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+
+			if t.callbacks.Ordered {
+				mu.Lock()
+				defer mu.Unlock()
+				for j, text := range batch {
+					for _, i := range positions[text] {
+						translations[i] = results[j]
+						fromCache[i] = cached[j]
+						ready[i] = true
+						passed[i] = overBudget
+					}
+				}
+				// 按原文顺序回调已连续完成的文本项
+				start := next
+				for next < totalItems && ready[next] {
+					if !passed[next] {
+						t.notifyTranslated(texts[next], translations[next])
+						t.notifySegment(fileName, texts[next], translations[next], fromCache[next], nil)
+					}
+					next++
+				}
+				if next > start && t.callbacks.OnProgress != nil && throttler.allow(next, totalItems) {
+					t.callbacks.OnProgress(fileName, next, totalItems)
+				}
+				return
+			}
+
+			count := 0
+			for j, text := range batch {
+				// 每个出现位置都触发一次回调，与逐项翻译时的日志一致
+				for _, i := range positions[text] {
+					translations[i] = results[j]
+					if !overBudget {
+						t.notifyTranslated(text, results[j])
+						t.notifySegment(fileName, text, results[j], cached[j], nil)
+					}
+					count++
+				}
+			}
+
+			// 报告进度，在锁内回调以保证完成数单调递增
+			mu.Lock()
+			defer mu.Unlock()
+			n := int(done.Add(int64(count)))
+			if t.callbacks.OnProgress != nil && throttler.allow(n, totalItems) {
+				t.callbacks.OnProgress(fileName, n, totalItems)
+			}
+		}(batch)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	// 在派发完成前被取消
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+	if err := t.ctx.Err(); err != nil {
+		return nil, err
+	}
 	return translations, nil
 }
@@ -0,0 +1,135 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPrepareAppliesWidthAndPreHook(t *testing.T) {
+	lt := &LocalTranslator{
+		widthMode: WidthNormalize,
+		callbacks: TranslationCallbacks{Hooks: SegmentHooks{Pre: strings.TrimSpace}},
+	}
+	seg := lt.prepare("  ＡＢＣ１２３  ")
+	if seg.source != "ABC123" {
+		t.Errorf("source = %q, want %q", seg.source, "ABC123")
+	}
+	if seg.text != "  ＡＢＣ１２３  " {
+		t.Errorf("text = %q, want the original text", seg.text)
+	}
+}
+
+// fakeEngine translates every text into "T " followed by the text after delay(text), recording
+// the order in which the translations finish.
+type fakeEngine struct {
+	delay func(text string) time.Duration
+
+	mu       sync.Mutex
+	calls    int
+	finished []string
+}
+
+func (e *fakeEngine) Translate(ctx context.Context, text string) (string, error) {
+	e.mu.Lock()
+	e.calls++
+	e.mu.Unlock()
+	if e.delay != nil {
+		select {
+		case <-time.After(e.delay(text)):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	e.mu.Lock()
+	e.finished = append(e.finished, text)
+	e.mu.Unlock()
+	return "T " + text, nil
+}
+
+func TestOrderedCallbacks(t *testing.T) {
+	texts := []string{"甲", "乙", "丙", "丁", "戊", "乙", "己", "庚"}
+	// Later texts finish first
+	delays := map[string]time.Duration{}
+	for i, text := range texts {
+		delays[text] = time.Duration(len(texts)-i) * 10 * time.Millisecond
+	}
+	engine := &fakeEngine{delay: func(text string) time.Duration { return delays[text] }}
+
+	var mu sync.Mutex
+	var translated []string
+	var progress []int
+	lt := NewTranslator(context.Background(), engine, TranslationCallbacks{
+		OnTranslated: func(original, translation string) {
+			mu.Lock()
+			defer mu.Unlock()
+			translated = append(translated, original)
+		},
+		OnProgress: func(_ string, done, _ int) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress = append(progress, done)
+		},
+		Ordered: true,
+	})
+	lt.SetConcurrency(len(texts))
+
+	results, err := lt.TranslateFileTexts(context.Background(), "document", texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, text := range texts {
+		if results[i] != "T "+text {
+			t.Errorf("result %d = %q, want %q", i, results[i], "T "+text)
+		}
+	}
+	if engine.finished[0] == texts[0] {
+		t.Fatalf("the engine finished in order %q, the test needs them out of order", engine.finished)
+	}
+	// The callbacks follow the source order, once for every occurrence of a text
+	if !slices.Equal(translated, texts) {
+		t.Errorf("OnTranslated order = %q, want %q", translated, texts)
+	}
+	if !slices.IsSorted(progress) || len(progress) == 0 || progress[len(progress)-1] != len(texts) {
+		t.Errorf("progress = %v, want increasing up to %d", progress, len(texts))
+	}
+}
+
+func TestTranslateFileTextsCancelled(t *testing.T) {
+	texts := make([]string, 50)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("文本 %d", i)
+	}
+	engine := &fakeEngine{delay: func(string) time.Duration { return 20 * time.Millisecond }}
+	lt := NewTranslator(context.Background(), engine, TranslationCallbacks{})
+	lt.SetConcurrency(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	results, err := lt.TranslateFileTexts(ctx, "document", texts)
+	if !errors.Is(err, context.Canceled) || results != nil {
+		t.Fatalf("got %d results and error %v, want context.Canceled", len(results), err)
+	}
+	// Translations in flight are abandoned and no more are started
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("returned %v after being cancelled", elapsed)
+	}
+	engine.mu.Lock()
+	calls := engine.calls
+	engine.mu.Unlock()
+	if calls >= len(texts) {
+		t.Errorf("the engine was called %d times, want fewer than %d", calls, len(texts))
+	}
+
+	// A cancelled context fails at once
+	results, err = lt.TranslateFileTexts(ctx, "document", texts)
+	if !errors.Is(err, context.Canceled) || results != nil {
+		t.Errorf("got %d results and error %v with a cancelled context, want context.Canceled", len(results), err)
+	}
+}
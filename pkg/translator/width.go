@@ -0,0 +1,86 @@
+package translator
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 原文中全角字母和数字（如 ＡＢＣ１２３）的处理方式
+const (
+	WidthKeep      = ""          // 原样发送给翻译引擎
+	WidthNormalize = "normalize" // 翻译前转换为半角
+	WidthRestore   = "restore"   // 翻译前转换为半角，译文中再恢复为原文的全角形式
+)
+
+// isFullWidthAlnum 判断字符是否为全角字母或数字
+func isFullWidthAlnum(r rune) bool {
+	return (r >= '０' && r <= '９') || (r >= 'Ａ' && r <= 'Ｚ') || (r >= 'ａ' && r <= 'ｚ')
+}
+
+// normalizeWidth 将全角字母和数字转换为半角，全角标点保持不变
+func normalizeWidth(s string) string {
+	if !strings.ContainsFunc(s, isFullWidthAlnum) {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if isFullWidthAlnum(r) {
+			return r - 0xFEE0
+		}
+		return r
+	}, s)
+}
+
+// restoreWidth 将译文中与原文全角字母数字串对应的半角串恢复为全角
+// 只替换前后不紧邻其他字母数字的完整串，避免改动译文中无关的字母和数字
+func restoreWidth(original, translated string) string {
+	runes := []rune(original)
+	for i := 0; i < len(runes); {
+		if !isFullWidthAlnum(runes[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isFullWidthAlnum(runes[j]) {
+			j++
+		}
+		full := string(runes[i:j])
+		translated = replaceToken(translated, normalizeWidth(full), full)
+		i = j
+	}
+	return translated
+}
+
+// replaceToken 替换 s 中前后不紧邻字母数字的 old
+func replaceToken(s, old, new string) string {
+	var sb strings.Builder
+	for {
+		k := strings.Index(s, old)
+		if k < 0 {
+			sb.WriteString(s)
+			return sb.String()
+		}
+		before, after := s[:k], s[k+len(old):]
+		if endsWithAlnum(before) || startsWithAlnum(after) {
+			sb.WriteString(s[:k+len(old)])
+		} else {
+			sb.WriteString(before)
+			sb.WriteString(new)
+		}
+		s = after
+	}
+}
+
+func isASCIIAlnum(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func endsWithAlnum(s string) bool {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return isASCIIAlnum(r)
+}
+
+func startsWithAlnum(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return isASCIIAlnum(r)
+}